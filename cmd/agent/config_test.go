@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDecodeConfig 测试仓库自带的配置文件按json标签解码，snake_case配置项和时间字段均生效
+func TestDecodeConfig(t *testing.T) {
+	v := viper.New()
+	v.SetConfigFile("../../configs/config.yaml")
+	require.NoError(t, v.ReadInConfig())
+
+	config, err := decodeConfig(v)
+	require.NoError(t, err)
+
+	assert.Equal(t, 8080, config.Agent.Port)
+	assert.Equal(t, 2*time.Minute, config.Agent.StreamTimeout)
+	assert.Equal(t, 4000, config.OpenAI.MaxTokens)
+	assert.Equal(t, "./data/knowledge", config.Knowledge.StoragePath)
+	assert.Equal(t, 6, config.ToolAgent.MaxSteps)
+	assert.Equal(t, 2*time.Minute, config.ToolAgent.Timeout)
+	assert.Equal(t, 10*time.Second, config.Retrieval.Timeout)
+	assert.Equal(t, 2*time.Second, config.Retrieval.SourceTimeouts["local"])
+	assert.Equal(t, 3, config.Retrieval.MinSources)
+	assert.Equal(t, 30*time.Minute, config.Memory.WorkingMemoryTTL)
+	assert.Equal(t, 1000, config.MCP.CacheMaxEntries)
+	assert.Equal(t, "10m", config.MCP.ApprovalTimeout)
+	assert.Equal(t, "${MCP_APPROVER_TOKEN}", config.MCP.Approvers["maintainer"])
+
+	deepwiki, exists := config.MCP.Servers["deepwiki"]
+	require.True(t, exists)
+	assert.Equal(t, "https://mcp.deepwiki.com/mcp", deepwiki.ServerURL)
+	assert.Equal(t, "deepwiki", deepwiki.SourceType)
+}
+
+// TestDecodeConfigDefaults 测试未配置的时间字段使用默认值
+func TestDecodeConfigDefaults(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader("agent:\n  name: test\nmemory:\n  working_memory_max_items: 5\n")))
+
+	config, err := decodeConfig(v)
+	require.NoError(t, err)
+	assert.Equal(t, "test", config.Agent.Name)
+	assert.Equal(t, 5, config.Memory.WorkingMemoryMaxItems)
+	assert.Equal(t, 30*time.Minute, config.Memory.WorkingMemoryTTL)
+	assert.Equal(t, 2*time.Hour, config.Memory.ShortTermMemoryTTL)
+	assert.Equal(t, 5*time.Minute, config.Memory.CleanupInterval)
+}
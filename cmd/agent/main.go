@@ -2,30 +2,53 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/mcp"
 	"github.com/community-governance-mcp-higress/internal/memory"
+	"github.com/community-governance-mcp-higress/internal/model"
 	"github.com/community-governance-mcp-higress/internal/openai"
-	"github.com/community-governance-mcp-higress/internal/mcp"
 	"github.com/community-governance-mcp-higress/tools"
 	"github.com/gin-gonic/gin"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
+const (
+	// defaultStreamTimeout 未配置agent.stream_timeout时流式处理接口的超时时间
+	defaultStreamTimeout = 2 * time.Minute
+	// approverContextKey 通过认证的审批人在gin上下文中的键
+	approverContextKey = "approver"
+	// oauthStateCookie 发起OAuth授权时写入浏览器的state，回调时校验，只有发起授权的浏览器可以完成授权
+	oauthStateCookie = "mcp_oauth_state"
+	// oauthCallbackPath OAuth授权回调路径
+	oauthCallbackPath = "/api/v1/mcp/oauth/callback"
+)
+
 // Server HTTP服务器
 type Server struct {
 	processor     *agent.Processor
 	memoryHandler *memory.Handler
+	mcpServer     *mcp.Server
+	mcpClient     *mcp.Client
+	toolAgent     *agent.ToolAgent
 	config        *agent.AgentConfig
+	approvers     map[string]string
 	logger        *logrus.Logger
 	router        *gin.Engine
 }
@@ -42,6 +65,26 @@ func NewServer(processor *agent.Processor, config *agent.AgentConfig) *Server {
 	// 创建记忆处理器
 	server.memoryHandler = memory.NewHandler(processor.GetMemoryManager())
 
+	// 加载本地工具，MCP服务端和工具调用Agent共享同一组工具
+	toolLoader := newToolLoader(processor, config, server.logger)
+
+	// 创建MCP服务端
+	server.mcpServer = newMCPServer(processor, toolLoader, config, server.logger)
+
+	// 创建工具调用Agent
+	server.toolAgent = agent.NewToolAgent(processor, toolLoader)
+
+	// 创建MCP客户端，各接口共享客户端以复用与远程服务器的会话
+	server.mcpClient = mcp.NewClient(30 * time.Second)
+
+	// 审批人令牌中的${ENV}占位符在启动时展开，展开后为空的令牌不可用
+	server.approvers = make(map[string]string, len(config.MCP.Approvers))
+	for name, token := range config.MCP.Approvers {
+		if token = os.ExpandEnv(token); token != "" {
+			server.approvers[name] = token
+		}
+	}
+
 	// 设置路由
 	server.setupRoutes()
 
@@ -52,26 +95,60 @@ func NewServer(processor *agent.Processor, config *agent.AgentConfig) *Server {
 func (s *Server) setupRoutes() {
 	// API版本组
 	v1 := s.router.Group("/api/v1")
+	v1.Use(mcpCacheBypass)
 	{
 		// 核心功能路由
 		v1.POST("/process", s.handleProcess)
+		v1.POST("/process/stream", s.handleProcessStream)
 		v1.POST("/analyze", s.handleAnalyze)
 		v1.GET("/stats", s.handleStats)
 		v1.GET("/health", s.handleHealth)
 		v1.GET("/config", s.handleConfig)
 
+		// 工具调用Agent
+		v1.POST("/agent/run", s.handleAgentRun)
+
 		// MCP集成路由
 		mcp := v1.Group("/mcp")
 		{
 			mcp.POST("/query", s.handleMCPQuery)
+			mcp.GET("/tools", s.handleMCPToolCatalog)
 			mcp.POST("/tools", s.handleMCPListTools)
 			mcp.POST("/call", s.handleMCPCallTool)
+			mcp.DELETE("/cache", s.handleMCPClearCache)
+
+			// 远程服务器OAuth授权，发起授权需要审批人令牌，回调需要发起授权时的浏览器Cookie或审批人令牌
+			mcp.GET("/oauth", s.handleMCPOAuthStatus)
+			mcp.GET("/oauth/callback", s.handleMCPOAuthCallback)
+			mcp.GET("/oauth/:server/authorize", s.requireApprover, s.handleMCPOAuthAuthorize)
+
+			// 资源和提示模板
+			mcp.GET("/resources", s.handleMCPListResources)
+			mcp.GET("/resources/templates", s.handleMCPListResourceTemplates)
+			mcp.POST("/resources/read", s.handleMCPReadResource)
+			mcp.POST("/resources/subscribe", s.handleMCPSubscribeResource)
+			mcp.POST("/resources/unsubscribe", s.handleMCPUnsubscribeResource)
+			mcp.GET("/prompts", s.handleMCPListPrompts)
+			mcp.POST("/prompts/get", s.handleMCPGetPrompt)
+
+			// 工具调用审批，需要审批人令牌
+			approvals := mcp.Group("/approvals", s.requireApprover)
+			approvals.GET("", s.handleListApprovals)
+			approvals.GET("/:id", s.handleGetApproval)
+			approvals.POST("/:id/approve", s.handleApproveApproval)
+			approvals.POST("/:id/deny", s.handleDenyApproval)
 		}
 	}
 
 	// 注册记忆组件路由
 	s.memoryHandler.RegisterRoutes(s.router)
 
+	// MCP服务端（Streamable HTTP）
+	s.router.Any("/mcp", gin.WrapH(s.mcpServer))
+
+	// Prometheus指标
+	s.router.GET("/metrics", s.handleMetrics)
+
 	// 根路径
 	s.router.GET("/", s.handleRoot)
 }
@@ -118,6 +195,110 @@ func (s *Server) handleProcess(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// handleProcessStream 以SSE流式返回问题处理进度和回答
+func (s *Server) handleProcessStream(c *gin.Context) {
+	var request agent.ProcessRequest
+
+	// 解析请求体
+	if err := c.ShouldBindJSON(&request); err != nil {
+		s.logger.WithError(err).Error("请求解析失败")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求格式错误",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 验证请求
+	if err := s.validateRequest(&request); err != nil {
+		s.logger.WithError(err).Error("请求验证失败")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求验证失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 流式输出LLM回答耗时较长，超时时间单独配置
+	timeout := s.config.Agent.StreamTimeout
+	if timeout <= 0 {
+		timeout = defaultStreamTimeout
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	// 处理协程将事件写入通道，响应协程负责输出SSE
+	events := make(chan *agent.StreamEvent, 16)
+	go func() {
+		defer close(events)
+
+		send := func(event *agent.StreamEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		response, err := s.processor.ProcessQuestionStream(ctx, &request, send)
+		if err != nil {
+			s.logger.WithError(err).Error("问题处理失败")
+			send(&agent.StreamEvent{
+				Type: agent.StreamEventError,
+				Data: gin.H{"error": "问题处理失败", "message": err.Error()},
+			})
+			return
+		}
+		send(&agent.StreamEvent{Type: agent.StreamEventDone, Data: response})
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent(string(event.Type), event.Data)
+		return true
+	})
+}
+
+// handleAgentRun 运行工具调用Agent
+// 模型通过函数调用使用本地工具和MCP工具，返回最终回答以及每一步的模型输出和工具调用记录
+func (s *Server) handleAgentRun(c *gin.Context) {
+	var request agent.AgentRunRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	result, err := s.toolAgent.Run(c.Request.Context(), &request)
+	if err != nil {
+		if errors.Is(err, agent.ErrToolAgentUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "工具调用Agent不可用",
+				"message": err.Error(),
+			})
+			return
+		}
+		s.logger.WithError(err).Error("工具调用Agent运行失败")
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "工具调用Agent运行失败",
+			"message": err.Error(),
+			"result":  result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // handleAnalyze 处理问题分析请求
 func (s *Server) handleAnalyze(c *gin.Context) {
 	var request agent.AnalyzeRequest
@@ -185,10 +366,19 @@ func (s *Server) handleStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// handleHealth 健康检查
+// handleHealth 健康检查，存在熔断中的MCP服务器时状态为degraded
 func (s *Server) handleHealth(c *gin.Context) {
+	mcpHealth := s.processor.GetMCPManager().Health()
+	status := "healthy"
+	for _, health := range mcpHealth {
+		if !health.Healthy {
+			status = "degraded"
+			break
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
+		"status":    status,
 		"timestamp": time.Now().Unix(),
 		"version":   s.config.Agent.Version,
 		"services": gin.H{
@@ -196,9 +386,17 @@ func (s *Server) handleHealth(c *gin.Context) {
 			"deepwiki":  s.config.DeepWiki.Enabled,
 			"knowledge": s.config.Knowledge.Enabled,
 		},
+		"mcp": mcpHealth,
 	})
 }
 
+// handleMetrics 输出Prometheus格式的MCP服务器健康指标
+func (s *Server) handleMetrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	s.processor.GetMCPManager().WriteMetrics(c.Writer)
+}
+
 // handleConfig 获取配置信息
 func (s *Server) handleConfig(c *gin.Context) {
 	// 返回安全的配置信息（不包含敏感数据）
@@ -223,11 +421,13 @@ func (s *Server) handleRoot(c *gin.Context) {
 		"message": "Higress社区治理Agent",
 		"version": s.config.Agent.Version,
 		"docs":    "/api/v1/",
+		"mcp":     "/mcp",
 		"features": []string{
 			"智能问答",
 			"问题分析",
 			"社区统计",
 			"知识融合",
+			"MCP服务",
 		},
 	})
 }
@@ -260,16 +460,16 @@ func (s *Server) handleMCPQuery(c *gin.Context) {
 		return
 	}
 
-	// 创建MCP客户端
-	mcpClient := mcp.NewClient(30 * time.Second)
-
-	// 执行查询
-	response, err := mcpClient.Query(c.Request.Context(), &request)
+	// 已配置的服务器通过管理器查询，受工具策略、熔断器和响应缓存约束
+	var response *mcp.QueryResponse
+	var err error
+	if _, exists := s.processor.GetMCPManager().GetServerConfig(request.ServerLabel); exists && request.ServerURL == "" {
+		response, err = s.processor.GetMCPManager().Query(c.Request.Context(), request.ServerLabel, request.Input, request.RepoName)
+	} else {
+		response, err = s.mcpClient.Query(c.Request.Context(), &request)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "MCP查询失败",
-			"message": err.Error(),
-		})
+		respondMCPError(c, err, "MCP查询失败")
 		return
 	}
 
@@ -288,7 +488,7 @@ func (s *Server) handleMCPQuery(c *gin.Context) {
 // handleMCPListTools 处理MCP工具列表请求
 func (s *Server) handleMCPListTools(c *gin.Context) {
 	var request mcp.ListToolsRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "请求参数错误",
 			"message": err.Error(),
@@ -296,11 +496,14 @@ func (s *Server) handleMCPListTools(c *gin.Context) {
 		return
 	}
 
-	// 创建MCP客户端
-	mcpClient := mcp.NewClient(30 * time.Second)
+	// 未指定服务器时返回全部服务器的工具目录
+	if request.ServerLabel == "" && request.ServerURL == "" {
+		s.handleMCPToolCatalog(c)
+		return
+	}
 
 	// 获取工具列表
-	response, err := mcpClient.ListTools(c.Request.Context(), &request)
+	response, err := s.mcpClient.ListTools(c.Request.Context(), &request)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "获取工具列表失败",
@@ -314,9 +517,34 @@ func (s *Server) handleMCPListTools(c *gin.Context) {
 	})
 }
 
+// handleMCPToolCatalog 返回全部已启用服务器的工具目录，refresh=true时重新获取
+func (s *Server) handleMCPToolCatalog(c *gin.Context) {
+	manager := s.processor.GetMCPManager()
+
+	var catalog []mcp.CatalogTool
+	var err error
+	if c.Query("refresh") == "true" {
+		catalog, err = manager.RefreshToolCatalog(c.Request.Context())
+	} else {
+		catalog, err = manager.ToolCatalog(c.Request.Context())
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取工具目录失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tools": catalog,
+		"total": len(catalog),
+	})
+}
+
 // handleMCPCallTool 处理MCP工具调用请求
 func (s *Server) handleMCPCallTool(c *gin.Context) {
-	var request mcp.CallToolRequest
+	var request MCPCallToolRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "请求参数错误",
@@ -325,16 +553,21 @@ func (s *Server) handleMCPCallTool(c *gin.Context) {
 		return
 	}
 
-	// 创建MCP客户端
-	mcpClient := mcp.NewClient(30 * time.Second)
+	// 只能调用已配置的服务器，调用受allowed_tools和require_approval约束；
+	// 未指定服务器时按工具目录中带服务器前缀的工具名路由
+	manager := s.processor.GetMCPManager()
+	if _, exists := manager.GetServerConfig(request.ServerLabel); !exists && request.ServerLabel != "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "MCP服务器未配置: " + request.ServerLabel,
+		})
+		return
+	}
 
-	// 调用工具
-	response, err := mcpClient.CallTool(c.Request.Context(), &request)
+	// 需要审批时不等待，返回202和审批ID，批准后携带approval_id重新调用
+	ctx := mcp.WithAsyncApproval(c.Request.Context(), request.ApprovalID)
+	response, err := manager.CallTool(ctx, request.ServerLabel, request.ToolName, request.Arguments)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "工具调用失败",
-			"message": err.Error(),
-		})
+		respondMCPError(c, err, "工具调用失败")
 		return
 	}
 
@@ -350,6 +583,493 @@ func (s *Server) handleMCPCallTool(c *gin.Context) {
 	})
 }
 
+// respondMCPError 按错误类型返回MCP调用失败的响应：等待审批返回202，需要OAuth授权返回401，
+// 工具策略拒绝返回403，审批不存在返回404、已使用返回409，服务器熔断返回503
+func respondMCPError(c *gin.Context, err error, message string) {
+	var pendingErr *mcp.ApprovalPendingError
+	if errors.As(err, &pendingErr) {
+		c.JSON(http.StatusAccepted, gin.H{
+			"status":      mcp.ApprovalPending,
+			"approval_id": pendingErr.Approval.ID,
+			"expires_at":  pendingErr.Approval.ExpiresAt,
+			"message":     "工具调用等待审批，批准后携带approval_id重新调用",
+		})
+		return
+	}
+	var authErr *mcp.AuthorizationRequiredError
+	if errors.As(err, &authErr) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":         "MCP服务器需要授权",
+			"message":       err.Error(),
+			"authorize_url": "/api/v1/mcp/oauth/" + authErr.Server + "/authorize",
+		})
+		return
+	}
+	var notAllowed *mcp.ToolNotAllowedError
+	var approvalErr *mcp.ApprovalError
+	if errors.As(err, &notAllowed) || errors.As(err, &approvalErr) || errors.Is(err, mcp.ErrApprovalMismatch) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "工具调用未获允许",
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, mcp.ErrApprovalNotFound) || errors.Is(err, mcp.ErrApprovalUsed) {
+		status := http.StatusNotFound
+		if errors.Is(err, mcp.ErrApprovalUsed) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	var openErr *mcp.CircuitOpenError
+	if errors.As(err, &openErr) {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(openErr.RetryAt).Seconds())+1))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "MCP服务器暂不可用",
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   message,
+		"message": err.Error(),
+	})
+}
+
+// mcpCacheBypass 请求带有X-MCP-Cache-Bypass或Cache-Control: no-cache时跳过MCP响应缓存
+func mcpCacheBypass(c *gin.Context) {
+	if c.GetHeader(mcp.CacheBypassHeader) != "" || strings.Contains(c.GetHeader("Cache-Control"), "no-cache") {
+		c.Request = c.Request.WithContext(mcp.WithCacheBypass(c.Request.Context()))
+	}
+	c.Next()
+}
+
+// handleMCPClearCache 清空MCP响应缓存
+func (s *Server) handleMCPClearCache(c *gin.Context) {
+	manager := s.processor.GetMCPManager()
+	cleared := manager.CacheSize()
+	manager.ClearCache()
+
+	c.JSON(http.StatusOK, gin.H{
+		"cleared": cleared,
+	})
+}
+
+// handleMCPOAuthStatus 获取启用OAuth的服务器的授权状态
+func (s *Server) handleMCPOAuthStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"servers": s.processor.GetMCPManager().OAuthStatus(),
+	})
+}
+
+// handleMCPOAuthAuthorize 发起授权码授权，默认重定向到授权服务器，?redirect=false时返回授权地址
+func (s *Server) handleMCPOAuthAuthorize(c *gin.Context) {
+	serverLabel := c.Param("server")
+	oauth, exists := s.processor.GetMCPManager().OAuthClient(serverLabel)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "MCP服务器未启用OAuth: " + serverLabel,
+		})
+		return
+	}
+
+	authorizationURL, err := oauth.AuthorizationURL(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "发起OAuth授权失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 授权回调只接受发起授权的浏览器，防止他人用自己的授权码完成授权
+	if parsed, err := url.Parse(authorizationURL); err == nil {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oauthStateCookie, parsed.Query().Get("state"), int(mcp.PendingAuthorizationTTL/time.Second),
+			oauthCallbackPath, "", c.Request.TLS != nil, true)
+	}
+
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, gin.H{
+			"authorization_url": authorizationURL,
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// handleMCPOAuthCallback 处理授权服务器的回调，用code换取令牌
+func (s *Server) handleMCPOAuthCallback(c *gin.Context) {
+	if authErr := c.Query("error"); authErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "OAuth授权被拒绝",
+			"message": strings.TrimSpace(authErr + " " + c.Query("error_description")),
+		})
+		return
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "缺少state或code参数",
+		})
+		return
+	}
+
+	// 不是发起授权的浏览器时需要审批人令牌，授权码不会被使用，可以携带令牌重新请求该地址
+	cookie, _ := c.Cookie(oauthStateCookie)
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		if _, ok := s.authenticateApprover(c); !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "请在发起授权的浏览器中完成授权，或携带审批人令牌请求该地址",
+			})
+			return
+		}
+	}
+	c.SetCookie(oauthStateCookie, "", -1, oauthCallbackPath, "", c.Request.TLS != nil, true)
+
+	serverLabel, err := s.processor.GetMCPManager().CompleteAuthorization(c.Request.Context(), state, code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "OAuth授权失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"server":  serverLabel,
+		"message": "授权成功",
+	})
+}
+
+// handleMCPListResources 列出MCP服务器的资源
+func (s *Server) handleMCPListResources(c *gin.Context) {
+	serverLabel := c.Query("server_label")
+	if serverLabel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "server_label不能为空",
+		})
+		return
+	}
+
+	response, err := s.processor.GetMCPManager().ListResources(c.Request.Context(), serverLabel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取资源列表失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleMCPListResourceTemplates 列出MCP服务器的资源模板
+func (s *Server) handleMCPListResourceTemplates(c *gin.Context) {
+	serverLabel := c.Query("server_label")
+	if serverLabel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "server_label不能为空",
+		})
+		return
+	}
+
+	response, err := s.processor.GetMCPManager().ListResourceTemplates(c.Request.Context(), serverLabel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取资源模板列表失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleMCPReadResource 读取MCP资源内容
+func (s *Server) handleMCPReadResource(c *gin.Context) {
+	request, ok := s.bindResourceRequest(c)
+	if !ok {
+		return
+	}
+
+	response, err := s.processor.GetMCPManager().ReadResource(c.Request.Context(), request.ServerLabel, request.URI)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "读取资源失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleMCPSubscribeResource 订阅MCP资源变化
+func (s *Server) handleMCPSubscribeResource(c *gin.Context) {
+	request, ok := s.bindResourceRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := s.processor.GetMCPManager().SubscribeResource(c.Request.Context(), request.ServerLabel, request.URI); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "订阅资源失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscribed": true,
+	})
+}
+
+// handleMCPUnsubscribeResource 取消订阅MCP资源变化
+func (s *Server) handleMCPUnsubscribeResource(c *gin.Context) {
+	request, ok := s.bindResourceRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := s.processor.GetMCPManager().UnsubscribeResource(c.Request.Context(), request.ServerLabel, request.URI); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "取消订阅资源失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subscribed": false,
+	})
+}
+
+// bindResourceRequest 解析资源请求，server_label和uri不能为空
+func (s *Server) bindResourceRequest(c *gin.Context) (*mcp.ResourceRequest, bool) {
+	var request mcp.ResourceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	if request.ServerLabel == "" || request.URI == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "server_label和uri不能为空",
+		})
+		return nil, false
+	}
+	return &request, true
+}
+
+// handleMCPListPrompts 列出MCP服务器的提示模板
+func (s *Server) handleMCPListPrompts(c *gin.Context) {
+	serverLabel := c.Query("server_label")
+	if serverLabel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "server_label不能为空",
+		})
+		return
+	}
+
+	response, err := s.processor.GetMCPManager().ListPrompts(c.Request.Context(), serverLabel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取提示模板列表失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleMCPGetPrompt 按参数展开MCP提示模板
+func (s *Server) handleMCPGetPrompt(c *gin.Context) {
+	var request mcp.GetPromptRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"message": err.Error(),
+		})
+		return
+	}
+	if request.ServerLabel == "" || request.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "server_label和name不能为空",
+		})
+		return
+	}
+
+	response, err := s.processor.GetMCPManager().GetPrompt(c.Request.Context(), request.ServerLabel, request.Name, request.Arguments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "获取提示模板失败",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MCPCallToolRequest 工具调用请求
+type MCPCallToolRequest struct {
+	ServerLabel string                 `json:"server_label"` // 服务器标签，为空时tool_name需带服务器前缀
+	ToolName    string                 `json:"tool_name" binding:"required"`
+	Arguments   map[string]interface{} `json:"arguments"`
+	ApprovalID  string                 `json:"approval_id"` // 审批通过后重新调用时携带的审批ID
+}
+
+// ApprovalDecisionRequest 审批处理请求，审批人由请求的令牌确定
+type ApprovalDecisionRequest struct {
+	Reason string `json:"reason"` // 拒绝原因
+}
+
+// requireApprover 校验审批人令牌（Authorization: Bearer <令牌>），通过后在上下文中记录审批人
+// 未配置mcp.approvers时拒绝全部请求
+func (s *Server) requireApprover(c *gin.Context) {
+	if len(s.approvers) == 0 {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "未配置审批人（mcp.approvers），审批接口不可用",
+		})
+		return
+	}
+
+	if name, ok := s.authenticateApprover(c); ok {
+		c.Set(approverContextKey, name)
+		c.Next()
+		return
+	}
+
+	c.Header("WWW-Authenticate", "Bearer")
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"error": "需要审批人令牌",
+	})
+}
+
+// authenticateApprover 按请求的Bearer令牌查找审批人
+func (s *Server) authenticateApprover(c *gin.Context) (string, bool) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+	for name, expected := range s.approvers {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// handleListApprovals 列出工具调用审批，可按status过滤
+func (s *Server) handleListApprovals(c *gin.Context) {
+	status := mcp.ApprovalStatus(c.Query("status"))
+	approvals := s.processor.GetMCPManager().Approvals().List(status)
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": approvals,
+		"total":     len(approvals),
+	})
+}
+
+// handleGetApproval 获取工具调用审批
+func (s *Server) handleGetApproval(c *gin.Context) {
+	approval, exists := s.processor.GetMCPManager().Approvals().Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": mcp.ErrApprovalNotFound.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// handleApproveApproval 批准工具调用
+func (s *Server) handleApproveApproval(c *gin.Context) {
+	approval, err := s.processor.GetMCPManager().Approvals().Approve(c.Param("id"), c.GetString(approverContextKey))
+	s.respondApproval(c, approval, err)
+}
+
+// handleDenyApproval 拒绝工具调用
+func (s *Server) handleDenyApproval(c *gin.Context) {
+	var request ApprovalDecisionRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "请求参数错误",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	approval, err := s.processor.GetMCPManager().Approvals().Deny(c.Param("id"), c.GetString(approverContextKey), request.Reason)
+	s.respondApproval(c, approval, err)
+}
+
+// respondApproval 返回审批处理结果
+func (s *Server) respondApproval(c *gin.Context, approval *mcp.Approval, err error) {
+	switch {
+	case errors.Is(err, mcp.ErrApprovalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case errors.Is(err, mcp.ErrApprovalDecided):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    err.Error(),
+			"approval": approval,
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "审批处理失败",
+			"message": err.Error(),
+		})
+	default:
+		s.logger.WithFields(logrus.Fields{
+			"id":       approval.ID,
+			"server":   approval.Server,
+			"tool":     approval.Tool,
+			"status":   approval.Status,
+			"approver": approval.DecidedBy,
+		}).Info("MCP工具调用审批已处理")
+		c.JSON(http.StatusOK, approval)
+	}
+}
+
+// newToolLoader 加载本地工具
+func newToolLoader(processor *agent.Processor, config *agent.AgentConfig, logger *logrus.Logger) *agent.ToolLoader {
+	toolLoader := agent.NewToolLoader()
+	if err := toolLoader.LoadTools(&model.Config{
+		OpenAIKey:   config.OpenAI.APIKey,
+		GitHubToken: config.GitHub.Token,
+	}); err != nil {
+		logger.WithError(err).Warn("加载工具失败")
+	}
+	// 与处理器共享同一个本地知识库
+	toolLoader.RegisterTool("knowledge_base", processor.GetKnowledgeBase())
+	return toolLoader
+}
+
+// newMCPServer 创建MCP服务端并注册全部工具
+func newMCPServer(processor *agent.Processor, toolLoader *agent.ToolLoader, config *agent.AgentConfig, logger *logrus.Logger) *mcp.Server {
+	mcpServer := mcp.NewServer(config.Agent.Name, config.Agent.Version)
+	mcpServer.SetLogger(logger)
+	mcpServer.SetInstructions("Higress社区治理Agent：提供社区问答、问题分析、社区统计和GitHub工具")
+	for _, tool := range agent.BuildMCPTools(processor, toolLoader) {
+		mcpServer.RegisterTool(tool)
+	}
+
+	logger.WithField("tools", len(mcpServer.GetTools())).Info("MCP服务端工具已注册")
+	return mcpServer
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	addr := fmt.Sprintf(":%d", s.config.Agent.Port)
@@ -374,14 +1094,9 @@ func loadConfig() (*agent.AgentConfig, error) {
 	}
 
 	// 解析配置
-	var config agent.AgentConfig
-	if err := viper.Unmarshal(&config); err != nil {
-		return nil, fmt.Errorf("解析配置失败: %w", err)
-	}
-
-	// 手动解析时间字段
-	if err := parseTimeFields(&config); err != nil {
-		return nil, fmt.Errorf("解析时间字段失败: %w", err)
+	config, err := decodeConfig(viper.GetViper())
+	if err != nil {
+		return nil, err
 	}
 
 	// 从环境变量获取敏感信息
@@ -393,6 +1108,24 @@ func loadConfig() (*agent.AgentConfig, error) {
 		config.GitHub.Token = githubToken
 	}
 
+	return config, nil
+}
+
+// decodeConfig 将读取的配置解码为Agent配置
+// 配置结构体只声明了json标签，按json标签匹配snake_case配置项，时间字段支持"30s"形式的字符串
+func decodeConfig(v *viper.Viper) (*agent.AgentConfig, error) {
+	var config agent.AgentConfig
+	if err := v.Unmarshal(&config, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "json"
+	}); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+
+	// 手动解析时间字段
+	if err := parseTimeFields(&config); err != nil {
+		return nil, fmt.Errorf("解析时间字段失败: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -436,6 +1169,18 @@ func setupLogging(config *agent.AgentConfig) *logrus.Logger {
 }
 
 func main() {
+	// 解析命令行参数
+	transport := flag.String("transport", "http", "传输模式: http 或 stdio")
+	syncDocs := flag.Bool("sync-docs", false, "同步Higress文档镜像到本地知识库后退出")
+	docsDir := flag.String("docs-dir", "", "本地Higress文档Markdown目录，覆盖higress.docs_path")
+	docsArchive := flag.String("docs-archive", "", "Higress文档仓库的tar.gz归档地址，覆盖higress.docs_archive_url")
+	flag.Parse()
+
+	if *transport != "http" && *transport != "stdio" {
+		fmt.Fprintf(os.Stderr, "不支持的传输模式: %s\n", *transport)
+		os.Exit(1)
+	}
+
 	// 加载配置
 	config, err := loadConfig()
 	if err != nil {
@@ -445,6 +1190,21 @@ func main() {
 
 	// 设置日志
 	logger := setupLogging(config)
+
+	// 文档同步模式：同步一次后退出，不启动定时同步
+	if *syncDocs {
+		if *docsDir != "" {
+			config.Higress.DocsPath = *docsDir
+		}
+		if *docsArchive != "" {
+			config.Higress.DocsPath = ""
+			config.Higress.DocsArchiveURL = *docsArchive
+		}
+		config.Higress.DocsSyncInterval = ""
+		runDocsSync(config, logger)
+		return
+	}
+
 	logger.Info("启动Higress社区治理Agent")
 
 	// 创建OpenAI客户端
@@ -452,6 +1212,13 @@ func main() {
 
 	// 创建处理器
 	processor := agent.NewProcessor(openaiClient, config)
+	defer processor.Stop()
+
+	// stdio模式：作为本地MCP子进程运行
+	if *transport == "stdio" {
+		runStdio(processor, config, logger)
+		return
+	}
 
 	// 创建服务器
 	server := NewServer(processor, config)
@@ -470,3 +1237,44 @@ func main() {
 
 	logger.Info("正在关闭服务器...")
 }
+
+// runDocsSync 同步Higress文档镜像到本地知识库
+func runDocsSync(config *agent.AgentConfig, logger *logrus.Logger) {
+	processor := agent.NewProcessor(openai.NewClient(config.OpenAI.APIKey, config.OpenAI.Model), config)
+	defer processor.Stop()
+	processor.SetLogger(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := processor.SyncDocs(ctx)
+	if err != nil {
+		logger.WithError(err).Error("同步Higress文档失败")
+		processor.Stop()
+		os.Exit(1)
+	}
+
+	fmt.Printf("文件: %d，章节: %d，新增: %d，更新: %d，未变化: %d，删除: %d，耗时: %s\n",
+		report.Files, report.Sections, report.Added, report.Updated, report.Unchanged, report.Removed, report.Duration)
+}
+
+// runStdio 以stdio传输运行MCP服务端
+// 标准输出专用于JSON-RPC消息，所有日志写入标准错误
+func runStdio(processor *agent.Processor, config *agent.AgentConfig, logger *logrus.Logger) {
+	logger.SetOutput(os.Stderr)
+	logrus.SetOutput(os.Stderr)
+	processor.SetLogger(logger)
+
+	mcpServer := newMCPServer(processor, newToolLoader(processor, config, logger), config, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info("MCP服务端以stdio模式运行")
+	if err := mcpServer.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil && !errors.Is(err, context.Canceled) {
+		logger.WithError(err).Error("stdio服务异常退出")
+		os.Exit(1)
+	}
+
+	logger.Info("MCP服务端已关闭")
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent 流式接口返回的SSE事件
+type sseEvent struct {
+	Type string
+	Data string
+}
+
+// newStreamingLLM 创建模拟OpenAI服务，流式请求按deltas逐个返回增量
+func newStreamingLLM(t *testing.T, deltas []string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.True(t, request.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, delta := range deltas {
+			chunk, _ := json.Marshal(map[string]interface{}{
+				"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{"content": delta}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+			flusher.Flush()
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestServer 创建使用模拟OpenAI服务和本地知识库的HTTP服务器
+func newTestServer(t *testing.T, llmURL string, configure func(config *agent.AgentConfig)) *Server {
+	gin.SetMode(gin.TestMode)
+
+	config := &agent.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.MCP.HealthCheck.Interval = "0"
+	config.Knowledge.Enabled = true
+	config.Knowledge.StoragePath = t.TempDir()
+	config.Knowledge.Embedder = "hash"
+	config.Retrieval = model.RetrievalBudgetConfig{
+		Timeout:        2 * time.Second,
+		SourceTimeouts: map[string]time.Duration{"higress": 100 * time.Millisecond},
+	}
+	if configure != nil {
+		configure(config)
+	}
+
+	client := openai.NewClient("test-key", "gpt-4o")
+	client.SetBaseURL(llmURL)
+	processor := agent.NewProcessor(client, config)
+	t.Cleanup(processor.Stop)
+
+	_, err := processor.GetKnowledgeBase().AddDocument(model.Document{
+		Title:   "key-rate-limit 插件",
+		Content: "key-rate-limit 插件按照请求头或参数对请求进行限流，配置 limit_by_header 和 limit_keys。",
+	})
+	require.NoError(t, err)
+
+	return NewServer(processor, config)
+}
+
+// postStream 请求流式处理接口并解析返回的SSE事件
+func postStream(t *testing.T, server *Server, body string) []sseEvent {
+	httpServer := httptest.NewServer(server.router)
+	defer httpServer.Close()
+
+	resp, err := http.Post(httpServer.URL+"/api/v1/process/stream", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			current.Type = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			current.Data = strings.TrimPrefix(line, "data:")
+		case line == "" && current.Type != "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+// TestProcessStream 测试流式接口的事件顺序以及逐个转发模型的增量输出
+func TestProcessStream(t *testing.T) {
+	llm := newStreamingLLM(t, []string{"配置 ", "limit_by_header", " 即可[1]"})
+	server := newTestServer(t, llm.URL, nil)
+
+	events := postStream(t, server, `{"title":"key-rate-limit 插件如何配置","content":"key-rate-limit 插件如何配置","type":"text"}`)
+	require.NotEmpty(t, events)
+
+	// 阶段事件按处理顺序发送，回答增量在融合之后、完成之前
+	var stages []string
+	var deltas []string
+	for _, event := range events {
+		if event.Type == string(agent.StreamEventToken) {
+			var data struct {
+				Delta string `json:"delta"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(event.Data), &data))
+			deltas = append(deltas, data.Delta)
+		}
+		if len(stages) == 0 || stages[len(stages)-1] != event.Type {
+			stages = append(stages, event.Type)
+		}
+	}
+	assert.Equal(t, []string{"memory", "question", "source", "fusion", "token", "done"}, stages)
+
+	// 模型的每个增量单独转发，参考来源作为最后一个增量
+	require.Len(t, deltas, 4)
+	assert.Equal(t, []string{"配置 ", "limit_by_header", " 即可[1]"}, deltas[:3])
+	assert.Contains(t, deltas[3], "参考来源")
+	assert.Contains(t, deltas[3], "[1] key-rate-limit 插件")
+
+	var response model.ProcessResponse
+	require.NoError(t, json.Unmarshal([]byte(events[len(events)-1].Data), &response))
+	assert.Equal(t, strings.Join(deltas, ""), response.Content)
+}
+
+// TestProcessStreamTimeout 测试流式接口使用配置的超时时间
+func TestProcessStreamTimeout(t *testing.T) {
+	// 模型一直不返回，直到请求被取消
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(llm.Close)
+	server := newTestServer(t, llm.URL, func(config *agent.AgentConfig) {
+		config.Agent.StreamTimeout = 300 * time.Millisecond
+	})
+
+	start := time.Now()
+	postStream(t, server, `{"title":"key-rate-limit","content":"key-rate-limit 插件如何配置","type":"text"}`)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// serveJSON 请求服务器接口并解析JSON响应
+func serveJSON(t *testing.T, server *Server, method, path, token, body string) (int, map[string]interface{}) {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	return recorder.Code, response
+}
+
+// TestMCPCallToolApproval 测试需要审批的工具调用立即返回审批ID，审批人批准后携带审批ID重新调用
+func TestMCPCallToolApproval(t *testing.T) {
+	remote := mcptest.NewServer(t, "stripe")
+	t.Setenv("TEST_APPROVER_TOKEN", "approver-secret")
+	server := newTestServer(t, "http://127.0.0.1:0", func(config *agent.AgentConfig) {
+		config.MCP.Approvers = map[string]string{"alice": "${TEST_APPROVER_TOKEN}", "bob": "${TEST_UNSET_TOKEN}"}
+		config.MCP.Servers = map[string]model.MCPServer{
+			"stripe": {Enabled: true, ServerURL: remote.URL, RequireApproval: mcp.ApprovalAlways},
+		}
+	})
+	call := `{"server_label":"stripe","tool_name":"create_refund","arguments":{"payment_intent":"pi_123"}}`
+
+	// 未配置的服务器直接拒绝，不会请求调用方提供的地址
+	status, _ := serveJSON(t, server, http.MethodPost, "/api/v1/mcp/call", "",
+		`{"server_label":"unknown","server_url":"`+remote.URL+`","tool_name":"create_refund"}`)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	start := time.Now()
+	status, response := serveJSON(t, server, http.MethodPost, "/api/v1/mcp/call", "", call)
+	require.Equal(t, http.StatusAccepted, status)
+	assert.Less(t, time.Since(start), time.Second)
+	approvalID, _ := response["approval_id"].(string)
+	require.NotEmpty(t, approvalID)
+	assert.Zero(t, remote.ToolCalls())
+
+	// 批准前重新调用仍返回202
+	status, _ = serveJSON(t, server, http.MethodPost, "/api/v1/mcp/call", "",
+		`{"server_label":"stripe","tool_name":"create_refund","arguments":{"payment_intent":"pi_123"},"approval_id":"`+approvalID+`"}`)
+	assert.Equal(t, http.StatusAccepted, status)
+
+	// 审批接口需要审批人令牌，未展开的占位符不能作为令牌
+	approvePath := "/api/v1/mcp/approvals/" + approvalID + "/approve"
+	status, _ = serveJSON(t, server, http.MethodPost, approvePath, "", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = serveJSON(t, server, http.MethodPost, approvePath, "${TEST_UNSET_TOKEN}", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = serveJSON(t, server, http.MethodGet, "/api/v1/mcp/approvals", "", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, response = serveJSON(t, server, http.MethodPost, approvePath, "approver-secret", "")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "alice", response["decided_by"])
+
+	// 参数不一致时不能使用该审批
+	status, _ = serveJSON(t, server, http.MethodPost, "/api/v1/mcp/call", "",
+		`{"server_label":"stripe","tool_name":"create_refund","arguments":{"payment_intent":"pi_999"},"approval_id":"`+approvalID+`"}`)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Zero(t, remote.ToolCalls())
+
+	withApproval := `{"server_label":"stripe","tool_name":"create_refund","arguments":{"payment_intent":"pi_123"},"approval_id":"` + approvalID + `"}`
+	status, response = serveJSON(t, server, http.MethodPost, "/api/v1/mcp/call", "", withApproval)
+	require.Equal(t, http.StatusOK, status)
+	assert.Contains(t, response["output"], "create_refund")
+	assert.Equal(t, 1, remote.ToolCalls())
+
+	// 每个审批只能执行一次
+	status, _ = serveJSON(t, server, http.MethodPost, "/api/v1/mcp/call", "", withApproval)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, 1, remote.ToolCalls())
+}
+
+// TestApprovalsRequireApprovers 测试未配置审批人时审批接口不可用
+func TestApprovalsRequireApprovers(t *testing.T) {
+	server := newTestServer(t, "http://127.0.0.1:0", nil)
+	status, _ := serveJSON(t, server, http.MethodPost, "/api/v1/mcp/approvals/any/approve", "anything", "")
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+// TestMCPOAuthRoutesRequireApprover 测试发起授权需要审批人令牌，回调需要发起授权时的Cookie或审批人令牌
+func TestMCPOAuthRoutesRequireApprover(t *testing.T) {
+	t.Setenv("TEST_APPROVER_TOKEN", "approver-secret")
+	server := newTestServer(t, "http://127.0.0.1:0", func(config *agent.AgentConfig) {
+		config.MCP.Approvers = map[string]string{"alice": "${TEST_APPROVER_TOKEN}"}
+		config.MCP.Servers = map[string]model.MCPServer{
+			"tracker": {
+				Enabled:   true,
+				ServerURL: "http://127.0.0.1:1/mcp",
+				OAuth:     model.MCPOAuthConfig{Enabled: true, ClientID: "agent", AuthorizationServer: "http://127.0.0.1:1"},
+			},
+		}
+	})
+
+	status, _ := serveJSON(t, server, http.MethodGet, "/api/v1/mcp/oauth/tracker/authorize?redirect=false", "", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// authorize 发起授权，返回授权地址中的state和写入的Cookie
+	authorize := func() (string, *http.Cookie) {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/mcp/oauth/tracker/authorize?redirect=false", nil)
+		request.Header.Set("Authorization", "Bearer approver-secret")
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response struct {
+			AuthorizationURL string `json:"authorization_url"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		authorizationURL, err := url.Parse(response.AuthorizationURL)
+		require.NoError(t, err)
+		cookies := recorder.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.True(t, cookies[0].HttpOnly)
+		return authorizationURL.Query().Get("state"), cookies[0]
+	}
+	// callback 请求回调地址，授权通过后换取令牌时因授权服务器不可用返回400
+	callback := func(state string, cookie *http.Cookie, token string) int {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/mcp/oauth/callback?code=code&state="+url.QueryEscape(state), nil)
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	firstState, firstCookie := authorize()
+	secondState, _ := authorize()
+	assert.Equal(t, http.StatusUnauthorized, callback(firstState, nil, ""))
+	assert.Equal(t, http.StatusUnauthorized, callback(secondState, firstCookie, ""))
+	assert.Equal(t, http.StatusUnauthorized, callback(secondState, nil, "wrong-token"))
+	assert.Equal(t, http.StatusBadRequest, callback(firstState, firstCookie, ""))
+	assert.Equal(t, http.StatusBadRequest, callback(secondState, nil, "approver-secret"))
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/community-governance-mcp-higress/internal/openai"
+)
+
+const (
+	// maxPromptSources 提示词中最多引用的知识源数量
+	maxPromptSources = 8
+	// maxSourceContentRunes 单个知识源在提示词中的最大字符数
+	maxSourceContentRunes = 1500
+	// defaultAnswerMaxTokens 未配置max_tokens时的回答长度上限
+	defaultAnswerMaxTokens = 2000
+)
+
+// answerSystemPrompt 回答生成的系统提示词
+const answerSystemPrompt = `你是Higress社区的技术助手，负责基于给定的参考资料回答社区用户的问题。
+要求：
+1. 只使用参考资料中的信息作答，资料不足以回答时请明确说明，不要编造配置项、命令或链接。
+2. 使用Markdown格式，先给出直接结论，再给出步骤或示例。
+3. 引用资料时在句末使用方括号编号标注来源，例如[1]或[1][3]，编号必须与参考资料编号一致。
+4. 不要在回答末尾罗列参考来源，系统会自动附加。
+5. 使用与用户问题相同的语言回答。
+6. 参考资料来自GitHub Issue或讨论且包含解答时，说明该问题曾在对应编号中解决，例如“该问题已在 #1234 中解决”。`
+
+// citationPattern 匹配回答中的引用编号
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// synthesizeAnswer 调用LLM基于融合结果生成带引用的回答
+// 流式调用时逐个增量发送token事件；LLM不可用或在输出前失败时返回错误，由调用方回退到模板回答
+func (p *Processor) synthesizeAnswer(ctx context.Context, question *Question, fusionResult *FusionResult) (string, error) {
+	if p.openaiClient == nil || !p.openaiClient.IsConfigured() {
+		return "", fmt.Errorf("未配置OpenAI客户端")
+	}
+	if len(fusionResult.Sources) == 0 {
+		return "", fmt.Errorf("没有可用的知识源")
+	}
+
+	messages := p.buildAnswerMessages(question, fusionResult)
+	maxTokens := p.config.OpenAI.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultAnswerMaxTokens
+	}
+	temperature := p.config.OpenAI.Temperature
+
+	var content string
+	if isStreaming(ctx) {
+		deltas, err := p.openaiClient.ChatStream(ctx, messages, maxTokens, temperature)
+		if err != nil {
+			return "", fmt.Errorf("LLM生成回答失败: %w", err)
+		}
+
+		var builder strings.Builder
+		for delta := range deltas {
+			if delta.Err != nil {
+				// 已经向客户端输出了部分内容时保留已生成的部分，避免与模板回答拼接
+				if builder.Len() == 0 {
+					return "", fmt.Errorf("LLM生成回答失败: %w", delta.Err)
+				}
+				p.logger.WithError(delta.Err).Warn("LLM流式输出中断，使用已生成的部分回答")
+				break
+			}
+			if delta.Content != "" {
+				builder.WriteString(delta.Content)
+				emitEvent(ctx, StreamEventToken, map[string]interface{}{
+					"delta": delta.Content,
+				})
+			}
+		}
+		content = builder.String()
+	} else {
+		response, err := p.openaiClient.Chat(ctx, messages, maxTokens, temperature)
+		if err != nil {
+			return "", fmt.Errorf("LLM生成回答失败: %w", err)
+		}
+		if len(response.Choices) == 0 {
+			return "", fmt.Errorf("LLM没有生成任何内容")
+		}
+		content = response.Choices[0].Message.Content
+	}
+
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", fmt.Errorf("LLM返回了空回答")
+	}
+
+	references := p.buildCitationReferences(content, fusionResult.Sources)
+	emitAnswerText(ctx, references)
+
+	return content + references, nil
+}
+
+// buildAnswerMessages 构建带参考资料的提示消息
+func (p *Processor) buildAnswerMessages(question *Question, fusionResult *FusionResult) []openai.Message {
+	var prompt strings.Builder
+
+	// 融合上下文包含编号后的参考资料及记忆，未经融合的结果直接由知识源构建
+	context := strings.TrimSpace(fusionResult.Context)
+	if context == "" {
+		context = buildFusionContext(fusionResult.Sources)
+	}
+	if context != "" {
+		prompt.WriteString("参考资料：\n\n")
+		prompt.WriteString(context)
+		prompt.WriteString("\n\n")
+	}
+
+	prompt.WriteString("用户问题：\n")
+	if question.Title != "" && question.Title != question.Content {
+		prompt.WriteString(question.Title)
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString(question.Content)
+
+	return []openai.Message{
+		{Role: "system", Content: answerSystemPrompt},
+		{Role: "user", Content: prompt.String()},
+	}
+}
+
+// buildFusionContext 根据融合后的知识源构建参考资料上下文
+// 编号与FusionResult.Sources的下标一一对应，合并过的知识源会列出全部来源
+func buildFusionContext(sources []KnowledgeItem) string {
+	var context strings.Builder
+	for i, source := range sources {
+		if i >= maxPromptSources {
+			break
+		}
+
+		origin := string(source.Source)
+		if merged, ok := source.Metadata["merged_sources"].([]KnowledgeSource); ok && len(merged) > 1 {
+			names := make([]string, len(merged))
+			for j, name := range merged {
+				names[j] = string(name)
+			}
+			origin = strings.Join(names, ", ")
+		}
+
+		context.WriteString(fmt.Sprintf("[%d] %s（来源: %s）\n", i+1, source.Title, origin))
+		if source.URL != "" {
+			context.WriteString(fmt.Sprintf("链接: %s\n", source.URL))
+		}
+		context.WriteString(truncateRunes(strings.TrimSpace(source.Content), maxSourceContentRunes))
+		context.WriteString("\n\n")
+	}
+	return strings.TrimSpace(context.String())
+}
+
+// buildCitationReferences 根据回答中的引用编号构建参考来源列表
+// 编号与Answer.Sources的下标一一对应（编号n对应Sources[n-1]），超出范围的编号会被忽略
+func (p *Processor) buildCitationReferences(content string, sources []KnowledgeItem) string {
+	limit := len(sources)
+	if limit > maxPromptSources {
+		limit = maxPromptSources
+	}
+
+	cited := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(content, -1) {
+		index, err := strconv.Atoi(match[1])
+		if err != nil || index < 1 || index > limit {
+			continue
+		}
+		cited[index] = true
+	}
+
+	// 回答没有标注引用时列出所有提供给模型的来源
+	if len(cited) == 0 {
+		for i := 1; i <= limit; i++ {
+			cited[i] = true
+		}
+	}
+
+	indexes := make([]int, 0, len(cited))
+	for index := range cited {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	var references strings.Builder
+	references.WriteString("\n\n参考来源：\n")
+	for _, index := range indexes {
+		source := sources[index-1]
+		if source.URL != "" {
+			references.WriteString(fmt.Sprintf("[%d] [%s](%s)\n", index, source.Title, source.URL))
+		} else {
+			references.WriteString(fmt.Sprintf("[%d] %s\n", index, source.Title))
+		}
+	}
+
+	return references.String()
+}
+
+// truncateRunes 按字符数截断文本
+func truncateRunes(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
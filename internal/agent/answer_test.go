@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answerLLM 模拟OpenAI服务，返回固定回答并记录请求
+type answerLLM struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []openai.ChatRequest
+}
+
+// newAnswerLLM 创建模拟OpenAI服务，status非200时返回错误
+func newAnswerLLM(t *testing.T, status int, content string) *answerLLM {
+	llm := &answerLLM{}
+	llm.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		llm.mutex.Lock()
+		llm.requests = append(llm.requests, request)
+		llm.mutex.Unlock()
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":{"message":"upstream unavailable","type":"server_error"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"choices": []map[string]interface{}{{"index": 0, "message": map[string]string{"role": "assistant", "content": content}}},
+		})
+	}))
+	t.Cleanup(llm.Close)
+	return llm
+}
+
+// recorded 获取收到的全部请求
+func (l *answerLLM) recorded() []openai.ChatRequest {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]openai.ChatRequest(nil), l.requests...)
+}
+
+// newAnswerProcessor 创建使用指定OpenAI服务的处理器
+func newAnswerProcessor(t *testing.T, llmURL string) *Processor {
+	config := &AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.MCP.HealthCheck.Interval = "0"
+
+	client := openai.NewClient("test-key", "gpt-4o")
+	client.SetBaseURL(llmURL)
+	processor := NewProcessor(client, config)
+	t.Cleanup(processor.Stop)
+	return processor
+}
+
+// answerFusionResult 构造包含count个知识源的融合结果，奇数编号的知识源带链接
+func answerFusionResult(count int) *FusionResult {
+	sources := make([]KnowledgeItem, count)
+	for i := range sources {
+		sources[i] = KnowledgeItem{
+			Title:     fmt.Sprintf("文档%d", i+1),
+			Content:   fmt.Sprintf("文档%d的内容：key-rate-limit 配置 limit_by_header", i+1),
+			Source:    KnowledgeSourceLocal,
+			Relevance: 0.9,
+		}
+		if i%2 == 0 {
+			sources[i].URL = fmt.Sprintf("https://higress.io/docs/%d", i+1)
+		}
+	}
+	return &FusionResult{Sources: sources, FusionScore: 0.8}
+}
+
+// TestSynthesizeAnswer 测试提示词中的编号参考资料，以及回答中的引用编号映射到Answer.Sources
+func TestSynthesizeAnswer(t *testing.T) {
+	llm := newAnswerLLM(t, http.StatusOK, "配置 limit_by_header 即可[2]，示例见[1][2]，超出范围的引用[9]会被忽略。")
+	processor := newAnswerProcessor(t, llm.URL)
+	fusionResult := answerFusionResult(10)
+	question := &Question{Title: "key-rate-limit 如何配置", Content: "key-rate-limit 插件按请求头限流怎么配置？"}
+
+	answer, err := processor.generateAnswer(context.Background(), question, fusionResult)
+	require.NoError(t, err)
+
+	// 提示词最多包含8个编号的知识源，编号与Sources下标一致
+	requests := llm.recorded()
+	require.Len(t, requests, 1)
+	messages := requests[0].Messages
+	require.Len(t, messages, 2)
+	assert.Equal(t, "system", messages[0].Role)
+	assert.Equal(t, answerSystemPrompt, messages[0].Content)
+	prompt := messages[1].Content
+	assert.True(t, strings.HasPrefix(prompt, "参考资料：\n\n[1] 文档1（来源: local）\n链接: https://higress.io/docs/1\n"))
+	assert.Contains(t, prompt, "[8] 文档8（来源: local）")
+	assert.NotContains(t, prompt, "文档9")
+	assert.True(t, strings.HasSuffix(prompt, "用户问题：\nkey-rate-limit 如何配置\nkey-rate-limit 插件按请求头限流怎么配置？"))
+
+	// 只列出被引用的来源，编号n对应Sources[n-1]
+	assert.True(t, strings.HasPrefix(answer.Content, "配置 limit_by_header 即可[2]"))
+	assert.True(t, strings.HasSuffix(answer.Content, "\n\n参考来源：\n[1] [文档1](https://higress.io/docs/1)\n[2] 文档2\n"))
+	assert.Equal(t, fusionResult.Sources, answer.Sources)
+	assert.Equal(t, "文档2", answer.Sources[1].Title)
+}
+
+// TestSynthesizeAnswerWithoutCitations 测试回答没有标注引用时列出提供给模型的前8个来源
+func TestSynthesizeAnswerWithoutCitations(t *testing.T) {
+	llm := newAnswerLLM(t, http.StatusOK, "配置 limit_by_header 即可。")
+	processor := newAnswerProcessor(t, llm.URL)
+
+	answer, err := processor.generateAnswer(context.Background(), &Question{Content: "如何限流"}, answerFusionResult(10))
+	require.NoError(t, err)
+
+	references := answer.Content[strings.Index(answer.Content, "参考来源："):]
+	assert.Equal(t, 8, strings.Count(references, "\n["))
+	assert.Contains(t, references, "[8] 文档8\n")
+	assert.NotContains(t, references, "[9]")
+}
+
+// TestSynthesizeAnswerFallback 测试LLM失败或未配置时使用模板回答
+func TestSynthesizeAnswerFallback(t *testing.T) {
+	fusionResult := answerFusionResult(3)
+	question := &Question{Content: "如何限流"}
+
+	t.Run("LLMError", func(t *testing.T) {
+		llm := newAnswerLLM(t, http.StatusInternalServerError, "")
+		processor := newAnswerProcessor(t, llm.URL)
+
+		answer, err := processor.generateAnswer(context.Background(), question, fusionResult)
+		require.NoError(t, err)
+		assert.Len(t, llm.recorded(), 1)
+		assert.Equal(t, processor.buildAnswerContent(fusionResult), answer.Content)
+		assert.Equal(t, fusionResult.Sources, answer.Sources)
+	})
+
+	t.Run("NotConfigured", func(t *testing.T) {
+		config := &AgentConfig{}
+		config.Memory.CleanupInterval = time.Minute
+		config.MCP.HealthCheck.Interval = "0"
+		processor := NewProcessor(openai.NewClient("", ""), config)
+		t.Cleanup(processor.Stop)
+
+		answer, err := processor.generateAnswer(context.Background(), question, fusionResult)
+		require.NoError(t, err)
+		assert.Equal(t, processor.buildAnswerContent(fusionResult), answer.Content)
+	})
+}
@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"hash/fnv"
+	"math"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+const (
+	// defaultDedupThreshold 未配置时判定为近似重复的Jaccard相似度
+	defaultDedupThreshold = 0.8
+	// dedupContainment 较短内容被较长内容包含的比例达到该值时同样视为重复
+	dedupContainment = 0.9
+	// shingleSize 字符级shingle长度
+	shingleSize = 5
+	// minHashSize MinHash签名长度
+	minHashSize = 128
+)
+
+// minHashSignature 内容的MinHash签名
+type minHashSignature struct {
+	values []uint64
+	size   int // shingle集合大小，用于估算包含度
+}
+
+// DeduplicateKnowledge 合并近似重复的知识源
+// sources需已按相关性降序排列：每组重复项保留排在最前的一项并合并标签，标题、链接和内容
+// 取自内容最完整的一项，并在Metadata中记录被合并项的来源；threshold为Jaccard相似度阈值，<=0时使用默认值
+func DeduplicateKnowledge(sources []KnowledgeItem, threshold float64) []KnowledgeItem {
+	if len(sources) < 2 {
+		return sources
+	}
+	if threshold <= 0 {
+		threshold = defaultDedupThreshold
+	}
+
+	signatures := make([]minHashSignature, len(sources))
+	for i, source := range sources {
+		signatures[i] = newMinHashSignature(source.Title + "\n" + source.Content)
+	}
+
+	merged := make([]KnowledgeItem, 0, len(sources))
+	kept := make([]int, 0, len(sources)) // merged中每项对应的sources下标
+	for i, source := range sources {
+		duplicateOf := -1
+		var similarity float64
+		for j, index := range kept {
+			if score, ok := isNearDuplicate(signatures[index], signatures[i], threshold); ok {
+				duplicateOf, similarity = j, score
+				break
+			}
+		}
+
+		if duplicateOf < 0 {
+			kept = append(kept, i)
+			merged = append(merged, source)
+			continue
+		}
+		mergeKnowledgeItem(&merged[duplicateOf], source, similarity)
+	}
+
+	return merged
+}
+
+// isNearDuplicate 判断两个签名是否近似重复，返回估算的Jaccard相似度
+func isNearDuplicate(a, b minHashSignature, threshold float64) (float64, bool) {
+	if a.size == 0 || b.size == 0 {
+		return 0, false
+	}
+
+	equal := 0
+	for i := range a.values {
+		if a.values[i] == b.values[i] {
+			equal++
+		}
+	}
+	jaccard := float64(equal) / float64(len(a.values))
+	if jaccard >= threshold {
+		return jaccard, true
+	}
+
+	// 由Jaccard相似度和集合大小估算较小集合被包含的比例，识别长文档中截取的片段
+	intersection := jaccard * float64(a.size+b.size) / (1 + jaccard)
+	containment := intersection / math.Min(float64(a.size), float64(b.size))
+	return jaccard, containment >= dedupContainment
+}
+
+// mergeKnowledgeItem 将重复项合并到保留项
+func mergeKnowledgeItem(target *KnowledgeItem, duplicate KnowledgeItem, similarity float64) {
+	// 元数据和标签可能与原始结果共享，写入副本
+	metadata := make(map[string]interface{}, len(target.Metadata)+2)
+	for key, value := range target.Metadata {
+		metadata[key] = value
+	}
+	target.Metadata = metadata
+	target.Tags = append([]string(nil), target.Tags...)
+
+	provenance, _ := target.Metadata["merged_from"].([]map[string]interface{})
+	target.Metadata["merged_from"] = append(provenance, map[string]interface{}{
+		"id":         duplicate.ID,
+		"source":     duplicate.Source,
+		"title":      duplicate.Title,
+		"url":        duplicate.URL,
+		"relevance":  duplicate.Relevance,
+		"similarity": similarity,
+	})
+
+	sources, _ := target.Metadata["merged_sources"].([]KnowledgeSource)
+	if len(sources) == 0 {
+		sources = []KnowledgeSource{target.Source}
+	}
+	if !containsSource(sources, duplicate.Source) {
+		sources = append(sources, duplicate.Source)
+	}
+	target.Metadata["merged_sources"] = sources
+
+	// 标题、链接和内容取自同一项，保证链接与内容对应：保留项只是重复项的片段时使用更完整的一项，
+	// 内容同样完整时使用链接更具体的一项
+	targetLength := len([]rune(strings.TrimSpace(target.Content)))
+	duplicateLength := len([]rune(strings.TrimSpace(duplicate.Content)))
+	if duplicateLength > targetLength || (duplicateLength == targetLength && urlSpecificity(duplicate.URL) > urlSpecificity(target.URL)) {
+		target.Title, target.URL, target.Content = duplicate.Title, duplicate.URL, duplicate.Content
+	}
+	for _, tag := range duplicate.Tags {
+		if !containsTag(target.Tags, tag) {
+			target.Tags = append(target.Tags, tag)
+		}
+	}
+}
+
+// newMinHashSignature 计算内容的MinHash签名
+// 内容先归一化为小写并压缩空白与标点，再按字符切分shingle，中英文混合内容均适用
+func newMinHashSignature(text string) minHashSignature {
+	shingles := make(map[uint64]struct{})
+	runes := normalizeForShingles(text)
+	if len(runes) > 0 && len(runes) < shingleSize {
+		shingles[hashShingle(runes)] = struct{}{}
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		shingles[hashShingle(runes[i:i+shingleSize])] = struct{}{}
+	}
+
+	values := make([]uint64, minHashSize)
+	for i := range values {
+		values[i] = math.MaxUint64
+	}
+	for shingle := range shingles {
+		for i := range values {
+			if value := mixHash(shingle + uint64(i)*0x9e3779b97f4a7c15); value < values[i] {
+				values[i] = value
+			}
+		}
+	}
+
+	return minHashSignature{values: values, size: len(shingles)}
+}
+
+// normalizeForShingles 归一化文本：小写、标点视为空白、连续空白压缩为一个
+func normalizeForShingles(text string) []rune {
+	runes := make([]rune, 0, len(text))
+	space := true
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			if !space {
+				runes = append(runes, ' ')
+				space = true
+			}
+			continue
+		}
+		runes = append(runes, r)
+		space = false
+	}
+	if len(runes) > 0 && runes[len(runes)-1] == ' ' {
+		runes = runes[:len(runes)-1]
+	}
+	return runes
+}
+
+// hashShingle 计算shingle的哈希值
+func hashShingle(runes []rune) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(string(runes)))
+	return hasher.Sum64()
+}
+
+// mixHash splitmix64混合函数，以不同种子模拟多个独立哈希函数
+func mixHash(value uint64) uint64 {
+	value ^= value >> 30
+	value *= 0xbf58476d1ce4e5b9
+	value ^= value >> 27
+	value *= 0x94d049bb133111eb
+	value ^= value >> 31
+	return value
+}
+
+// urlSpecificity URL的具体程度，路径层级越深越具体，空URL为-1
+func urlSpecificity(rawURL string) int {
+	if rawURL == "" {
+		return -1
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	depth := 0
+	for _, segment := range strings.Split(parsed.Path, "/") {
+		if segment != "" {
+			depth++
+		}
+	}
+	if parsed.Fragment != "" {
+		depth++
+	}
+	return depth
+}
+
+// containsSource 判断来源列表是否包含指定来源
+func containsSource(sources []KnowledgeSource, source KnowledgeSource) bool {
+	for _, s := range sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// containsTag 判断标签列表是否包含指定标签（忽略大小写）
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
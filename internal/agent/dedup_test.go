@@ -0,0 +1,86 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDeduplicateKnowledge 测试近似重复知识源合并
+func TestDeduplicateKnowledge(t *testing.T) {
+	passage := "key-rate-limit 插件可以按照请求头或 URL 参数对请求进行限流。配置 limit_by_header 指定限流依据的请求头，" +
+		"limit_keys 中为每个键设置 query_per_second 或 query_per_minute。超过限制时返回 429 状态码。"
+
+	sources := []model.KnowledgeItem{
+		{
+			ID:        "higress-1",
+			Source:    model.KnowledgeSourceHigress,
+			Title:     "key-rate-limit",
+			Content:   passage,
+			URL:       "https://higress.io/docs",
+			Relevance: 0.9,
+			Tags:      []string{"higress", "plugin"},
+		},
+		{
+			ID:        "deepwiki-1",
+			Source:    model.KnowledgeSourceDeepWiki,
+			Title:     "Key-Rate-Limit",
+			Content:   passage + " ",
+			URL:       "https://higress.io/docs/plugins/traffic/key-rate-limit",
+			Relevance: 0.8,
+			Tags:      []string{"Plugin", "rate-limit"},
+		},
+		{
+			ID:        "local-1",
+			Source:    model.KnowledgeSourceLocal,
+			Title:     "ai-proxy",
+			Content:   "ai-proxy 插件将请求转发到 OpenAI、通义千问等大模型服务，支持流式响应。",
+			Relevance: 0.5,
+		},
+	}
+
+	merged := agent.DeduplicateKnowledge(sources, 0.8)
+	require.Len(t, merged, 2)
+
+	primary := merged[0]
+	assert.Equal(t, "higress-1", primary.ID)
+	// 内容同样完整时标题和链接取自链接更具体的一项
+	assert.Equal(t, "Key-Rate-Limit", primary.Title)
+	assert.Equal(t, "https://higress.io/docs/plugins/traffic/key-rate-limit", primary.URL)
+	assert.ElementsMatch(t, []string{"higress", "plugin", "rate-limit"}, primary.Tags)
+	assert.Equal(t, []model.KnowledgeSource{model.KnowledgeSourceHigress, model.KnowledgeSourceDeepWiki}, primary.Metadata["merged_sources"])
+
+	provenance, ok := primary.Metadata["merged_from"].([]map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, provenance, 1)
+	assert.Equal(t, "deepwiki-1", provenance[0]["id"])
+
+	assert.Equal(t, "local-1", merged[1].ID)
+	assert.Nil(t, merged[1].Metadata)
+
+	// 不修改原始结果
+	assert.Nil(t, sources[0].Metadata)
+	assert.Equal(t, []string{"higress", "plugin"}, sources[0].Tags)
+}
+
+// TestDeduplicateKnowledgeSnippet 测试长文档中截取的片段被识别为重复
+func TestDeduplicateKnowledgeSnippet(t *testing.T) {
+	snippet := "Higress 网关支持通过 Wasm 插件扩展能力，插件可以使用 Go、Rust 或 AssemblyScript 编写，并通过 WasmPlugin 资源下发到网关。"
+	document := "Higress 是基于 Istio 和 Envoy 的云原生 API 网关。" + snippet + "插件配置变更会实时生效，不会中断现有连接。"
+
+	sources := []model.KnowledgeItem{
+		{ID: "snippet", Source: model.KnowledgeSourceHigress, Title: "Wasm插件", Content: snippet, URL: "https://higress.io/docs/plugins/wasm/overview", Relevance: 0.9},
+		{ID: "document", Source: model.KnowledgeSourceLocal, Title: "Higress介绍", Content: document, URL: "https://higress.io/docs", Relevance: 0.7},
+	}
+
+	merged := agent.DeduplicateKnowledge(sources, 0.8)
+	require.Len(t, merged, 1)
+	assert.Equal(t, "snippet", merged[0].ID)
+	// 标题、链接和内容取自同一项
+	assert.Equal(t, "Higress介绍", merged[0].Title)
+	assert.Equal(t, "https://higress.io/docs", merged[0].URL)
+	assert.Equal(t, document, merged[0].Content)
+}
@@ -0,0 +1,349 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/tools"
+)
+
+const (
+	// defaultDeepWikiServer 默认的DeepWiki MCP服务器标签
+	defaultDeepWikiServer = "deepwiki"
+	// deepWikiBaseURL DeepWiki页面地址
+	deepWikiBaseURL = "https://deepwiki.com"
+	// defaultDeepWikiCacheTTL Wiki目录和内容的默认缓存时间
+	defaultDeepWikiCacheTTL = time.Hour
+	// maxDeepWikiPages 每个问题最多引用的Wiki页面数
+	maxDeepWikiPages = 3
+	// deepWikiPageRunes 单个Wiki页面内容的最大字符数
+	deepWikiPageRunes = 3000
+
+	// DeepWiki MCP服务器提供的工具
+	deepWikiToolStructure = "read_wiki_structure"
+	deepWikiToolContents  = "read_wiki_contents"
+	deepWikiToolAsk       = "ask_question"
+)
+
+var (
+	// deepWikiPagePattern 匹配Wiki目录中的页面行，如 "- 2.1 Plugin System"
+	deepWikiPagePattern = regexp.MustCompile(`^\s*[-*]\s+(\d+(?:\.\d+)*)\.?\s+(.+?)\s*$`)
+	// deepWikiContentsPattern 匹配Wiki内容中的页面分隔行，如 "# Page: Plugin System"
+	deepWikiContentsPattern = regexp.MustCompile(`(?m)^#\s+Page:\s*(.+?)\s*$`)
+)
+
+// deepWikiPage Wiki目录中的页面
+type deepWikiPage struct {
+	Number string
+	Title  string
+}
+
+// deepWikiCacheEntry Wiki工具输出缓存
+type deepWikiCacheEntry struct {
+	output    string
+	expiresAt time.Time
+}
+
+// deepWikiCache 缓存与问题无关的Wiki目录和内容，避免每次检索都重新读取
+type deepWikiCache struct {
+	entries map[string]deepWikiCacheEntry
+	mutex   sync.Mutex
+}
+
+// retrieveDeepWiki 通过DeepWiki MCP服务器检索Higress仓库的Wiki
+// 先读取Wiki目录选出与问题相关的页面，再并发调用ask_question获取问答结果、read_wiki_contents读取相关页面内容；
+// 服务器不可用时使用备用数据
+func (p *Processor) retrieveDeepWiki(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
+	p.logger.Info("开始检索DeepWiki")
+
+	server := defaultString(p.config.DeepWiki.Server, defaultDeepWikiServer)
+	if !p.mcpManager.IsServerEnabled(server) {
+		return p.deepWikiFallback(question, fmt.Errorf("MCP服务器 %s 未启用", server))
+	}
+
+	repo := fmt.Sprintf("%s/%s", defaultString(p.config.Higress.RepoOwner, "alibaba"), defaultString(p.config.Higress.RepoName, "higress"))
+	source := p.mcpManager.SourceType(server)
+
+	var (
+		wg          sync.WaitGroup
+		answerItem  *KnowledgeItem
+		pageItems   []KnowledgeItem
+		askErr      error
+		contentsErr error
+	)
+
+	if p.deepWikiToolAllowed(server, deepWikiToolAsk) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answerItem, askErr = p.askDeepWiki(ctx, server, repo, question)
+		}()
+	}
+
+	if p.deepWikiToolAllowed(server, deepWikiToolStructure) {
+		pages, err := p.readDeepWikiStructure(ctx, server, repo)
+		if err != nil {
+			contentsErr = err
+		} else if selected := selectDeepWikiPages(pages, question, maxDeepWikiPages); len(selected) > 0 && p.deepWikiToolAllowed(server, deepWikiToolContents) {
+			pageItems, contentsErr = p.readDeepWikiPages(ctx, server, repo, selected, question)
+		}
+	}
+	wg.Wait()
+
+	var items []KnowledgeItem
+	if answerItem != nil {
+		items = append(items, *answerItem)
+	}
+	items = append(items, pageItems...)
+	for i := range items {
+		items[i].Source = source
+		items[i].Metadata["server"] = server
+		items[i].Metadata["repo"] = repo
+	}
+
+	if len(items) == 0 {
+		err := askErr
+		if err == nil {
+			err = contentsErr
+		}
+		if err != nil {
+			return p.deepWikiFallback(question, err)
+		}
+	}
+
+	p.logger.WithField("results_count", len(items)).Info("DeepWiki检索完成")
+	return items, nil
+}
+
+// deepWikiFallback DeepWiki不可用时使用备用数据，没有匹配的备用数据时返回错误
+func (p *Processor) deepWikiFallback(question *Question, err error) ([]KnowledgeItem, error) {
+	items := p.convertFallbackToKnowledgeItems(question, p.fallbackStrategy.GetDeepWikiFallbackData(), KnowledgeSourceDeepWiki)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("DeepWiki检索失败: %w", err)
+	}
+	p.logger.WithError(err).Warn("DeepWiki检索失败，使用备用数据")
+	return items, nil
+}
+
+// askDeepWiki 调用ask_question针对仓库提问
+func (p *Processor) askDeepWiki(ctx context.Context, server, repo string, question *Question) (*KnowledgeItem, error) {
+	answer, err := p.callDeepWikiTool(ctx, server, deepWikiToolAsk, map[string]interface{}{
+		"repoName": repo,
+		"question": questionText(question),
+	})
+	if err != nil {
+		return nil, err
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, nil
+	}
+
+	return &KnowledgeItem{
+		ID:        "deepwiki_answer_" + repo,
+		Title:     fmt.Sprintf("DeepWiki问答: %s", repo),
+		Content:   answer,
+		URL:       fmt.Sprintf("%s/%s", deepWikiBaseURL, repo),
+		Relevance: 0.9,
+		Tags:      []string{"deepwiki", "answer"},
+		CreatedAt: time.Now(),
+		Metadata: map[string]interface{}{
+			"source": "deepwiki_mcp",
+			"tool":   deepWikiToolAsk,
+		},
+	}, nil
+}
+
+// readDeepWikiStructure 读取Wiki目录，结果按缓存时间缓存
+func (p *Processor) readDeepWikiStructure(ctx context.Context, server, repo string) ([]deepWikiPage, error) {
+	output, err := p.cachedDeepWikiTool(ctx, server, deepWikiToolStructure, repo)
+	if err != nil {
+		return nil, err
+	}
+	return parseDeepWikiStructure(output), nil
+}
+
+// readDeepWikiPages 读取Wiki内容并提取选中的页面
+func (p *Processor) readDeepWikiPages(ctx context.Context, server, repo string, pages []deepWikiPage, question *Question) ([]KnowledgeItem, error) {
+	output, err := p.cachedDeepWikiTool(ctx, server, deepWikiToolContents, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	contents := splitDeepWikiContents(output)
+	var items []KnowledgeItem
+	for _, page := range pages {
+		content, ok := contents[strings.ToLower(page.Title)]
+		if !ok || content == "" {
+			continue
+		}
+
+		item := KnowledgeItem{
+			ID:        fmt.Sprintf("deepwiki_%s_%s", repo, page.Number),
+			Title:     fmt.Sprintf("%s %s", page.Number, page.Title),
+			Content:   truncateRunes(content, deepWikiPageRunes),
+			URL:       fmt.Sprintf("%s/%s/%s", deepWikiBaseURL, repo, deepWikiPageSlug(page)),
+			Tags:      []string{"deepwiki", "wiki"},
+			CreatedAt: time.Now(),
+			Metadata: map[string]interface{}{
+				"source": "deepwiki_mcp",
+				"tool":   deepWikiToolContents,
+				"page":   page.Number,
+			},
+		}
+		item.Relevance = keywordRelevance(question, &item)
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// cachedDeepWikiTool 调用只依赖仓库的Wiki工具，结果按higress.cache_duration缓存
+func (p *Processor) cachedDeepWikiTool(ctx context.Context, server, tool, repo string) (string, error) {
+	key := server + "|" + tool + "|" + repo
+
+	p.deepWikiCache.mutex.Lock()
+	entry, ok := p.deepWikiCache.entries[key]
+	p.deepWikiCache.mutex.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.output, nil
+	}
+
+	output, err := p.callDeepWikiTool(ctx, server, tool, map[string]interface{}{"repoName": repo})
+	if err != nil {
+		return "", err
+	}
+
+	ttl := defaultDeepWikiCacheTTL
+	if duration, err := time.ParseDuration(p.config.Higress.CacheDuration); err == nil && duration > 0 {
+		ttl = duration
+	}
+
+	p.deepWikiCache.mutex.Lock()
+	p.deepWikiCache.entries[key] = deepWikiCacheEntry{output: output, expiresAt: time.Now().Add(ttl)}
+	p.deepWikiCache.mutex.Unlock()
+	return output, nil
+}
+
+// callDeepWikiTool 调用DeepWiki工具并返回文本内容
+func (p *Processor) callDeepWikiTool(ctx context.Context, server, tool string, arguments map[string]interface{}) (string, error) {
+	response, err := p.mcpManager.CallTool(ctx, server, tool, arguments)
+	if err != nil {
+		return "", fmt.Errorf("调用DeepWiki工具%s失败: %w", tool, err)
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("DeepWiki工具%s返回错误: %s", tool, response.Error)
+	}
+	return mcp.ToolResultText(response.Output), nil
+}
+
+// deepWikiToolAllowed 服务器配置了allowed_tools时只调用其中的工具
+func (p *Processor) deepWikiToolAllowed(server, tool string) bool {
+	config, exists := p.mcpManager.GetServerConfig(server)
+	return !exists || mcp.ToolAllowed(config, tool)
+}
+
+// parseDeepWikiStructure 解析Wiki目录中的页面编号和标题
+func parseDeepWikiStructure(output string) []deepWikiPage {
+	var pages []deepWikiPage
+	for _, line := range strings.Split(output, "\n") {
+		if match := deepWikiPagePattern.FindStringSubmatch(line); match != nil {
+			pages = append(pages, deepWikiPage{Number: match[1], Title: match[2]})
+		}
+	}
+	return pages
+}
+
+// splitDeepWikiContents 按页面分隔行拆分Wiki内容，返回小写标题到页面内容的映射
+func splitDeepWikiContents(output string) map[string]string {
+	contents := make(map[string]string)
+	matches := deepWikiContentsPattern.FindAllStringSubmatchIndex(output, -1)
+	for i, match := range matches {
+		end := len(output)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		title := strings.ToLower(output[match[2]:match[3]])
+		contents[title] = strings.TrimSpace(output[match[1]:end])
+	}
+	return contents
+}
+
+// selectDeepWikiPages 按标题与问题的词元重合度选出相关页面
+// 英文词元按前缀匹配（如limit与Limiting），目录标题多为英文时中文问题依靠其中的插件名和配置项匹配
+func selectDeepWikiPages(pages []deepWikiPage, question *Question, limit int) []deepWikiPage {
+	var questionTerms []string
+	for _, token := range tools.Tokenize(questionText(question)) {
+		if len([]rune(token)) >= 2 && !isSearchStopWord(token) {
+			questionTerms = append(questionTerms, token)
+		}
+	}
+
+	type scoredPage struct {
+		page  deepWikiPage
+		score int
+	}
+	var scored []scoredPage
+	for _, page := range pages {
+		score := 0
+		for _, titleTerm := range tools.Tokenize(page.Title) {
+			for _, term := range questionTerms {
+				if deepWikiTermMatch(term, titleTerm) {
+					score++
+					break
+				}
+			}
+		}
+		if score > 0 {
+			scored = append(scored, scoredPage{page: page, score: score})
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	var selected []deepWikiPage
+	for _, item := range scored {
+		if len(selected) >= limit {
+			break
+		}
+		selected = append(selected, item.page)
+	}
+	return selected
+}
+
+// deepWikiTermMatch 判断问题词元与标题词元是否匹配，长度不少于4的词元允许前缀匹配
+func deepWikiTermMatch(term, titleTerm string) bool {
+	if term == titleTerm {
+		return true
+	}
+	if len(term) < 4 || len(titleTerm) < 4 {
+		return false
+	}
+	return strings.HasPrefix(term, titleTerm) || strings.HasPrefix(titleTerm, term)
+}
+
+// deepWikiPageSlug 生成DeepWiki页面路径，如 "2.1 Plugin System" -> "2.1-plugin-system"
+func deepWikiPageSlug(page deepWikiPage) string {
+	var builder strings.Builder
+	builder.WriteString(page.Number)
+	builder.WriteRune('-')
+	lastDash := true
+	for _, r := range strings.ToLower(page.Title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+			lastDash = false
+		} else if !lastDash {
+			builder.WriteRune('-')
+			lastDash = true
+		}
+	}
+	return strings.TrimRight(builder.String(), "-")
+}
@@ -0,0 +1,155 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const wikiStructure = `Available pages for alibaba/higress:
+
+- 1 Overview
+- 2 Plugin System
+  - 2.1 Wasm Plugin Development
+  - 2.2 Rate Limiting Plugins
+- 3 Deployment
+`
+
+const wikiContents = `# Page: Overview
+
+Higress is a cloud-native API gateway.
+
+# Page: Rate Limiting Plugins
+
+key-rate-limit limits requests by limit_by_header or limit_by_param.
+
+# Page: Deployment
+
+Install with helm.
+`
+
+// newFakeDeepWikiServer 创建模拟DeepWiki MCP服务的服务器
+func newFakeDeepWikiServer(t *testing.T) *mcptest.Server {
+	server := mcptest.NewServer(t, "deepwiki")
+	reply := func(text string) mcptest.ToolFunc {
+		return func(_ *mcptest.Request, arguments map[string]interface{}) (string, error) {
+			assert.Equal(t, "alibaba/higress", arguments["repoName"])
+			return text, nil
+		}
+	}
+	server.AddTool("read_wiki_structure", "", reply(wikiStructure))
+	server.AddTool("read_wiki_contents", "", reply(wikiContents))
+	server.AddTool("ask_question", "", func(request *mcptest.Request, arguments map[string]interface{}) (string, error) {
+		assert.Contains(t, arguments["question"], "key-rate-limit")
+		return reply("使用 key-rate-limit 插件并配置 limit_by_header。")(request, arguments)
+	})
+	return server
+}
+
+// TestDeepWikiRetrieval 测试通过MCP工具检索DeepWiki并按页面生成知识项
+func TestDeepWikiRetrieval(t *testing.T) {
+	server := newFakeDeepWikiServer(t)
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.DeepWiki.Enabled = true
+	config.Higress.RepoOwner = "alibaba"
+	config.Higress.RepoName = "higress"
+	config.MCP.Servers = map[string]model.MCPServer{
+		"deepwiki": {Enabled: true, ServerURL: server.URL, SourceType: "wiki"},
+	}
+	config.Retrieval.Sources = []model.RetrieverConfig{{Name: "deepwiki", Type: "deepwiki", Enabled: true}}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	retriever, ok := processor.GetRetrieverRegistry().Get("deepwiki")
+	require.True(t, ok)
+
+	question := &model.Question{Title: "key-rate-limit 限流不生效", Content: "rate limit 插件如何配置"}
+	items, err := retriever.Retrieve(context.Background(), question)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	answer := items[0]
+	assert.Equal(t, model.KnowledgeSource("wiki"), answer.Source)
+	assert.Equal(t, "使用 key-rate-limit 插件并配置 limit_by_header。", answer.Content)
+	assert.Equal(t, "ask_question", answer.Metadata["tool"])
+	assert.Equal(t, "https://deepwiki.com/alibaba/higress", answer.URL)
+
+	page := items[1]
+	assert.Equal(t, "2.2 Rate Limiting Plugins", page.Title)
+	assert.Equal(t, "https://deepwiki.com/alibaba/higress/2.2-rate-limiting-plugins", page.URL)
+	assert.Contains(t, page.Content, "limit_by_header")
+	assert.NotContains(t, page.Content, "helm")
+	assert.Equal(t, "deepwiki", page.Metadata["server"])
+
+	// Wiki目录和内容被缓存，问答每次调用
+	_, err = retriever.Retrieve(context.Background(), question)
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.Calls("read_wiki_structure"))
+	assert.Equal(t, 1, server.Calls("read_wiki_contents"))
+	assert.Equal(t, 2, server.Calls("ask_question"))
+}
+
+// TestDeepWikiSourceType 测试并发检索后保留MCP服务器配置的source_type
+func TestDeepWikiSourceType(t *testing.T) {
+	server := newFakeDeepWikiServer(t)
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.DeepWiki.Enabled = true
+	config.MCP.Servers = map[string]model.MCPServer{
+		"deepwiki": {Enabled: true, ServerURL: server.URL, SourceType: "wiki"},
+	}
+	config.Retrieval.Sources = []model.RetrieverConfig{{Name: "deepwiki", Type: "deepwiki", Enabled: true}}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	response, err := processor.ProcessQuestion(context.Background(), &model.ProcessRequest{
+		Type:    model.QuestionTypeText,
+		Title:   "key-rate-limit 限流不生效",
+		Content: "rate limit 插件如何配置",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, response.Sources)
+	for _, source := range response.Sources {
+		assert.Equal(t, model.KnowledgeSource("wiki"), source.Source)
+	}
+	require.Len(t, response.SourceStatuses, 1)
+	assert.Equal(t, model.KnowledgeSource("deepwiki"), response.SourceStatuses[0].Source)
+}
+
+// TestDeepWikiAllowedTools 测试只调用服务器配置允许的工具
+func TestDeepWikiAllowedTools(t *testing.T) {
+	server := newFakeDeepWikiServer(t)
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.DeepWiki.Enabled = true
+	config.MCP.Servers = map[string]model.MCPServer{
+		"deepwiki": {Enabled: true, ServerURL: server.URL, AllowedTools: []string{"ask_question"}},
+	}
+	config.Retrieval.Sources = []model.RetrieverConfig{{Name: "deepwiki", Type: "deepwiki", Enabled: true}}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	retriever, _ := processor.GetRetrieverRegistry().Get("deepwiki")
+	items, err := retriever.Retrieve(context.Background(), &model.Question{Content: "key-rate-limit rate limit"})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, model.KnowledgeSource("deepwiki"), items[0].Source)
+	assert.Zero(t, server.Calls("read_wiki_structure"))
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/tools"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultDocsBaseURL 文档站点地址，用于生成章节链接
+	defaultDocsBaseURL = "https://higress.io/docs"
+	// docsMirrorMaxResults 从文档镜像中检索的最大章节数
+	docsMirrorMaxResults = 5
+	// docsMirrorSource 来自文档镜像的知识项在元数据中的来源标识
+	docsMirrorSource = "docs_mirror"
+)
+
+// SyncDocs 将Higress文档同步到本地知识库的文档镜像
+// 配置了docs_path时读取本地文档仓库，否则下载docs_archive_url归档；同步后更新检索索引
+func (p *Processor) SyncDocs(ctx context.Context) (*tools.IngestReport, error) {
+	higressConfig := p.config.Higress
+	ingester := tools.NewDocsIngester(p.knowledgeBase, defaultString(higressConfig.DocsURL, defaultDocsBaseURL))
+
+	var (
+		report *tools.IngestReport
+		err    error
+	)
+	switch {
+	case higressConfig.DocsPath != "":
+		report, err = ingester.IngestDirectory(ctx, higressConfig.DocsPath)
+	case higressConfig.DocsArchiveURL != "":
+		report, err = ingester.IngestArchive(ctx, higressConfig.DocsArchiveURL, higressConfig.DocsArchiveDir)
+	default:
+		return nil, fmt.Errorf("未配置文档来源: 需要设置docs_path或docs_archive_url")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("同步Higress文档失败: %w", err)
+	}
+
+	// 索引失败不影响同步结果，检索时会重试
+	if err := p.knowledgeBase.Reindex(ctx); err != nil {
+		p.logger.WithError(err).Warn("同步文档后更新索引失败")
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"files":     report.Files,
+		"sections":  report.Sections,
+		"added":     report.Added,
+		"updated":   report.Updated,
+		"unchanged": report.Unchanged,
+		"removed":   report.Removed,
+		"duration":  report.Duration,
+	}).Info("Higress文档镜像同步完成")
+	return report, nil
+}
+
+// startDocsSync 按docs_sync_interval定时同步文档镜像，启动时立即同步一次
+func (p *Processor) startDocsSync() {
+	higressConfig := p.config.Higress
+	if higressConfig.DocsSyncInterval == "" || (higressConfig.DocsPath == "" && higressConfig.DocsArchiveURL == "") {
+		return
+	}
+
+	interval, err := time.ParseDuration(higressConfig.DocsSyncInterval)
+	if err != nil || interval <= 0 {
+		p.logger.WithField("interval", higressConfig.DocsSyncInterval).Warn("文档同步间隔配置无效，不定时同步")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stopDocsSync = cancel
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := p.SyncDocs(ctx); err != nil && ctx.Err() == nil {
+				p.logger.WithError(err).Warn("定时同步Higress文档失败")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// retrieveDocsMirror 从本地知识库的文档镜像中检索Higress文档章节
+func (p *Processor) retrieveDocsMirror(ctx context.Context, question *Question) []KnowledgeItem {
+	if p.knowledgeBase == nil {
+		return nil
+	}
+
+	query := question.Title + " " + question.Content
+	searchResult, err := p.knowledgeBase.SearchKnowledgeFiltered(ctx, query, docsMirrorMaxResults, isDocsMirrorDocument)
+	if err != nil {
+		p.logger.WithError(err).Warn("检索Higress文档镜像失败")
+		return nil
+	}
+
+	var items []KnowledgeItem
+	for _, result := range searchResult.Results {
+		doc, err := p.knowledgeBase.GetDocument(result.DocumentID)
+		if err != nil {
+			continue
+		}
+		items = append(items, KnowledgeItem{
+			ID:        result.DocumentID,
+			Source:    KnowledgeSourceHigress,
+			Title:     result.Title,
+			Content:   result.Content,
+			URL:       doc.URL,
+			Relevance: result.RelevanceScore,
+			Tags:      doc.Tags,
+			CreatedAt: doc.UpdatedAt,
+			Metadata: map[string]interface{}{
+				"source":      docsMirrorSource,
+				"snippet":     result.Snippet,
+				"chunk_index": result.ChunkIndex,
+				"synced_at":   doc.UpdatedAt,
+			},
+		})
+	}
+	return items
+}
+
+// isDocsMirrorDocument 判断文档是否属于Higress文档镜像
+func isDocsMirrorDocument(doc model.Document) bool {
+	return doc.Source == tools.DocsSource
+}
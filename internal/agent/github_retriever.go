@@ -0,0 +1,381 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/tools"
+)
+
+const (
+	// defaultGitHubMaxResults 默认返回的Issue和讨论数量上限
+	defaultGitHubMaxResults = 5
+	// maxGitHubSearchTerms GitHub搜索的最大关键词数，搜索词之间为AND关系，过多会导致无结果
+	maxGitHubSearchTerms = 4
+	// githubQuestionRunes 知识项中问题描述的最大字符数
+	githubQuestionRunes = 600
+	// githubAnswerRunes 知识项中解答的最大字符数
+	githubAnswerRunes = 1500
+	// unansweredRelevanceFactor 未找到解答的Issue相关性折扣
+	unansweredRelevanceFactor = 0.6
+)
+
+// maintainerAssociations 视为维护者的仓库关系
+var maintainerAssociations = map[string]bool{
+	"OWNER":        true,
+	"MEMBER":       true,
+	"COLLABORATOR": true,
+}
+
+// GitHubRetriever 从GitHub Issue和讨论中检索已解答的相似问题
+// 讨论优先使用被采纳的回答，Issue使用维护者评论中获得正面反馈最多的一条作为解答
+type GitHubRetriever struct {
+	name        string
+	enabled     bool
+	manager     *tools.GitHubManager
+	owner       string
+	repo        string
+	maxResults  int
+	discussions bool
+}
+
+// newGitHubRetriever 根据配置创建GitHub检索器
+// 选项：owner、repo（默认使用higress配置的仓库）、max_results、discussions（是否检索讨论，默认true）
+func newGitHubRetriever(p *Processor, config RetrieverConfig) (Retriever, error) {
+	// 展开${ENV}占位符，环境变量未设置时不使用令牌
+	token := os.ExpandEnv(p.config.GitHub.Token)
+	manager := tools.NewGitHubManager(token)
+	if p.config.GitHub.APIURL != "" {
+		manager.SetBaseURL(p.config.GitHub.APIURL)
+	}
+
+	maxResults := defaultGitHubMaxResults
+	switch value := config.Options["max_results"].(type) {
+	case int:
+		maxResults = value
+	case float64:
+		maxResults = int(value)
+	}
+
+	discussions := true
+	if value, ok := config.Options["discussions"].(bool); ok {
+		discussions = value
+	}
+
+	return &GitHubRetriever{
+		name:        config.Name,
+		enabled:     config.Enabled,
+		manager:     manager,
+		owner:       optionString(config.Options, "owner", defaultString(p.config.Higress.RepoOwner, "alibaba")),
+		repo:        optionString(config.Options, "repo", defaultString(p.config.Higress.RepoName, "higress")),
+		maxResults:  maxResults,
+		discussions: discussions && token != "",
+	}, nil
+}
+
+// Name 知识源名称
+func (r *GitHubRetriever) Name() string {
+	return r.name
+}
+
+// Enabled 是否参与检索
+func (r *GitHubRetriever) Enabled() bool {
+	return r.enabled
+}
+
+// Retrieve 并发搜索Issue和讨论并提取解答
+func (r *GitHubRetriever) Retrieve(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
+	terms := githubSearchTerms(question)
+	if terms == "" {
+		return []KnowledgeItem{}, nil
+	}
+
+	var (
+		wg                          sync.WaitGroup
+		issueItems, discussionItems []KnowledgeItem
+		issueErr, discussionErr     error
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		issueItems, issueErr = r.retrieveIssues(ctx, terms)
+	}()
+	if r.discussions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			discussionItems, discussionErr = r.retrieveDiscussions(ctx, terms)
+		}()
+	}
+	wg.Wait()
+
+	if issueErr != nil && (discussionErr != nil || !r.discussions) {
+		return nil, issueErr
+	}
+
+	// 按相关性合并，相同时讨论（通常有被采纳的回答）在前
+	items := append(discussionItems, issueItems...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Relevance > items[j].Relevance
+	})
+	if len(items) > r.maxResults {
+		items = items[:r.maxResults]
+	}
+	return items, nil
+}
+
+// retrieveIssues 搜索有评论的Issue并提取解答
+func (r *GitHubRetriever) retrieveIssues(ctx context.Context, terms string) ([]KnowledgeItem, error) {
+	issues, err := r.manager.SearchIssuesContext(ctx, terms+" is:issue comments:>0", r.owner, r.repo)
+	if err != nil {
+		return nil, fmt.Errorf("搜索GitHub Issue失败: %w", err)
+	}
+	if len(issues) > r.maxResults {
+		issues = issues[:r.maxResults]
+	}
+
+	items := make([]KnowledgeItem, len(issues))
+	var wg sync.WaitGroup
+	for i, issue := range issues {
+		wg.Add(1)
+		go func(i int, issue *model.GitHubIssue) {
+			defer wg.Done()
+
+			// 评论获取失败时仍返回Issue本身
+			comments, _ := r.manager.GetCommentsContext(ctx, r.owner, r.repo, issue.Number)
+			author := ""
+			if issue.User != nil {
+				author = issue.User.Login
+			}
+			answer, answerType := selectGitHubAnswer(comments, author)
+
+			item := newGitHubKnowledgeItem("issue", issue.Number, issue.Title, issue.Body, issue.HTMLURL, answer, answerType, rankRelevance(i, len(issues)))
+			item.Tags = append(item.Tags, issue.Labels...)
+			item.Metadata["state"] = issue.State
+			if createdAt, err := time.Parse(time.RFC3339, issue.CreatedAt); err == nil {
+				item.CreatedAt = createdAt
+			}
+			items[i] = item
+		}(i, issue)
+	}
+	wg.Wait()
+
+	return items, nil
+}
+
+// retrieveDiscussions 搜索讨论并提取被采纳的回答
+func (r *GitHubRetriever) retrieveDiscussions(ctx context.Context, terms string) ([]KnowledgeItem, error) {
+	discussions, err := r.manager.SearchDiscussionsContext(ctx, terms, r.owner, r.repo, r.maxResults)
+	if err != nil {
+		return nil, fmt.Errorf("搜索GitHub讨论失败: %w", err)
+	}
+
+	var items []KnowledgeItem
+	for i, discussion := range discussions {
+		answer, answerType := discussion.Answer, "accepted"
+		if answer == nil {
+			author := ""
+			if discussion.User != nil {
+				author = discussion.User.Login
+			}
+			answer, answerType = selectGitHubAnswer(discussion.Comments, author)
+		}
+		// 讨论没有任何解答时参考价值较低
+		if answer == nil {
+			continue
+		}
+
+		item := newGitHubKnowledgeItem("discussion", discussion.Number, discussion.Title, discussion.Body, discussion.URL, answer, answerType, rankRelevance(i, len(discussions)))
+		if discussion.Category != "" {
+			item.Tags = append(item.Tags, discussion.Category)
+		}
+		if createdAt, err := time.Parse(time.RFC3339, discussion.CreatedAt); err == nil {
+			item.CreatedAt = createdAt
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// newGitHubKnowledgeItem 将Issue或讨论及其解答转换为知识项
+func newGitHubKnowledgeItem(kind string, number int, title, body, htmlURL string, answer *model.GitHubComment, answerType string, relevance float64) KnowledgeItem {
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("问题 #%d: %s\n", number, title))
+	if body = strings.TrimSpace(body); body != "" {
+		content.WriteString(truncateRunes(body, githubQuestionRunes))
+		content.WriteString("\n")
+	}
+
+	metadata := map[string]interface{}{
+		"source": "github_" + kind,
+		"kind":   kind,
+		"number": number,
+	}
+
+	if answer != nil {
+		answeredBy := "unknown"
+		if answer.User != nil {
+			answeredBy = answer.User.Login
+		}
+		content.WriteString(fmt.Sprintf("\n解答（@%s，%s）:\n", answeredBy, answerTypeLabel(answerType)))
+		content.WriteString(truncateRunes(strings.TrimSpace(answer.Body), githubAnswerRunes))
+
+		metadata["answer_type"] = answerType
+		metadata["answered_by"] = answeredBy
+		metadata["answer_url"] = answer.HTMLURL
+	} else {
+		relevance *= unansweredRelevanceFactor
+	}
+
+	prefix := "#"
+	if kind == "discussion" {
+		prefix = "讨论 #"
+	}
+
+	return KnowledgeItem{
+		ID:        fmt.Sprintf("github_%s_%d", kind, number),
+		Source:    KnowledgeSourceGitHub,
+		Title:     fmt.Sprintf("%s%d %s", prefix, number, title),
+		Content:   content.String(),
+		URL:       htmlURL,
+		Relevance: relevance,
+		Tags:      []string{"github", kind},
+		CreatedAt: time.Now(),
+		Metadata:  metadata,
+	}
+}
+
+// selectGitHubAnswer 从评论中选出解答
+// 优先选择维护者评论中正面反馈最多的一条（相同时取较新的），没有维护者评论时选择提问者以外获得正面反馈的评论
+func selectGitHubAnswer(comments []*model.GitHubComment, author string) (*model.GitHubComment, string) {
+	var maintainer, community *model.GitHubComment
+	for _, comment := range comments {
+		if comment == nil || strings.TrimSpace(comment.Body) == "" || isBotComment(comment) {
+			continue
+		}
+
+		if maintainerAssociations[comment.AuthorAssociation] {
+			if maintainer == nil || comment.Reactions >= maintainer.Reactions {
+				maintainer = comment
+			}
+			continue
+		}
+
+		if comment.User != nil && comment.User.Login == author {
+			continue
+		}
+		if comment.Reactions > 0 && (community == nil || comment.Reactions >= community.Reactions) {
+			community = comment
+		}
+	}
+
+	if maintainer != nil {
+		return maintainer, "maintainer"
+	}
+	if community != nil {
+		return community, "community"
+	}
+	return nil, ""
+}
+
+// isBotComment 判断是否为机器人评论
+func isBotComment(comment *model.GitHubComment) bool {
+	if comment.User == nil {
+		return false
+	}
+	return comment.User.Type == "Bot" || strings.HasSuffix(comment.User.Login, "[bot]")
+}
+
+// answerTypeLabel 解答类型的中文描述
+func answerTypeLabel(answerType string) string {
+	switch answerType {
+	case "accepted":
+		return "已采纳的回答"
+	case "maintainer":
+		return "维护者回复"
+	default:
+		return "社区回复"
+	}
+}
+
+// rankRelevance 根据搜索排名计算相关性，第一名为1，最后一名约为0.5
+func rankRelevance(rank, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return 1 - float64(rank)/float64(2*total)
+}
+
+// githubSearchTerms 从问题中提取GitHub搜索关键词
+// 优先使用插件名、配置项、错误码等英文和数字词元；没有时使用问题标题
+func githubSearchTerms(question *Question) string {
+	var terms []string
+	for _, token := range tools.Tokenize(question.Title + " " + question.Content) {
+		if len(terms) >= maxGitHubSearchTerms {
+			break
+		}
+		if len(token) < 3 || !hasASCIIAlnum(token) || isSearchStopWord(token) {
+			continue
+		}
+
+		// 复合词的组成部分已包含在复合词中
+		covered := false
+		for _, term := range terms {
+			if strings.Contains(term, token) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			terms = append(terms, token)
+		}
+	}
+	if len(terms) > 0 {
+		return strings.Join(terms, " ")
+	}
+
+	title := strings.TrimSpace(question.Title)
+	if title == "" {
+		title = strings.TrimSpace(question.Content)
+	}
+	return truncateRunes(title, 30)
+}
+
+// hasASCIIAlnum 判断词元是否包含英文字母或数字
+func hasASCIIAlnum(token string) bool {
+	for _, r := range token {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchStopWords GitHub搜索中无区分度的词
+var searchStopWords = map[string]bool{
+	"the": true, "and": true, "how": true, "what": true, "why": true, "when": true,
+	"does": true, "with": true, "for": true, "can": true, "not": true, "use": true,
+	"higress": true,
+}
+
+// isSearchStopWord 判断是否为搜索停用词
+func isSearchStopWord(token string) bool {
+	return searchStopWords[token]
+}
+
+// defaultString 返回非空字符串，为空时返回默认值
+func defaultString(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
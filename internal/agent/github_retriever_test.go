@@ -0,0 +1,147 @@
+package agent_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeGitHubServer 创建模拟GitHub REST和GraphQL接口的服务
+func newFakeGitHubServer(t *testing.T) (*httptest.Server, *string) {
+	var searchQuery string
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		searchQuery = r.URL.Query().Get("q")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items": []map[string]interface{}{
+				{
+					"number":     1234,
+					"title":      "key-rate-limit 不生效",
+					"body":       "配置了 limit_keys 之后请求没有被限流",
+					"state":      "closed",
+					"html_url":   "https://github.com/alibaba/higress/issues/1234",
+					"created_at": "2024-03-01T10:00:00Z",
+					"user":       map[string]interface{}{"login": "alice"},
+					"labels":     []map[string]interface{}{{"name": "plugin"}},
+				},
+				{
+					"number":   1300,
+					"title":    "key-rate-limit 文档问题",
+					"state":    "open",
+					"html_url": "https://github.com/alibaba/higress/issues/1300",
+					"user":     map[string]interface{}{"login": "bob"},
+				},
+			},
+		})
+	})
+
+	mux.HandleFunc("/repos/alibaba/higress/issues/1234/comments", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"body": "同样遇到了", "user": map[string]interface{}{"login": "alice"}, "author_association": "NONE"},
+			{"body": "需要同时配置 limit_by_header，否则插件不会生效。", "html_url": "https://github.com/alibaba/higress/issues/1234#issuecomment-1",
+				"user": map[string]interface{}{"login": "johnlanni"}, "author_association": "MEMBER",
+				"reactions": map[string]interface{}{"+1": 3}},
+			{"body": "This issue is stale", "user": map[string]interface{}{"login": "github-actions[bot]", "type": "Bot"}, "author_association": "NONE"},
+		})
+	})
+
+	mux.HandleFunc("/repos/alibaba/higress/issues/1300/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	})
+
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"search": map[string]interface{}{
+					"nodes": []map[string]interface{}{
+						{
+							"number":   56,
+							"title":    "如何按租户限流",
+							"body":     "多租户场景下 key-rate-limit 怎么配置？",
+							"url":      "https://github.com/alibaba/higress/discussions/56",
+							"category": map[string]interface{}{"name": "Q&A"},
+							"author":   map[string]interface{}{"login": "carol"},
+							"answer": map[string]interface{}{
+								"body":              "使用 limit_by_header: x-tenant-id",
+								"url":               "https://github.com/alibaba/higress/discussions/56#discussioncomment-1",
+								"authorAssociation": "MEMBER",
+								"author":            map[string]interface{}{"login": "johnlanni"},
+							},
+						},
+						{"number": 57, "title": "没有回答的讨论", "url": "https://github.com/alibaba/higress/discussions/57"},
+					},
+				},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &searchQuery
+}
+
+// TestGitHubRetriever 测试从Issue和讨论中检索已解答的问题
+func TestGitHubRetriever(t *testing.T) {
+	server, searchQuery := newFakeGitHubServer(t)
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	// 令牌中的${ENV}占位符从环境变量展开
+	t.Setenv("HIGRESS_TEST_GITHUB_TOKEN", "test-token")
+	config.GitHub.Token = "${HIGRESS_TEST_GITHUB_TOKEN}"
+	config.GitHub.APIURL = server.URL
+	config.Retrieval.Sources = []model.RetrieverConfig{{Name: "github", Type: "github", Enabled: true}}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	retriever, ok := processor.GetRetrieverRegistry().Get("github")
+	require.True(t, ok)
+
+	items, err := retriever.Retrieve(context.Background(), &model.Question{
+		Title:   "key-rate-limit 限流不生效",
+		Content: "配置 key-rate-limit 后没有效果",
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(*searchQuery, "key-rate-limit "), *searchQuery)
+	assert.Contains(t, *searchQuery, "repo:alibaba/higress")
+
+	require.Len(t, items, 3)
+	byID := make(map[string]model.KnowledgeItem)
+	for _, item := range items {
+		byID[item.ID] = item
+		assert.Equal(t, model.KnowledgeSourceGitHub, item.Source)
+	}
+
+	issue := byID["github_issue_1234"]
+	assert.Equal(t, "https://github.com/alibaba/higress/issues/1234", issue.URL)
+	assert.Equal(t, "#1234 key-rate-limit 不生效", issue.Title)
+	assert.Contains(t, issue.Content, "limit_by_header")
+	assert.Equal(t, "johnlanni", issue.Metadata["answered_by"])
+	assert.Equal(t, "maintainer", issue.Metadata["answer_type"])
+	assert.Contains(t, issue.Tags, "plugin")
+
+	discussion := byID["github_discussion_56"]
+	assert.Equal(t, "accepted", discussion.Metadata["answer_type"])
+	assert.Equal(t, "https://github.com/alibaba/higress/discussions/56", discussion.URL)
+
+	// 没有解答的Issue相关性降低，没有解答的讨论被忽略
+	unanswered := byID["github_issue_1300"]
+	assert.Nil(t, unanswered.Metadata["answer_type"])
+	assert.Less(t, unanswered.Relevance, issue.Relevance)
+	_, ok = byID["github_discussion_57"]
+	assert.False(t, ok)
+}
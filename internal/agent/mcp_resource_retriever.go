@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+)
+
+const (
+	// defaultResourceMaxResults 默认每次检索读取的资源数量上限
+	defaultResourceMaxResults = 3
+	// resourceContentRunes 知识项中资源内容的最大字符数
+	resourceContentRunes = 4000
+	// resourceCacheTTL 资源列表和内容的缓存时间，订阅的资源变化时提前失效
+	resourceCacheTTL = 10 * time.Minute
+)
+
+// cachedResource 缓存的资源内容
+type cachedResource struct {
+	contents  *mcp.ResourceContents
+	fetchedAt time.Time
+}
+
+// MCPResourceRetriever 将MCP服务器的资源（Wiki页面、仓库文件等）作为知识源
+// 配置了uris时只读取这些资源，否则从服务器的资源列表中按名称和描述选出与问题相关的资源；
+// 资源列表和内容在检索器中缓存，服务器支持订阅时订阅已读取的资源，收到变化通知后重新读取
+type MCPResourceRetriever struct {
+	name       string
+	enabled    bool
+	manager    *mcp.Manager
+	server     string
+	uris       []string
+	maxResults int
+	subscribe  bool
+	processor  *Processor
+
+	mutex      sync.Mutex
+	resources  []mcp.Resource
+	listedAt   time.Time
+	contents   map[string]*cachedResource
+	subscribed map[string]bool
+}
+
+// newMCPResourceRetriever 根据配置创建MCP资源检索器
+// 选项：server（必填）、uris（固定读取的资源URI）、max_results（默认3）、subscribe（是否订阅资源变化，默认true）
+func newMCPResourceRetriever(p *Processor, config RetrieverConfig) (Retriever, error) {
+	server := optionString(config.Options, "server", "")
+	if server == "" {
+		return nil, fmt.Errorf("MCP资源检索器 %s 未配置server", config.Name)
+	}
+
+	var uris []string
+	if values, ok := config.Options["uris"].([]interface{}); ok {
+		for _, value := range values {
+			if uri, ok := value.(string); ok && uri != "" {
+				uris = append(uris, uri)
+			}
+		}
+	}
+
+	maxResults := defaultResourceMaxResults
+	switch value := config.Options["max_results"].(type) {
+	case int:
+		maxResults = value
+	case float64:
+		maxResults = int(value)
+	}
+
+	subscribe := true
+	if value, ok := config.Options["subscribe"].(bool); ok {
+		subscribe = value
+	}
+
+	retriever := &MCPResourceRetriever{
+		name:       config.Name,
+		enabled:    config.Enabled,
+		manager:    p.mcpManager,
+		server:     server,
+		uris:       uris,
+		maxResults: maxResults,
+		subscribe:  subscribe,
+		processor:  p,
+		contents:   make(map[string]*cachedResource),
+		subscribed: make(map[string]bool),
+	}
+	p.mcpManager.AddResourceListener(retriever.handleResourceChanged)
+	return retriever, nil
+}
+
+// Name 知识源名称
+func (r *MCPResourceRetriever) Name() string {
+	return r.name
+}
+
+// Enabled 检索器和对应的MCP服务器均启用时参与检索
+func (r *MCPResourceRetriever) Enabled() bool {
+	return r.enabled && r.manager.IsServerEnabled(r.server)
+}
+
+// Retrieve 选出与问题相关的资源并读取内容
+func (r *MCPResourceRetriever) Retrieve(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
+	candidates, err := r.candidates(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]KnowledgeItem, 0, len(candidates))
+	for _, resource := range candidates {
+		contents, err := r.read(ctx, resource.URI)
+		if err != nil {
+			r.processor.logger.WithError(err).WithField("uri", resource.URI).Warn("读取MCP资源失败")
+			continue
+		}
+		if contents.Text == "" {
+			continue
+		}
+
+		item := KnowledgeItem{
+			ID:        fmt.Sprintf("%s_%s", r.name, resource.URI),
+			Title:     resourceTitle(resource),
+			Content:   truncateRunes(contents.Text, resourceContentRunes),
+			Tags:      []string{"mcp", "resource"},
+			CreatedAt: time.Now(),
+			Metadata: map[string]interface{}{
+				"source":    "mcp_resource",
+				"server":    r.server,
+				"uri":       resource.URI,
+				"mime_type": contents.MimeType,
+			},
+		}
+		if strings.HasPrefix(resource.URI, "http://") || strings.HasPrefix(resource.URI, "https://") {
+			item.URL = resource.URI
+		}
+		item.Relevance = keywordRelevance(question, &item)
+		items = append(items, item)
+	}
+
+	if len(candidates) > 0 && len(items) == 0 {
+		return nil, fmt.Errorf("读取MCP资源失败")
+	}
+	return items, nil
+}
+
+// candidates 选出需要读取的资源
+// 配置了uris时全部读取；否则按资源名称和描述与问题的关键词重叠度排序，取前max_results个有重叠的资源
+func (r *MCPResourceRetriever) candidates(ctx context.Context, question *Question) ([]mcp.Resource, error) {
+	if len(r.uris) > 0 {
+		resources := make([]mcp.Resource, 0, len(r.uris))
+		for _, uri := range r.uris {
+			resources = append(resources, mcp.Resource{URI: uri, Name: uri})
+		}
+		return resources, nil
+	}
+
+	resources, err := r.listResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type scored struct {
+		resource mcp.Resource
+		score    float64
+	}
+	var ranked []scored
+	for _, resource := range resources {
+		score := keywordRelevance(question, &KnowledgeItem{
+			Title:   resourceTitle(resource),
+			Content: resource.Description + " " + resource.URI,
+		})
+		if score > 0 {
+			ranked = append(ranked, scored{resource: resource, score: score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	selected := make([]mcp.Resource, 0, r.maxResults)
+	for _, candidate := range ranked {
+		if len(selected) >= r.maxResults {
+			break
+		}
+		selected = append(selected, candidate.resource)
+	}
+	return selected, nil
+}
+
+// listResources 获取服务器的资源列表，优先使用缓存
+func (r *MCPResourceRetriever) listResources(ctx context.Context) ([]mcp.Resource, error) {
+	r.mutex.Lock()
+	resources := r.resources
+	fresh := time.Since(r.listedAt) < resourceCacheTTL
+	r.mutex.Unlock()
+	if resources != nil && fresh {
+		return resources, nil
+	}
+
+	response, err := r.manager.ListResources(ctx, r.server)
+	if err != nil {
+		return nil, fmt.Errorf("获取MCP资源列表失败: %w", err)
+	}
+
+	r.mutex.Lock()
+	r.resources = response.Resources
+	r.listedAt = time.Now()
+	r.mutex.Unlock()
+	return response.Resources, nil
+}
+
+// read 读取资源的文本内容，优先使用缓存，首次读取时订阅资源变化
+func (r *MCPResourceRetriever) read(ctx context.Context, uri string) (*mcp.ResourceContents, error) {
+	r.mutex.Lock()
+	cached, exists := r.contents[uri]
+	r.mutex.Unlock()
+	if exists && time.Since(cached.fetchedAt) < resourceCacheTTL {
+		return cached.contents, nil
+	}
+
+	response, err := r.manager.ReadResource(ctx, r.server, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一个资源可能包含多段内容，只使用文本内容
+	contents := &mcp.ResourceContents{URI: uri}
+	var texts []string
+	for _, content := range response.Contents {
+		if content.Text == "" {
+			continue
+		}
+		if contents.MimeType == "" {
+			contents.MimeType = content.MimeType
+		}
+		texts = append(texts, content.Text)
+	}
+	contents.Text = strings.Join(texts, "\n\n")
+
+	r.mutex.Lock()
+	r.contents[uri] = &cachedResource{contents: contents, fetchedAt: time.Now()}
+	needSubscribe := r.subscribe && !r.subscribed[uri]
+	r.mutex.Unlock()
+
+	// 订阅成功后才记录，失败时下次重新读取资源时重试
+	if needSubscribe {
+		if err := r.manager.SubscribeResource(ctx, r.server, uri); err != nil {
+			r.processor.logger.WithError(err).WithField("uri", uri).Debug("订阅MCP资源失败，缓存过期后重新读取")
+		} else {
+			r.mutex.Lock()
+			r.subscribed[uri] = true
+			r.mutex.Unlock()
+		}
+	}
+	return contents, nil
+}
+
+// handleResourceChanged 资源变化时清除缓存，uri为空表示资源列表变化
+func (r *MCPResourceRetriever) handleResourceChanged(serverLabel, uri string) {
+	if serverLabel != r.server {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if uri == "" {
+		r.resources = nil
+		return
+	}
+	delete(r.contents, uri)
+}
+
+// resourceTitle 资源的显示标题
+func resourceTitle(resource mcp.Resource) string {
+	if resource.Title != "" {
+		return resource.Title
+	}
+	if resource.Name != "" {
+		return resource.Name
+	}
+	return resource.URI
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+)
+
+// MCPRetriever 通过MCP服务器检索知识
+// 配置了tool时调用该工具并以query_argument传入问题，否则使用服务器的查询接口；
+// 工具输出为{"results":[...]}时逐条转换为知识项，否则整体作为一个知识项
+type MCPRetriever struct {
+	name          string
+	enabled       bool
+	manager       *mcp.Manager
+	server        string
+	tool          string
+	queryArgument string
+	repo          string
+	arguments     map[string]interface{}
+}
+
+// newMCPRetriever 根据配置创建MCP检索器
+// 选项：server（必填）、tool、query_argument（默认question）、repo、arguments（固定参数）
+func newMCPRetriever(p *Processor, config RetrieverConfig) (Retriever, error) {
+	server := optionString(config.Options, "server", "")
+	if server == "" {
+		return nil, fmt.Errorf("MCP检索器 %s 未配置server", config.Name)
+	}
+
+	arguments, _ := config.Options["arguments"].(map[string]interface{})
+	return &MCPRetriever{
+		name:          config.Name,
+		enabled:       config.Enabled,
+		manager:       p.mcpManager,
+		server:        server,
+		tool:          optionString(config.Options, "tool", ""),
+		queryArgument: optionString(config.Options, "query_argument", "question"),
+		repo:          optionString(config.Options, "repo", ""),
+		arguments:     arguments,
+	}, nil
+}
+
+// Name 知识源名称
+func (r *MCPRetriever) Name() string {
+	return r.name
+}
+
+// Enabled 检索器和对应的MCP服务器均启用时参与检索
+func (r *MCPRetriever) Enabled() bool {
+	return r.enabled && r.manager.IsServerEnabled(r.server)
+}
+
+// Retrieve 调用MCP服务器检索知识
+func (r *MCPRetriever) Retrieve(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
+	query := questionText(question)
+
+	var output string
+	if r.tool != "" {
+		arguments := make(map[string]interface{}, len(r.arguments)+1)
+		for key, value := range r.arguments {
+			arguments[key] = value
+		}
+		arguments[r.queryArgument] = query
+
+		response, err := r.manager.CallTool(ctx, r.server, r.tool, arguments)
+		if err != nil {
+			return nil, fmt.Errorf("调用MCP工具失败: %w", err)
+		}
+		if response.Error != "" {
+			return nil, fmt.Errorf("MCP工具返回错误: %s", response.Error)
+		}
+		output = response.Output
+	} else {
+		response, err := r.manager.Query(ctx, r.server, query, r.repo)
+		if err != nil {
+			return nil, fmt.Errorf("MCP查询失败: %w", err)
+		}
+		if response.Error != "" {
+			return nil, fmt.Errorf("MCP查询返回错误: %s", response.Error)
+		}
+		output = response.Output
+	}
+
+	if output == "" {
+		return []KnowledgeItem{}, nil
+	}
+	return r.manager.ParseKnowledgeItems(output, KnowledgeSource(r.name)), nil
+}
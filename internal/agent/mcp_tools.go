@@ -0,0 +1,312 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/tools"
+)
+
+// BuildMCPTools 构建对外发布的MCP工具列表
+// 包含处理器的核心能力以及ToolLoader中已加载的全部工具
+func BuildMCPTools(processor *Processor, loader *ToolLoader) []*mcp.ServerTool {
+	serverTools := []*mcp.ServerTool{
+		{
+			Name:        "process_question",
+			Description: "回答Higress社区问题，融合本地知识库、Higress文档和DeepWiki生成带来源的回答",
+			InputSchema: objectSchema(map[string]interface{}{
+				"title":    stringProperty("问题标题"),
+				"content":  stringProperty("问题内容"),
+				"author":   stringProperty("提问者，默认为anonymous"),
+				"type":     enumProperty("问题类型", string(QuestionTypeIssue), string(QuestionTypePR), string(QuestionTypeText)),
+				"priority": enumProperty("优先级", string(PriorityLow), string(PriorityMedium), string(PriorityHigh), string(PriorityUrgent)),
+				"tags":     arrayProperty("标签", stringProperty("标签")),
+			}, "title", "content"),
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				request := &ProcessRequest{
+					Type:     QuestionType(getStringArg(args, "type")),
+					Title:    getStringArg(args, "title"),
+					Content:  getStringArg(args, "content"),
+					Author:   getStringArg(args, "author"),
+					Priority: Priority(getStringArg(args, "priority")),
+					Tags:     getStringSliceArg(args, "tags"),
+				}
+				if request.Title == "" || request.Content == "" {
+					return nil, fmt.Errorf("title和content不能为空")
+				}
+				if request.Author == "" {
+					request.Author = "anonymous"
+				}
+				return processor.ProcessQuestion(ctx, request)
+			},
+		},
+		{
+			Name:        "analyze_problem",
+			Description: "分析Bug、错误截图或GitHub Issue，给出诊断和解决方案",
+			InputSchema: objectSchema(map[string]interface{}{
+				"issue_type":  enumProperty("问题类型", "bug", "stack_trace", "image", "screenshot", "issue", "github_issue", "general"),
+				"content":     stringProperty("问题内容"),
+				"title":       stringProperty("标题"),
+				"stack_trace": stringProperty("错误堆栈"),
+				"image_url":   stringProperty("截图URL"),
+			}, "issue_type", "content"),
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				request := &AnalyzeRequest{
+					IssueType:  getStringArg(args, "issue_type"),
+					Content:    getStringArg(args, "content"),
+					Title:      getStringArg(args, "title"),
+					StackTrace: getStringArg(args, "stack_trace"),
+					ImageURL:   getStringArg(args, "image_url"),
+				}
+				return processor.AnalyzeProblem(ctx, request)
+			},
+		},
+		{
+			Name:        "get_community_stats",
+			Description: "获取GitHub仓库的社区统计信息（Issue、PR、贡献者、健康度）",
+			InputSchema: objectSchema(map[string]interface{}{
+				"owner":  stringProperty("仓库所有者，默认为配置中的Higress仓库"),
+				"repo":   stringProperty("仓库名，默认为配置中的Higress仓库"),
+				"period": stringProperty("统计周期，例如7d、30d，默认为30d"),
+			}),
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return processor.GetCommunityStats(ctx, getStringArg(args, "owner"), getStringArg(args, "repo"), getStringArg(args, "period"))
+			},
+		},
+	}
+
+	if loader != nil {
+		serverTools = append(serverTools, buildLoaderTools(loader)...)
+	}
+
+	return serverTools
+}
+
+// buildLoaderTools 为ToolLoader中的工具构建MCP定义
+func buildLoaderTools(loader *ToolLoader) []*mcp.ServerTool {
+	var serverTools []*mcp.ServerTool
+
+	for _, name := range loader.GetToolNames() {
+		tool, err := loader.GetTool(name)
+		if err != nil {
+			continue
+		}
+
+		switch t := tool.(type) {
+		case *tools.BugAnalyzer:
+			serverTools = append(serverTools, &mcp.ServerTool{
+				Name:        name,
+				Description: "分析错误堆栈，给出错误类型、严重程度、根因和解决方案",
+				InputSchema: objectSchema(map[string]interface{}{
+					"stack_trace": stringProperty("错误堆栈或错误日志"),
+					"environment": stringProperty("运行环境，例如kubernetes、docker"),
+					"version":     stringProperty("Higress版本"),
+				}, "stack_trace"),
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					return t.AnalyzeBug(getStringArg(args, "stack_trace"), getStringArg(args, "environment"), getStringArg(args, "version"))
+				},
+			})
+		case *tools.ImageAnalyzer:
+			serverTools = append(serverTools, &mcp.ServerTool{
+				Name:        name,
+				Description: "分析错误截图或配置截图，提取问题并给出建议",
+				InputSchema: objectSchema(map[string]interface{}{
+					"image_url": stringProperty("图片URL"),
+				}, "image_url"),
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					return t.AnalyzeImage(getStringArg(args, "image_url"))
+				},
+			})
+		case *tools.CommunityStats:
+			serverTools = append(serverTools, &mcp.ServerTool{
+				Name:        name,
+				Description: "统计指定GitHub仓库的Issue、PR和贡献者数据",
+				InputSchema: objectSchema(map[string]interface{}{
+					"owner":  stringProperty("仓库所有者"),
+					"repo":   stringProperty("仓库名"),
+					"period": stringProperty("统计周期，例如7d、30d"),
+				}, "owner", "repo"),
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					period := getStringArg(args, "period")
+					if period == "" {
+						period = "30d"
+					}
+					return t.GetCommunityStats(getStringArg(args, "owner"), getStringArg(args, "repo"), period)
+				},
+			})
+		case *tools.IssueClassifier:
+			serverTools = append(serverTools, &mcp.ServerTool{
+				Name:        name,
+				Description: "对GitHub Issue进行分类，给出类别、优先级、建议标签和分配人",
+				InputSchema: objectSchema(map[string]interface{}{
+					"title":  stringProperty("Issue标题"),
+					"body":   stringProperty("Issue内容"),
+					"labels": arrayProperty("现有标签", stringProperty("标签")),
+				}, "title", "body"),
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					return t.ClassifyIssue(getStringArg(args, "title"), getStringArg(args, "body"), getStringSliceArg(args, "labels"))
+				},
+			})
+		case *tools.KnowledgeBase:
+			serverTools = append(serverTools, &mcp.ServerTool{
+				Name:        name,
+				Description: "搜索、添加和管理本地知识库中的文档",
+				InputSchema: objectSchema(map[string]interface{}{
+					"action":      enumProperty("操作类型，默认为search", "search", "add_document", "get_document", "delete_document"),
+					"query":       stringProperty("查询内容，search时必填"),
+					"max_results": integerProperty("最大返回数量，默认为5"),
+					"document_id": stringProperty("文档ID，get_document和delete_document时必填"),
+					"title":       stringProperty("文档标题，add_document时必填"),
+					"content":     stringProperty("文档内容，add_document时必填"),
+					"url":         stringProperty("文档链接"),
+					"tags":        arrayProperty("文档标签", stringProperty("标签")),
+				}),
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					switch action := getStringArg(args, "action"); action {
+					case "", "search":
+						maxResults := getIntArg(args, "max_results")
+						if maxResults <= 0 {
+							maxResults = 5
+						}
+						return t.SearchKnowledge(getStringArg(args, "query"), maxResults)
+					case "add_document":
+						if getStringArg(args, "title") == "" || getStringArg(args, "content") == "" {
+							return nil, fmt.Errorf("add_document需要title和content")
+						}
+						return t.AddDocument(model.Document{
+							ID:      getStringArg(args, "document_id"),
+							Title:   getStringArg(args, "title"),
+							Content: getStringArg(args, "content"),
+							URL:     getStringArg(args, "url"),
+							Source:  "mcp",
+							Tags:    getStringSliceArg(args, "tags"),
+						})
+					case "get_document":
+						return t.GetDocument(getStringArg(args, "document_id"))
+					case "delete_document":
+						documentID := getStringArg(args, "document_id")
+						if err := t.DeleteDocument(documentID); err != nil {
+							return nil, err
+						}
+						return map[string]interface{}{"deleted": documentID}, nil
+					default:
+						return nil, fmt.Errorf("不支持的操作: %s", action)
+					}
+				},
+			})
+		case *tools.GitHubManager:
+			serverTools = append(serverTools, &mcp.ServerTool{
+				Name:        name,
+				Description: "查询GitHub仓库的Issue、评论和仓库统计",
+				InputSchema: objectSchema(map[string]interface{}{
+					"action":       enumProperty("操作类型", "get_issue", "search_issues", "get_comments", "get_repository_stats"),
+					"owner":        stringProperty("仓库所有者"),
+					"repo":         stringProperty("仓库名"),
+					"issue_number": integerProperty("Issue编号，get_issue和get_comments时必填"),
+					"query":        stringProperty("搜索关键词，search_issues时必填"),
+				}, "action", "owner", "repo"),
+				Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+					owner := getStringArg(args, "owner")
+					repo := getStringArg(args, "repo")
+					switch action := getStringArg(args, "action"); action {
+					case "get_issue":
+						return t.GetIssue(owner, repo, getIntArg(args, "issue_number"))
+					case "search_issues":
+						return t.SearchIssues(getStringArg(args, "query"), owner, repo)
+					case "get_comments":
+						return t.GetComments(owner, repo, getIntArg(args, "issue_number"))
+					case "get_repository_stats":
+						return t.GetRepositoryStats(owner, repo)
+					default:
+						return nil, fmt.Errorf("不支持的操作: %s", action)
+					}
+				},
+			})
+		}
+	}
+
+	return serverTools
+}
+
+// objectSchema 构建对象类型的JSON Schema
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// stringProperty 字符串属性
+func stringProperty(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": description,
+	}
+}
+
+// integerProperty 整数属性
+func integerProperty(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "integer",
+		"description": description,
+	}
+}
+
+// enumProperty 枚举字符串属性
+func enumProperty(description string, values ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"description": description,
+		"enum":        values,
+	}
+}
+
+// arrayProperty 数组属性
+func arrayProperty(description string, items map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"description": description,
+		"items":       items,
+	}
+}
+
+// getStringArg 安全获取字符串参数
+func getStringArg(args map[string]interface{}, key string) string {
+	if val, ok := args[key].(string); ok {
+		return val
+	}
+	return ""
+}
+
+// getIntArg 安全获取整数参数（JSON数字解析为float64）
+func getIntArg(args map[string]interface{}, key string) int {
+	switch val := args[key].(type) {
+	case float64:
+		return int(val)
+	case int:
+		return val
+	}
+	return 0
+}
+
+// getStringSliceArg 安全获取字符串数组参数
+func getStringSliceArg(args map[string]interface{}, key string) []string {
+	values, ok := args[key].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
@@ -10,9 +10,6 @@ import (
 	"github.com/community-governance-mcp-higress/internal/openai"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"net/http"
-	"net/url"
-	"encoding/json"
 	"io"
 	"github.com/community-governance-mcp-higress/tools"
 	"github.com/community-governance-mcp-higress/internal/mcp"
@@ -28,6 +25,12 @@ type Processor struct {
 	retrievalManager *RetrievalManager
 	memoryManager   *memory.Manager
 	fallbackStrategy *FallbackStrategy
+	knowledgeBase   *tools.KnowledgeBase
+	reranker        Reranker
+	retrievers      *RetrieverRegistry
+	stopReload      context.CancelFunc
+	stopDocsSync    context.CancelFunc
+	deepWikiCache   *deepWikiCache
 }
 
 // NewProcessor 创建新的处理器
@@ -61,6 +64,7 @@ func NewProcessor(openaiClient *openai.Client, config *model.AgentConfig) *Proce
 		retrievalManager: retrievalManager,
 		memoryManager:   memoryManager,
 		fallbackStrategy: fallbackStrategy,
+		deepWikiCache:   &deepWikiCache{entries: make(map[string]deepWikiCacheEntry)},
 	}
 
 	// 设置日志级别
@@ -70,9 +74,98 @@ func NewProcessor(openaiClient *openai.Client, config *model.AgentConfig) *Proce
 	}
 	processor.logger.SetLevel(level)
 
+	// 创建本地知识库
+	processor.knowledgeBase = processor.newKnowledgeBase()
+
+	// 根据配置创建知识源检索器
+	processor.retrievers = processor.newRetrieverRegistry()
+
+	// 创建重排序器
+	reranker, err := NewReranker(config.Fusion.Reranker, openaiClient, config)
+	if err != nil {
+		processor.logger.WithError(err).Warn("重排序器配置无效，使用启发式重排序")
+		reranker = NewHeuristicReranker()
+	}
+	processor.reranker = reranker
+
+	// 定时同步Higress文档镜像
+	processor.startDocsSync()
+
 	return processor
 }
 
+// newKnowledgeBase 创建本地知识库
+// 启用知识库时文档持久化到StoragePath并按UpdateInterval从磁盘重新加载，打开失败时退化为内存知识库
+func (p *Processor) newKnowledgeBase() *tools.KnowledgeBase {
+	knowledgeConfig := p.config.Knowledge
+	if !knowledgeConfig.Enabled || knowledgeConfig.StoragePath == "" {
+		return tools.NewKnowledgeBase(p.config.OpenAI.APIKey)
+	}
+
+	embedder, err := tools.NewEmbedder(knowledgeConfig.Embedder, p.config.OpenAI.APIKey, knowledgeConfig.EmbeddingModel)
+	if err != nil {
+		p.logger.WithError(err).Warn("向量配置无效，使用本地哈希向量")
+		embedder = tools.NewHashEmbedder(0)
+	}
+
+	maxSize, err := tools.ParseByteSize(knowledgeConfig.MaxSize)
+	if err != nil {
+		p.logger.WithError(err).Warn("知识库容量配置无效，不限制容量")
+		maxSize = 0
+	}
+
+	store, err := tools.NewDocumentStore(knowledgeConfig.StoragePath, maxSize)
+	if err != nil {
+		p.logger.WithError(err).Warn("打开本地知识库失败，使用内存知识库")
+		return tools.NewKnowledgeBase(p.config.OpenAI.APIKey)
+	}
+
+	if knowledgeConfig.UpdateInterval != "" {
+		interval, err := time.ParseDuration(knowledgeConfig.UpdateInterval)
+		if err != nil {
+			p.logger.WithError(err).Warn("知识库更新间隔配置无效，不自动重新加载")
+		} else {
+			ctx, cancel := context.WithCancel(context.Background())
+			p.stopReload = cancel
+			store.StartAutoReload(ctx, interval, func(err error) {
+				p.logger.WithError(err).Warn("重新加载本地知识库失败")
+			})
+		}
+	}
+
+	chunkSize := knowledgeConfig.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = tools.DefaultChunkSize
+	}
+	chunkOverlap := knowledgeConfig.ChunkOverlap
+	if chunkOverlap <= 0 {
+		chunkOverlap = tools.DefaultChunkOverlap
+	}
+
+	index, err := tools.NewVectorIndex(store.IndexPath(), embedder, chunkSize, chunkOverlap)
+	if err != nil {
+		p.logger.WithError(err).Warn("加载向量索引失败，重新建立索引")
+		index, _ = tools.NewVectorIndex("", embedder, chunkSize, chunkOverlap)
+	}
+
+	knowledgeBase := tools.NewKnowledgeBaseWithIndex(store, index)
+
+	p.logger.WithFields(logrus.Fields{
+		"storage_path": knowledgeConfig.StoragePath,
+		"documents":    store.Count(),
+		"size":         store.Size(),
+		"embedder":     embedder.Name(),
+	}).Info("本地知识库已加载")
+
+	// 后台建立向量索引，检索不等待索引完成
+	knowledgeBase.OnIndexError(func(err error) {
+		p.logger.WithError(err).Warn("更新向量索引失败，文档变化或下次检索时重试")
+	})
+	knowledgeBase.ReindexAsync()
+
+	return knowledgeBase
+}
+
 // ProcessQuestion 处理用户问题
 func (p *Processor) ProcessQuestion(ctx context.Context, request *ProcessRequest) (*ProcessResponse, error) {
 	startTime := time.Now()
@@ -91,15 +184,19 @@ func (p *Processor) ProcessQuestion(ctx context.Context, request *ProcessRequest
 	if err != nil {
 		p.logger.WithError(err).Warn("检索记忆失败，继续处理")
 	}
+	emitEvent(ctx, StreamEventMemory, map[string]interface{}{
+		"count": len(relatedMemories),
+	})
 
 	// 1. 问题理解和分类
 	question, err := p.understandQuestion(ctx, request, questionID)
 	if err != nil {
 		return nil, fmt.Errorf("问题理解失败: %w", err)
 	}
+	emitEvent(ctx, StreamEventQuestion, question)
 
 	// 2. 多源知识检索
-	sources, err := p.retrieveKnowledge(ctx, question)
+	sources, sourceStatuses, err := p.retrieveKnowledge(ctx, question)
 	if err != nil {
 		return nil, fmt.Errorf("知识检索失败: %w", err)
 	}
@@ -109,9 +206,13 @@ func (p *Processor) ProcessQuestion(ctx context.Context, request *ProcessRequest
 	if err != nil {
 		return nil, fmt.Errorf("知识融合失败: %w", err)
 	}
+	emitEvent(ctx, StreamEventFusion, map[string]interface{}{
+		"fusion_score":  fusionResult.FusionScore,
+		"sources_count": len(fusionResult.Sources),
+	})
 
 	// 4. 生成回答
-	answer, err := p.generateAnswer(ctx, fusionResult)
+	answer, err := p.generateAnswer(ctx, question, fusionResult)
 	if err != nil {
 		return nil, fmt.Errorf("生成回答失败: %w", err)
 	}
@@ -131,6 +232,7 @@ func (p *Processor) ProcessQuestion(ctx context.Context, request *ProcessRequest
 		ProcessingTime:  processingTime.String(),
 		FusionScore:     fusionResult.FusionScore,
 		Recommendations: p.generateRecommendations(question, answer),
+		SourceStatuses:  sourceStatuses,
 	}
 
 	p.logger.WithFields(logrus.Fields{
@@ -364,9 +466,25 @@ func (p *Processor) buildMemoryContext(memories []memory.MemoryItem) string {
 	return strings.Join(contextParts, "\n")
 }
 
-// GetCommunityStats 获取社区统计
-func (p *Processor) GetCommunityStats(ctx context.Context) (*CommunityStats, error) {
-	return nil, fmt.Errorf("社区统计功能未实现")
+// GetCommunityStats 获取社区统计，owner/repo为空时使用Higress配置中的仓库
+func (p *Processor) GetCommunityStats(ctx context.Context, owner, repo, period string) (*CommunityStats, error) {
+	if owner == "" {
+		owner = p.config.Higress.RepoOwner
+	}
+	if repo == "" {
+		repo = p.config.Higress.RepoName
+	}
+	if period == "" {
+		period = "30d"
+	}
+
+	statsTool := tools.NewCommunityStats(p.config.GitHub.Token)
+	stats, err := statsTool.GetCommunityStats(owner, repo, period)
+	if err != nil {
+		return nil, fmt.Errorf("获取社区统计失败: %w", err)
+	}
+
+	return stats, nil
 }
 
 // understandQuestion 理解问题
@@ -501,65 +619,18 @@ func (p *Processor) determinePriority(request *ProcessRequest) Priority {
 	return PriorityLow
 }
 
-// retrieveKnowledge 检索知识
-func (p *Processor) retrieveKnowledge(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
-	var allSources []KnowledgeItem
-
-	// 1. 检索本地知识库
-	if p.config.Knowledge.Enabled {
-		localSources, err := p.retrieveLocalKnowledge(ctx, question)
-		if err != nil {
-			p.logger.WithError(err).Warn("本地知识库检索失败")
-		} else {
-			allSources = append(allSources, localSources...)
-		}
-	}
-
-	// 2. 检索Higress文档
-	higressSources, err := p.retrieveHigressDocs(ctx, question)
-	if err != nil {
-		p.logger.WithError(err).Warn("Higress文档检索失败")
-	} else {
-		allSources = append(allSources, higressSources...)
-	}
-
-	// 3. 检索DeepWiki
-	if p.config.DeepWiki.Enabled {
-		deepwikiSources, err := p.retrieveDeepWiki(ctx, question)
-		if err != nil {
-			p.logger.WithError(err).Warn("DeepWiki检索失败")
-		} else {
-			allSources = append(allSources, deepwikiSources...)
-		}
-	}
-
-	// 4. 计算相关性并排序
-	for i := range allSources {
-		allSources[i].Relevance = p.calculateRelevance(question, &allSources[i])
-	}
-	p.sortByRelevance(allSources)
-
-	// 限制返回数量
-	if len(allSources) > p.config.Fusion.MaxSources {
-		allSources = allSources[:p.config.Fusion.MaxSources]
-	}
-
-	p.logger.WithField("sources_count", len(allSources)).Info("知识检索完成")
-	return allSources, nil
-}
-
 // retrieveLocalKnowledge 检索本地知识库
 func (p *Processor) retrieveLocalKnowledge(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
 	p.logger.Info("开始检索本地知识库")
 	
-	// 使用现有的知识库工具
-	knowledgeBase := tools.NewKnowledgeBase(p.config.OpenAI.APIKey)
-	
 	// 构建查询
 	query := question.Title + " " + question.Content
 	
 	// 执行搜索
-	searchResult, err := knowledgeBase.SearchKnowledge(query, 5)
+	// 文档镜像中的章节由Higress文档知识源检索
+	searchResult, err := p.knowledgeBase.SearchKnowledgeFiltered(ctx, query, 5, func(doc model.Document) bool {
+		return !isDocsMirrorDocument(doc)
+	})
 	if err != nil {
 		p.logger.WithError(err).Warn("本地知识库检索失败")
 		return []KnowledgeItem{}, nil // 返回空结果而不是错误
@@ -573,14 +644,22 @@ func (p *Processor) retrieveLocalKnowledge(ctx context.Context, question *Questi
 			Source:    KnowledgeSourceLocal,
 			Title:     result.Title,
 			Content:   result.Content,
-			URL:       "", // 本地知识库没有URL
+			URL:       "", // 由文档信息补充
 			Relevance: result.RelevanceScore,
 			Tags:      []string{}, // 可以从文档内容中提取标签
 			CreatedAt: time.Now(),
 			Metadata: map[string]interface{}{
-				"snippet": result.Snippet,
+				"snippet":      result.Snippet,
+				"chunk_index":  result.ChunkIndex,
+				"start_offset": result.StartOffset,
+				"end_offset":   result.EndOffset,
 			},
 		}
+		// 补充文档的链接和标签
+		if doc, err := p.knowledgeBase.GetDocument(result.DocumentID); err == nil {
+			item.URL = doc.URL
+			item.Tags = doc.Tags
+		}
 		items = append(items, item)
 	}
 	
@@ -592,10 +671,6 @@ func (p *Processor) retrieveLocalKnowledge(ctx context.Context, question *Questi
 func (p *Processor) retrieveHigressDocs(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
 	p.logger.Info("开始检索Higress文档")
 	
-	// 创建带超时的上下文
-	timeoutCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-	
 	// 使用多个备用API端点，避免网络限制
 	endpoints := []string{
 		"https://higress.io/docs",
@@ -605,7 +680,7 @@ func (p *Processor) retrieveHigressDocs(ctx context.Context, question *Question)
 	
 	// 使用多端点检索
 	multiRetrieval := NewMultiEndpointRetrieval(endpoints, DefaultRetrievalConfig())
-	result, err := multiRetrieval.Retrieve(timeoutCtx, p.retrievalManager)
+	result, err := multiRetrieval.Retrieve(ctx, p.retrievalManager)
 	
 	if err == nil && result.Success {
 		// 解析响应内容
@@ -616,7 +691,13 @@ func (p *Processor) retrieveHigressDocs(ctx context.Context, question *Question)
 		}
 	}
 	
-	// 如果所有端点都失败，使用本地缓存或模拟数据
+	// 所有端点都失败时使用本地文档镜像
+	if items := p.retrieveDocsMirror(ctx, question); len(items) > 0 {
+		p.logger.WithField("results_count", len(items)).Info("使用Higress文档镜像")
+		return items, nil
+	}
+
+	// 没有文档镜像时使用内置备用数据
 	fallbackItems := p.fallbackStrategy.GetHigressFallbackData()
 	items := p.convertFallbackToKnowledgeItems(question, fallbackItems, KnowledgeSourceHigress)
 	p.logger.Info("使用Higress文档备用数据")
@@ -672,7 +753,7 @@ func (p *Processor) parseHigressResponse(body io.Reader, question *Question) ([]
 			Title:     fmt.Sprintf("Higress文档片段 %d", i+1),
 			Content:   snippet,
 			URL:       "https://higress.io/docs",
-			Relevance: p.calculateRelevance(question, &KnowledgeItem{Content: snippet}),
+			Relevance: keywordRelevance(question, &KnowledgeItem{Content: snippet}),
 			Tags:      []string{"higress", "documentation"},
 			CreatedAt: time.Now(),
 			Metadata: map[string]interface{}{
@@ -727,140 +808,30 @@ func (p *Processor) extractRelevantSnippets(text, query string) []string {
 	return snippets
 }
 
-// retrieveDeepWiki 检索DeepWiki
-func (p *Processor) retrieveDeepWiki(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
-	p.logger.Info("开始检索DeepWiki")
-	
-	// 创建带超时的上下文
-	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
-	defer cancel()
-	
-	// 检查DeepWiki配置
-	if !p.config.DeepWiki.Enabled {
-		p.logger.Info("DeepWiki未启用，跳过检索")
-		return []KnowledgeItem{}, nil
-	}
-	
-	// 使用MCP管理器进行查询
-	items, err := p.mcpManager.QueryWithFallback(
-		timeoutCtx,
-		"deepwiki",
-		question.Title+" "+question.Content,
-		"modelcontextprotocol/modelcontextprotocol", // 默认仓库，可根据需要调整
-		func() ([]model.KnowledgeItem, error) {
-			// 备用方案：直接HTTP调用
-			httpItems, err := p.retrieveFromDeepWikiHTTP(timeoutCtx, question)
-			if err != nil {
-				// 如果HTTP调用也失败，使用备用数据
-				fallbackData := p.fallbackStrategy.GetDeepWikiFallbackData()
-				items := p.convertFallbackToKnowledgeItems(question, fallbackData, KnowledgeSourceDeepWiki)
-				return items, nil
-			}
-			return httpItems, nil
-		},
-	)
-	
-	if err != nil {
-		p.logger.WithError(err).Warn("DeepWiki检索失败，使用备用数据")
-		fallbackData := p.fallbackStrategy.GetDeepWikiFallbackData()
-		items = p.convertFallbackToKnowledgeItems(question, fallbackData, KnowledgeSourceDeepWiki)
-	}
-	
-	p.logger.WithField("results_count", len(items)).Info("DeepWiki检索完成")
-	return items, nil
-}
+// fuseKnowledge 融合知识
+func (p *Processor) fuseKnowledge(ctx context.Context, question *Question, sources []KnowledgeItem) (*FusionResult, error) {
+	// 重排序并过滤低于相似度阈值的知识源
+	sources = p.rerankSources(ctx, question, sources)
 
-// retrieveFromDeepWikiHTTP 通过HTTP调用检索DeepWiki
-func (p *Processor) retrieveFromDeepWikiHTTP(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
-	// 构建HTTP客户端
-	client := &http.Client{
-		Timeout: 15 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  true,
-		},
-	}
-	
-	// 构建请求URL
-	baseURL := p.config.DeepWiki.Endpoint
-	if baseURL == "" {
-		baseURL = "https://api.deepwiki.com" // 默认端点
-	}
-	
-	query := url.QueryEscape(question.Title + " " + question.Content)
-	requestURL := fmt.Sprintf("%s/search?q=%s&limit=5", baseURL, query)
-	
-	// 构建请求
-	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	
-	// 设置请求头
-	req.Header.Set("User-Agent", "HigressBot/1.0")
-	req.Header.Set("Accept", "application/json")
-	if p.config.DeepWiki.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+p.config.DeepWiki.APIKey)
-	}
-	
-	// 发送请求
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("DeepWiki API返回错误: %d", resp.StatusCode)
-	}
-	
-	// 解析JSON响应
-	var response struct {
-		Results []struct {
-			Title   string  `json:"title"`
-			Content string  `json:"content"`
-			URL     string  `json:"url"`
-			Score   float64 `json:"score"`
-		} `json:"results"`
-	}
-	
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, err
+	// 合并不同来源返回的近似重复内容，避免回答重复
+	deduplicated := DeduplicateKnowledge(sources, p.config.Fusion.DedupThreshold)
+	if merged := len(sources) - len(deduplicated); merged > 0 {
+		p.logger.WithField("merged", merged).Info("合并近似重复知识源")
 	}
-	
-	// 转换为KnowledgeItem
-	var items []KnowledgeItem
-	for _, result := range response.Results {
-		item := KnowledgeItem{
-			ID:        fmt.Sprintf("deepwiki_%s", result.Title),
-			Source:    KnowledgeSourceDeepWiki,
-			Title:     result.Title,
-			Content:   result.Content,
-			URL:       result.URL,
-			Relevance: result.Score,
-			Tags:      []string{"deepwiki"},
-			CreatedAt: time.Now(),
-			Metadata: map[string]interface{}{
-				"source": "deepwiki_api",
-			},
-		}
-		items = append(items, item)
+	sources = deduplicated
+
+	// 限制返回数量
+	if p.config.Fusion.MaxSources > 0 && len(sources) > p.config.Fusion.MaxSources {
+		sources = sources[:p.config.Fusion.MaxSources]
 	}
-	
-	return items, nil
-}
 
-// fuseKnowledge 融合知识
-func (p *Processor) fuseKnowledge(ctx context.Context, question *Question, sources []KnowledgeItem) (*FusionResult, error) {
 	// 计算融合分数
 	fusionScore := p.calculateFusionScore(sources)
 	// 构建融合结果
 	fResult := &FusionResult{
 		Sources:     sources,
 		FusionScore: fusionScore,
-		Context:     "", // 可根据需要补充上下文
+		Context:     buildFusionContext(sources),
 	}
 	p.logger.WithFields(logrus.Fields{
 		"fusion_score":  fusionScore,
@@ -869,65 +840,6 @@ func (p *Processor) fuseKnowledge(ctx context.Context, question *Question, sourc
 	return fResult, nil
 }
 
-// calculateRelevance 计算相关性
-func (p *Processor) calculateRelevance(question *Question, source *KnowledgeItem) float64 {
-	// 简单的关键词匹配算法
-	questionText := strings.ToLower(question.Title + " " + question.Content)
-	sourceText := strings.ToLower(source.Title + " " + source.Content)
-
-	// 计算关键词匹配度
-	questionWords := strings.Fields(questionText)
-	sourceWords := strings.Fields(sourceText)
-
-	matches := 0
-	for _, qWord := range questionWords {
-		if len(qWord) < 3 { // 忽略短词
-			continue
-		}
-		for _, sWord := range sourceWords {
-			if strings.Contains(sWord, qWord) || strings.Contains(qWord, sWord) {
-				matches++
-				break
-			}
-		}
-	}
-
-	if len(questionWords) == 0 {
-		return 0.0
-	}
-
-	relevance := float64(matches) / float64(len(questionWords))
-
-	// 标签匹配加分
-	for _, qTag := range question.Tags {
-		for _, sTag := range source.Tags {
-			if strings.EqualFold(qTag, sTag) {
-				relevance += 0.2
-				break
-			}
-		}
-	}
-
-	// 确保分数在0-1之间
-	if relevance > 1.0 {
-		relevance = 1.0
-	}
-
-	return relevance
-}
-
-// sortByRelevance 按相关性排序
-func (p *Processor) sortByRelevance(sources []KnowledgeItem) {
-	// 简单的冒泡排序，按相关性降序
-	for i := 0; i < len(sources)-1; i++ {
-		for j := 0; j < len(sources)-1-i; j++ {
-			if sources[j].Relevance < sources[j+1].Relevance {
-				sources[j], sources[j+1] = sources[j+1], sources[j]
-			}
-		}
-	}
-}
-
 // calculateFusionScore 计算融合分数
 func (p *Processor) calculateFusionScore(sources []KnowledgeItem) float64 {
 	if len(sources) == 0 {
@@ -962,8 +874,14 @@ func (p *Processor) calculateFusionScore(sources []KnowledgeItem) float64 {
 }
 
 // generateAnswer 生成回答
-func (p *Processor) generateAnswer(ctx context.Context, fusionResult *FusionResult) (*Answer, error) {
-	content := p.buildAnswerContent(fusionResult)
+// 优先由LLM基于融合结果生成带引用的回答，LLM不可用时回退到模板回答
+func (p *Processor) generateAnswer(ctx context.Context, question *Question, fusionResult *FusionResult) (*Answer, error) {
+	content, err := p.synthesizeAnswer(ctx, question, fusionResult)
+	if err != nil {
+		p.logger.WithError(err).Warn("LLM生成回答不可用，使用模板回答")
+		content = p.buildAnswerContent(fusionResult)
+		emitAnswerText(ctx, content)
+	}
 	summary := p.buildAnswerSummary(content)
 	confidence := p.calculateConfidence(fusionResult)
 	answer := &Answer{
@@ -1150,3 +1068,36 @@ func (p *Processor) SetLogger(logger *logrus.Logger) {
 func (p *Processor) GetMemoryManager() *memory.Manager {
 	return p.memoryManager
 }
+
+// GetKnowledgeBase 获取本地知识库
+func (p *Processor) GetKnowledgeBase() *tools.KnowledgeBase {
+	return p.knowledgeBase
+}
+
+// GetMCPManager 获取MCP管理器
+func (p *Processor) GetMCPManager() *mcp.Manager {
+	return p.mcpManager
+}
+
+// GetRetrieverRegistry 获取知识源检索器注册表
+func (p *Processor) GetRetrieverRegistry() *RetrieverRegistry {
+	return p.retrievers
+}
+
+// Stop 停止后台任务
+func (p *Processor) Stop() {
+	if p.stopReload != nil {
+		p.stopReload()
+	}
+	if p.stopDocsSync != nil {
+		p.stopDocsSync()
+	}
+	// 等待后台索引更新退出，避免停止后继续写入索引文件
+	p.knowledgeBase.Close()
+	p.memoryManager.Stop()
+
+	// 通知远程MCP服务器释放会话
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	p.mcpManager.Close(ctx)
+}
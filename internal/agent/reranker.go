@@ -0,0 +1,337 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/community-governance-mcp-higress/tools"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RerankerHeuristic 关键词重叠启发式重排序
+	RerankerHeuristic = "heuristic"
+	// RerankerEmbedding 向量余弦相似度重排序
+	RerankerEmbedding = "embedding"
+	// RerankerLLM 大模型逐条打分重排序
+	RerankerLLM = "llm"
+
+	// rerankWeight 最终相关性中重排序得分的权重，其余为归一化后的检索得分
+	rerankWeight = 0.7
+	// llmRerankConcurrency LLM打分的最大并发数
+	llmRerankConcurrency = 4
+	// rerankContentRunes 参与重排序的知识内容最大字符数
+	rerankContentRunes = 2000
+)
+
+// Reranker 知识源重排序器
+// 对同一问题下不同来源的知识统一打分，得分范围为0-1
+type Reranker interface {
+	// Score 返回每个知识源与问题的相似度，顺序与sources一致
+	Score(ctx context.Context, question *Question, sources []KnowledgeItem) ([]float64, error)
+	// Name 重排序器名称
+	Name() string
+}
+
+// NewReranker 根据配置创建重排序器，name为空时使用启发式重排序
+func NewReranker(name string, openaiClient *openai.Client, config *AgentConfig) (Reranker, error) {
+	switch name {
+	case "", RerankerHeuristic:
+		return NewHeuristicReranker(), nil
+	case RerankerEmbedding:
+		embedder, err := tools.NewEmbedder(config.Knowledge.Embedder, config.OpenAI.APIKey, config.Knowledge.EmbeddingModel)
+		if err != nil {
+			return nil, fmt.Errorf("创建向量重排序器失败: %w", err)
+		}
+		return NewEmbeddingReranker(embedder), nil
+	case RerankerLLM:
+		if openaiClient == nil || !openaiClient.IsConfigured() {
+			return nil, fmt.Errorf("LLM重排序需要配置OpenAI客户端")
+		}
+		return NewLLMReranker(openaiClient), nil
+	default:
+		return nil, fmt.Errorf("不支持的重排序器: %s", name)
+	}
+}
+
+// HeuristicReranker 关键词重叠启发式重排序器
+type HeuristicReranker struct{}
+
+// NewHeuristicReranker 创建启发式重排序器
+func NewHeuristicReranker() *HeuristicReranker {
+	return &HeuristicReranker{}
+}
+
+// Score 按关键词重叠和标签匹配打分
+func (r *HeuristicReranker) Score(ctx context.Context, question *Question, sources []KnowledgeItem) ([]float64, error) {
+	scores := make([]float64, len(sources))
+	for i := range sources {
+		scores[i] = keywordRelevance(question, &sources[i])
+	}
+	return scores, nil
+}
+
+// Name 重排序器名称
+func (r *HeuristicReranker) Name() string {
+	return RerankerHeuristic
+}
+
+// keywordRelevance 计算问题与知识源的关键词重叠度
+// 使用与知识库检索相同的分词，中文按相邻双字匹配；单字词元区分度太低，不参与计算
+func keywordRelevance(question *Question, source *KnowledgeItem) float64 {
+	sourceTokens := make(map[string]bool)
+	for _, token := range tools.Tokenize(source.Title + " " + source.Content) {
+		sourceTokens[token] = true
+	}
+
+	questionTokens := make(map[string]bool)
+	matches := 0
+	for _, token := range tools.Tokenize(question.Title + " " + question.Content) {
+		if utf8.RuneCountInString(token) < 2 || questionTokens[token] {
+			continue
+		}
+		questionTokens[token] = true
+		if sourceTokens[token] {
+			matches++
+		}
+	}
+
+	relevance := 0.0
+	if len(questionTokens) > 0 {
+		relevance = float64(matches) / float64(len(questionTokens))
+	}
+
+	// 标签匹配加分
+	for _, qTag := range question.Tags {
+		for _, sTag := range source.Tags {
+			if strings.EqualFold(qTag, sTag) {
+				relevance += 0.2
+				break
+			}
+		}
+	}
+
+	// 确保分数在0-1之间
+	if relevance > 1.0 {
+		relevance = 1.0
+	}
+
+	return relevance
+}
+
+// EmbeddingReranker 向量余弦相似度重排序器
+type EmbeddingReranker struct {
+	embedder tools.Embedder
+}
+
+// NewEmbeddingReranker 创建向量重排序器
+func NewEmbeddingReranker(embedder tools.Embedder) *EmbeddingReranker {
+	return &EmbeddingReranker{embedder: embedder}
+}
+
+// Score 计算问题向量与知识源向量的余弦相似度，负相似度记为0
+func (r *EmbeddingReranker) Score(ctx context.Context, question *Question, sources []KnowledgeItem) ([]float64, error) {
+	texts := make([]string, 0, len(sources)+1)
+	texts = append(texts, questionText(question))
+	for _, source := range sources {
+		texts = append(texts, source.Title+"\n"+truncateRunes(source.Content, rerankContentRunes))
+	}
+
+	vectors, err := r.embedder.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("生成重排序向量失败: %w", err)
+	}
+
+	scores := make([]float64, len(sources))
+	for i := range sources {
+		var similarity float64
+		for j, value := range vectors[0] {
+			if j < len(vectors[i+1]) {
+				similarity += float64(value) * float64(vectors[i+1][j])
+			}
+		}
+		scores[i] = clampScore(similarity)
+	}
+	return scores, nil
+}
+
+// Name 重排序器名称
+func (r *EmbeddingReranker) Name() string {
+	return RerankerEmbedding
+}
+
+// LLMReranker 大模型逐条打分重排序器
+type LLMReranker struct {
+	client *openai.Client
+}
+
+// NewLLMReranker 创建LLM重排序器
+func NewLLMReranker(client *openai.Client) *LLMReranker {
+	return &LLMReranker{client: client}
+}
+
+// scorePattern 匹配模型输出中的分数
+var scorePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Score 逐条请求模型给出0-10的相关性分数并归一化
+func (r *LLMReranker) Score(ctx context.Context, question *Question, sources []KnowledgeItem) ([]float64, error) {
+	scores := make([]float64, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, llmRerankConcurrency)
+	for i := range sources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			scores[i], errs[i] = r.scoreOne(ctx, question, &sources[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scores, nil
+}
+
+// scoreOne 为单个知识源打分
+func (r *LLMReranker) scoreOne(ctx context.Context, question *Question, source *KnowledgeItem) (float64, error) {
+	messages := []openai.Message{
+		{
+			Role:    "system",
+			Content: "你是检索结果评估器。根据参考资料能在多大程度上回答用户问题给出0到10的整数分数：0表示完全无关，10表示可以直接完整回答。只输出分数，不要输出其他内容。",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("用户问题：\n%s\n\n参考资料（%s）：\n%s\n%s",
+				questionText(question), source.Source, source.Title, truncateRunes(source.Content, rerankContentRunes)),
+		},
+	}
+
+	response, err := r.client.Chat(ctx, messages, 5, 0)
+	if err != nil {
+		return 0, fmt.Errorf("LLM打分失败: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return 0, fmt.Errorf("LLM没有返回分数")
+	}
+
+	match := scorePattern.FindString(response.Choices[0].Message.Content)
+	if match == "" {
+		return 0, fmt.Errorf("无法解析LLM分数: %s", response.Choices[0].Message.Content)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析LLM分数: %w", err)
+	}
+
+	return clampScore(score / 10), nil
+}
+
+// Name 重排序器名称
+func (r *LLMReranker) Name() string {
+	return RerankerLLM
+}
+
+// rerankSources 对知识源重排序
+// 最终相关性 = 重排序得分×0.7 + 按来源归一化的检索得分×0.3；重排序得分低于相似度阈值的知识源会被过滤
+func (p *Processor) rerankSources(ctx context.Context, question *Question, sources []KnowledgeItem) []KnowledgeItem {
+	if len(sources) == 0 {
+		return sources
+	}
+
+	rerankerName := p.reranker.Name()
+	scores, err := p.reranker.Score(ctx, question, sources)
+	if err != nil {
+		p.logger.WithError(err).WithField("reranker", rerankerName).Warn("重排序失败，使用启发式重排序")
+		rerankerName = RerankerHeuristic
+		scores, _ = NewHeuristicReranker().Score(ctx, question, sources)
+	}
+
+	retrievalScores := normalizeBySource(sources)
+	threshold := p.config.Fusion.SimilarityThreshold
+
+	ranked := make([]KnowledgeItem, 0, len(sources))
+	for i, source := range sources {
+		if scores[i] < threshold {
+			continue
+		}
+
+		// 元数据可能与缓存或其他结果共享，写入副本
+		metadata := make(map[string]interface{}, len(source.Metadata)+3)
+		for key, value := range source.Metadata {
+			metadata[key] = value
+		}
+		source.Metadata = metadata
+		source.Metadata["retrieval_score"] = source.Relevance
+		source.Metadata["rerank_score"] = scores[i]
+		source.Metadata["reranker"] = rerankerName
+
+		source.Relevance = rerankWeight*scores[i] + (1-rerankWeight)*retrievalScores[i]
+		ranked = append(ranked, source)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Relevance > ranked[j].Relevance
+	})
+
+	p.logger.WithFields(logrus.Fields{
+		"reranker":  rerankerName,
+		"threshold": threshold,
+		"kept":      len(ranked),
+		"dropped":   len(sources) - len(ranked),
+	}).Info("知识重排序完成")
+
+	return ranked
+}
+
+// normalizeBySource 按来源归一化检索得分
+// 不同来源的检索得分尺度不同（BM25/RRF、关键词匹配、固定值），除以同来源的最高分后才可比较
+func normalizeBySource(sources []KnowledgeItem) []float64 {
+	maxScores := make(map[KnowledgeSource]float64)
+	for _, source := range sources {
+		if source.Relevance > maxScores[source.Source] {
+			maxScores[source.Source] = source.Relevance
+		}
+	}
+
+	normalized := make([]float64, len(sources))
+	for i, source := range sources {
+		if maxScore := maxScores[source.Source]; maxScore > 0 {
+			normalized[i] = source.Relevance / maxScore
+		}
+	}
+	return normalized
+}
+
+// questionText 问题的完整文本
+func questionText(question *Question) string {
+	if question.Title == "" || question.Title == question.Content {
+		return question.Content
+	}
+	return question.Title + "\n" + question.Content
+}
+
+// clampScore 将分数限制在0-1之间
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
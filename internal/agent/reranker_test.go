@@ -0,0 +1,116 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/community-governance-mcp-higress/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rerankSources 重排序测试用的知识源
+func rerankSources() []model.KnowledgeItem {
+	return []model.KnowledgeItem{
+		{
+			ID:      "unrelated",
+			Source:  model.KnowledgeSourceDeepWiki,
+			Title:   "ai-proxy 插件",
+			Content: "ai-proxy 插件将请求转发到 OpenAI、通义千问等大模型服务。",
+		},
+		{
+			ID:      "rate-limit",
+			Source:  model.KnowledgeSourceLocal,
+			Title:   "key-rate-limit 插件",
+			Content: "key-rate-limit 插件可以按照请求头或参数对请求进行限流，配置 limit_by_header 和 limit_keys 字段。",
+		},
+	}
+}
+
+// TestEmbeddingReranker 测试向量重排序
+func TestEmbeddingReranker(t *testing.T) {
+	reranker := agent.NewEmbeddingReranker(tools.NewHashEmbedder(0))
+	question := &model.Question{Content: "Higress 如何配置限流插件"}
+
+	scores, err := reranker.Score(context.Background(), question, rerankSources())
+	require.NoError(t, err)
+	require.Len(t, scores, 2)
+	assert.Greater(t, scores[1], scores[0])
+	for _, score := range scores {
+		assert.GreaterOrEqual(t, score, 0.0)
+		assert.LessOrEqual(t, score, 1.0)
+	}
+	assert.Equal(t, agent.RerankerEmbedding, reranker.Name())
+}
+
+// TestHeuristicReranker 测试启发式重排序
+func TestHeuristicReranker(t *testing.T) {
+	reranker := agent.NewHeuristicReranker()
+	question := &model.Question{Content: "configure key-rate-limit plugin", Tags: []string{"plugin"}}
+	sources := []model.KnowledgeItem{
+		{Title: "ai-proxy", Content: "forward requests to llm providers"},
+		{Title: "key-rate-limit", Content: "configure key-rate-limit plugin", Tags: []string{"plugin"}},
+	}
+
+	scores, err := reranker.Score(context.Background(), question, sources)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, scores[0])
+	assert.Equal(t, 1.0, scores[1])
+
+	// 中文按相邻双字匹配
+	scores, err = reranker.Score(context.Background(), &model.Question{Content: "如何配置限流插件"}, rerankSources())
+	require.NoError(t, err)
+	assert.Greater(t, scores[1], 0.3)
+	assert.Greater(t, scores[1], scores[0])
+}
+
+// TestLLMReranker 测试LLM逐条打分
+func TestLLMReranker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		score := "2"
+		if strings.Contains(string(body), "limit_keys") {
+			score = "8"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"%s"},"finish_reason":"stop"}]}`, score)
+	}))
+	defer server.Close()
+
+	client := openai.NewClient("test-key", "gpt-4o")
+	client.SetBaseURL(server.URL)
+	reranker := agent.NewLLMReranker(client)
+
+	scores, err := reranker.Score(context.Background(), &model.Question{Content: "如何配置限流"}, rerankSources())
+	require.NoError(t, err)
+	assert.InDelta(t, 0.2, scores[0], 1e-9)
+	assert.InDelta(t, 0.8, scores[1], 1e-9)
+}
+
+// TestNewReranker 测试按配置创建重排序器
+func TestNewReranker(t *testing.T) {
+	config := &model.AgentConfig{}
+	config.Knowledge.Embedder = tools.EmbedderHash
+
+	reranker, err := agent.NewReranker("", nil, config)
+	require.NoError(t, err)
+	assert.Equal(t, agent.RerankerHeuristic, reranker.Name())
+
+	reranker, err = agent.NewReranker(agent.RerankerEmbedding, nil, config)
+	require.NoError(t, err)
+	assert.Equal(t, agent.RerankerEmbedding, reranker.Name())
+
+	_, err = agent.NewReranker(agent.RerankerLLM, nil, config)
+	assert.Error(t, err)
+
+	_, err = agent.NewReranker("cross-encoder", nil, config)
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRetrievalTimeout 未配置时的整体检索时间预算
+	defaultRetrievalTimeout = 10 * time.Second
+	// defaultMinRelevance 未配置时计入提前返回数量的最低相关性（重排序后）
+	defaultMinRelevance = 0.5
+	// defaultSourceTimeout 未配置且没有内置预算的知识源的时间预算
+	defaultSourceTimeout = 5 * time.Second
+)
+
+// defaultSourceTimeouts 未配置时各知识源的时间预算
+var defaultSourceTimeouts = map[KnowledgeSource]time.Duration{
+	KnowledgeSourceLocal:    2 * time.Second,
+	KnowledgeSourceHigress:  5 * time.Second,
+	KnowledgeSourceDeepWiki: 8 * time.Second,
+	KnowledgeSourceGitHub:   6 * time.Second,
+}
+
+// errEarlyReturn 已获得足够的高相关知识，取消仍在进行的检索
+var errEarlyReturn = errors.New("已获得足够的高相关知识")
+
+// sourceResult 单个知识源的检索结果
+type sourceResult struct {
+	index   int
+	items   []KnowledgeItem
+	err     error
+	latency time.Duration
+}
+
+// retrieveKnowledge 并发检索所有启用的知识源
+// 每个知识源有独立的时间预算，整体检索不超过全局预算；配置了min_sources时，
+// 已完成的知识源中高相关知识达到数量后立即返回并取消其余检索。结果按知识源顺序合并，各知识源状态随结果返回
+// 各知识源的原始检索得分尺度不同，高相关知识按重排序后的相关性计数，与融合阶段使用同样的打分和相似度阈值
+func (p *Processor) retrieveKnowledge(ctx context.Context, question *Question) ([]KnowledgeItem, []SourceStatus, error) {
+	retrievers := p.retrievers.Enabled()
+	budget := p.config.Retrieval
+
+	timeout := budget.Timeout
+	if timeout <= 0 {
+		timeout = defaultRetrievalTimeout
+	}
+	retrievalCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	earlyCtx, cancelEarly := context.WithCancelCause(retrievalCtx)
+	defer cancelEarly(nil)
+
+	startTime := time.Now()
+	results := make(chan sourceResult, len(retrievers))
+	for i, retriever := range retrievers {
+		go func(i int, retriever Retriever) {
+			source := KnowledgeSource(retriever.Name())
+			sourceCtx, cancel := context.WithTimeout(earlyCtx, p.sourceTimeout(source))
+			defer cancel()
+
+			started := time.Now()
+			items, err := retriever.Retrieve(sourceCtx, question)
+			if err == nil && sourceCtx.Err() != nil && len(items) == 0 {
+				err = sourceCtx.Err()
+			}
+			// 检索器已标注来源（如MCP服务器配置的source_type）时保留
+			for j := range items {
+				if items[j].Source == "" {
+					items[j].Source = source
+				}
+			}
+			results <- sourceResult{index: i, items: items, err: err, latency: time.Since(started)}
+		}(i, retriever)
+	}
+
+	minRelevance := budget.MinRelevance
+	if minRelevance <= 0 {
+		minRelevance = defaultMinRelevance
+	}
+
+	collected := make([]*sourceResult, len(retrievers))
+	relevantCount := 0
+collect:
+	for pending := len(retrievers); pending > 0; pending-- {
+		var result sourceResult
+		select {
+		case result = <-results:
+		case <-retrievalCtx.Done():
+			break collect
+		}
+
+		collected[result.index] = &result
+		source := KnowledgeSource(retrievers[result.index].Name())
+		if result.err != nil {
+			p.logger.WithError(result.err).WithField("source", source).Warn("知识源检索失败")
+		}
+		// 流式事件在汇总协程中发送，避免并发写入
+		emitSourceEvent(ctx, source, result.items, result.err)
+
+		if budget.MinSources > 0 && pending > 1 && result.err == nil && !isFallbackResult(result.items) {
+			relevantCount += p.countRelevant(retrievalCtx, question, result.items, minRelevance)
+		}
+		if budget.MinSources > 0 && relevantCount >= budget.MinSources && pending > 1 {
+			cancelEarly(errEarlyReturn)
+			break collect
+		}
+	}
+
+	var allSources []KnowledgeItem
+	statuses := make([]SourceStatus, len(retrievers))
+	for i, retriever := range retrievers {
+		statuses[i] = p.sourceStatus(earlyCtx, KnowledgeSource(retriever.Name()), collected[i], time.Since(startTime))
+		if collected[i] != nil && collected[i].err == nil {
+			allSources = append(allSources, collected[i].items...)
+		}
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"sources_count": len(allSources),
+		"elapsed":       time.Since(startTime),
+	}).Info("知识检索完成")
+	return allSources, statuses, nil
+}
+
+// countRelevant 统计重排序后相关性不低于minRelevance的知识数量
+// 重排序按来源归一化检索得分，单个知识源单独重排序与融合阶段整体重排序得到的相关性一致
+func (p *Processor) countRelevant(ctx context.Context, question *Question, items []KnowledgeItem, minRelevance float64) int {
+	count := 0
+	for _, item := range p.rerankSources(ctx, question, items) {
+		if item.Relevance >= minRelevance {
+			count++
+		}
+	}
+	return count
+}
+
+// sourceTimeout 获取知识源的时间预算
+func (p *Processor) sourceTimeout(source KnowledgeSource) time.Duration {
+	if timeout, ok := p.config.Retrieval.SourceTimeouts[string(source)]; ok && timeout > 0 {
+		return timeout
+	}
+	if timeout, ok := defaultSourceTimeouts[source]; ok {
+		return timeout
+	}
+	return defaultSourceTimeout
+}
+
+// sourceStatus 根据检索结果生成知识源状态，result为nil表示未在预算内完成
+func (p *Processor) sourceStatus(ctx context.Context, source KnowledgeSource, result *sourceResult, elapsed time.Duration) SourceStatus {
+	if result == nil {
+		status := SourceStatus{Source: source, Status: RetrievalStatusTimeout, Latency: elapsed.String(), Error: "超出整体检索时间预算"}
+		if errors.Is(context.Cause(ctx), errEarlyReturn) {
+			status.Status = RetrievalStatusCancelled
+			status.Error = errEarlyReturn.Error()
+		}
+		return status
+	}
+
+	status := SourceStatus{Source: source, Count: len(result.items), Latency: result.latency.String()}
+	switch {
+	case result.err != nil && errors.Is(result.err, context.DeadlineExceeded):
+		status.Status = RetrievalStatusTimeout
+		status.Error = fmt.Sprintf("超出时间预算 %s", p.sourceTimeout(source))
+	case result.err != nil:
+		status.Status = RetrievalStatusError
+		status.Error = result.err.Error()
+	case isFallbackResult(result.items):
+		status.Status = RetrievalStatusFallback
+	default:
+		status.Status = RetrievalStatusOK
+	}
+	return status
+}
+
+// isFallbackResult 判断结果是否全部来自备用数据或本地文档镜像
+func isFallbackResult(items []KnowledgeItem) bool {
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		source := item.Metadata["source"]
+		if source != "fallback_cache" && source != docsMirrorSource {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRetrievalProcessor 创建只使用本地知识库和Higress文档的处理器
+func newRetrievalProcessor(t *testing.T, retrieval model.RetrievalBudgetConfig) *agent.Processor {
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.Knowledge.Enabled = true
+	config.Knowledge.StoragePath = t.TempDir()
+	config.Knowledge.Embedder = "hash"
+	config.Retrieval = retrieval
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	_, err := processor.GetKnowledgeBase().AddDocument(model.Document{
+		Title:   "key-rate-limit 插件",
+		Content: "key-rate-limit 插件按照请求头或参数对请求进行限流，配置 limit_by_header 和 limit_keys。",
+	})
+	require.NoError(t, err)
+	return processor
+}
+
+// sourceStatusMap 按知识源索引检索状态
+func sourceStatusMap(statuses []model.SourceStatus) map[model.KnowledgeSource]model.SourceStatus {
+	result := make(map[model.KnowledgeSource]model.SourceStatus, len(statuses))
+	for _, status := range statuses {
+		result[status.Source] = status
+	}
+	return result
+}
+
+// TestRetrievalSourceBudget 测试单个知识源超出预算时不阻塞整体检索
+func TestRetrievalSourceBudget(t *testing.T) {
+	processor := newRetrievalProcessor(t, model.RetrievalBudgetConfig{
+		Timeout:        2 * time.Second,
+		SourceTimeouts: map[string]time.Duration{"higress": 300 * time.Millisecond},
+	})
+
+	start := time.Now()
+	response, err := processor.ProcessQuestion(context.Background(), &model.ProcessRequest{
+		Type:    model.QuestionTypeText,
+		Content: "key-rate-limit 插件如何配置",
+	})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	statuses := sourceStatusMap(response.SourceStatuses)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, model.RetrievalStatusOK, statuses[model.KnowledgeSourceLocal].Status)
+	assert.Equal(t, 1, statuses[model.KnowledgeSourceLocal].Count)
+	assert.Contains(t, []model.RetrievalStatus{model.RetrievalStatusOK, model.RetrievalStatusFallback}, statuses[model.KnowledgeSourceHigress].Status)
+}
+
+// TestRetrievalEarlyReturn 测试获得足够高相关知识后提前返回
+func TestRetrievalEarlyReturn(t *testing.T) {
+	processor := newRetrievalProcessor(t, model.RetrievalBudgetConfig{
+		Timeout:        10 * time.Second,
+		SourceTimeouts: map[string]time.Duration{"higress": 10 * time.Second},
+		MinSources:     1,
+		MinRelevance:   0.5,
+	})
+
+	start := time.Now()
+	response, err := processor.ProcessQuestion(context.Background(), &model.ProcessRequest{
+		Type:    model.QuestionTypeText,
+		Content: "key-rate-limit limit_keys",
+	})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	statuses := sourceStatusMap(response.SourceStatuses)
+	assert.Equal(t, model.RetrievalStatusOK, statuses[model.KnowledgeSourceLocal].Status)
+	assert.Contains(t, []model.RetrievalStatus{model.RetrievalStatusOK, model.RetrievalStatusCancelled}, statuses[model.KnowledgeSourceHigress].Status)
+}
+
+// TestRetrievalEarlyReturnUsesRerankedRelevance 测试提前返回按重排序后的相关性计数，快速返回的无关知识不会取消相关的慢知识源
+func TestRetrievalEarlyReturnUsesRerankedRelevance(t *testing.T) {
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.Retrieval = model.RetrievalBudgetConfig{
+		Timeout:      5 * time.Second,
+		MinSources:   3,
+		MinRelevance: 0.5,
+		Sources:      []model.RetrieverConfig{{Name: "higress", Type: "higress", Enabled: false}},
+	}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	// 快速返回的无关文档，检索得分未经校准
+	registry := processor.GetRetrieverRegistry()
+	registry.Register(agent.NewFuncRetriever("fast-local", nil, func(ctx context.Context, question *model.Question) ([]model.KnowledgeItem, error) {
+		return []model.KnowledgeItem{
+			{ID: "pasta", Title: "意面做法", Content: "意大利面煮八分钟后捞出，拌入番茄酱和罗勒即可。", Relevance: 1},
+			{ID: "garden", Title: "阳台种菜", Content: "番茄需要充足日照，每周浇水两到三次。", Relevance: 1},
+			{ID: "bicycle", Title: "自行车保养", Content: "链条每骑行三百公里清洁一次并上油。", Relevance: 1},
+		}, nil
+	}))
+	registry.Register(agent.NewFuncRetriever("slow-wiki", nil, func(ctx context.Context, question *model.Question) ([]model.KnowledgeItem, error) {
+		select {
+		case <-time.After(300 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return []model.KnowledgeItem{{
+			ID:        "wiki-rate-limit",
+			Title:     "key-rate-limit 插件",
+			Content:   "key-rate-limit 插件按照请求头对请求限流，limit_keys 配置每个键的限流阈值。",
+			Relevance: 0.4,
+		}}, nil
+	}))
+
+	response, err := processor.ProcessQuestion(context.Background(), &model.ProcessRequest{
+		Type:    model.QuestionTypeText,
+		Content: "key-rate-limit 插件 limit_keys 怎么配置",
+	})
+	require.NoError(t, err)
+
+	statuses := sourceStatusMap(response.SourceStatuses)
+	assert.Equal(t, model.RetrievalStatusOK, statuses["fast-local"].Status)
+	assert.Equal(t, model.RetrievalStatusOK, statuses["slow-wiki"].Status)
+	require.NotEmpty(t, response.Sources)
+	assert.Equal(t, model.KnowledgeSource("slow-wiki"), response.Sources[0].Source)
+}
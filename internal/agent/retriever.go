@@ -0,0 +1,240 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// RetrieverTypeLocal 本地知识库检索器
+	RetrieverTypeLocal = "local"
+	// RetrieverTypeHigress Higress文档检索器
+	RetrieverTypeHigress = "higress"
+	// RetrieverTypeDeepWiki DeepWiki检索器
+	RetrieverTypeDeepWiki = "deepwiki"
+	// RetrieverTypeMCP 通用MCP工具检索器
+	RetrieverTypeMCP = "mcp"
+	// RetrieverTypeMCPResource MCP资源检索器
+	RetrieverTypeMCPResource = "mcp_resource"
+	// RetrieverTypeGitHub GitHub Issue和讨论检索器
+	RetrieverTypeGitHub = "github"
+)
+
+// Retriever 知识源检索器
+// 检索器返回的知识项保留检索器设置的来源类型（如wiki、github），未设置来源时记为检索器名称
+type Retriever interface {
+	// Name 知识源名称
+	Name() string
+	// Enabled 是否参与检索
+	Enabled() bool
+	// Retrieve 检索与问题相关的知识
+	Retrieve(ctx context.Context, question *Question) ([]KnowledgeItem, error)
+}
+
+// RetrieverFactory 根据配置创建检索器
+type RetrieverFactory func(p *Processor, config RetrieverConfig) (Retriever, error)
+
+var (
+	retrieverFactories = make(map[string]RetrieverFactory)
+	factoriesMutex     sync.RWMutex
+)
+
+// RegisterRetrieverType 注册检索器类型，需在创建Processor之前调用，配置中的type与kind对应
+func RegisterRetrieverType(kind string, factory RetrieverFactory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	retrieverFactories[kind] = factory
+}
+
+// RetrieverTypes 获取已注册的检索器类型
+func RetrieverTypes() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	kinds := make([]string, 0, len(retrieverFactories))
+	for kind := range retrieverFactories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func init() {
+	RegisterRetrieverType(RetrieverTypeLocal, func(p *Processor, config RetrieverConfig) (Retriever, error) {
+		return NewFuncRetriever(config.Name, func() bool {
+			return config.Enabled && p.config.Knowledge.Enabled && p.knowledgeBase != nil
+		}, p.retrieveLocalKnowledge), nil
+	})
+	RegisterRetrieverType(RetrieverTypeHigress, func(p *Processor, config RetrieverConfig) (Retriever, error) {
+		return NewFuncRetriever(config.Name, func() bool {
+			return config.Enabled
+		}, p.retrieveHigressDocs), nil
+	})
+	RegisterRetrieverType(RetrieverTypeDeepWiki, func(p *Processor, config RetrieverConfig) (Retriever, error) {
+		return NewFuncRetriever(config.Name, func() bool {
+			return config.Enabled && p.config.DeepWiki.Enabled
+		}, p.retrieveDeepWiki), nil
+	})
+	RegisterRetrieverType(RetrieverTypeMCP, newMCPRetriever)
+	RegisterRetrieverType(RetrieverTypeMCPResource, newMCPResourceRetriever)
+	RegisterRetrieverType(RetrieverTypeGitHub, newGitHubRetriever)
+}
+
+// FuncRetriever 由函数实现的检索器
+type FuncRetriever struct {
+	name     string
+	enabled  func() bool
+	retrieve func(ctx context.Context, question *Question) ([]KnowledgeItem, error)
+}
+
+// NewFuncRetriever 创建函数检索器，enabled为nil时始终启用
+func NewFuncRetriever(name string, enabled func() bool, retrieve func(ctx context.Context, question *Question) ([]KnowledgeItem, error)) *FuncRetriever {
+	return &FuncRetriever{
+		name:     name,
+		enabled:  enabled,
+		retrieve: retrieve,
+	}
+}
+
+// Name 知识源名称
+func (r *FuncRetriever) Name() string {
+	return r.name
+}
+
+// Enabled 是否参与检索
+func (r *FuncRetriever) Enabled() bool {
+	return r.enabled == nil || r.enabled()
+}
+
+// Retrieve 检索知识
+func (r *FuncRetriever) Retrieve(ctx context.Context, question *Question) ([]KnowledgeItem, error) {
+	return r.retrieve(ctx, question)
+}
+
+// RetrieverRegistry 检索器注册表，检索结果按注册顺序合并
+type RetrieverRegistry struct {
+	retrievers []Retriever
+	mutex      sync.RWMutex
+}
+
+// NewRetrieverRegistry 创建检索器注册表
+func NewRetrieverRegistry() *RetrieverRegistry {
+	return &RetrieverRegistry{}
+}
+
+// Register 注册检索器，同名检索器会被替换
+func (rr *RetrieverRegistry) Register(retriever Retriever) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	for i, existing := range rr.retrievers {
+		if existing.Name() == retriever.Name() {
+			rr.retrievers[i] = retriever
+			return
+		}
+	}
+	rr.retrievers = append(rr.retrievers, retriever)
+}
+
+// Unregister 移除检索器
+func (rr *RetrieverRegistry) Unregister(name string) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	for i, existing := range rr.retrievers {
+		if existing.Name() == name {
+			rr.retrievers = append(rr.retrievers[:i], rr.retrievers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Get 按名称获取检索器
+func (rr *RetrieverRegistry) Get(name string) (Retriever, bool) {
+	rr.mutex.RLock()
+	defer rr.mutex.RUnlock()
+
+	for _, retriever := range rr.retrievers {
+		if retriever.Name() == name {
+			return retriever, true
+		}
+	}
+	return nil, false
+}
+
+// List 获取所有检索器
+func (rr *RetrieverRegistry) List() []Retriever {
+	rr.mutex.RLock()
+	defer rr.mutex.RUnlock()
+
+	return append([]Retriever(nil), rr.retrievers...)
+}
+
+// Enabled 获取当前启用的检索器
+func (rr *RetrieverRegistry) Enabled() []Retriever {
+	var enabled []Retriever
+	for _, retriever := range rr.List() {
+		if retriever.Enabled() {
+			enabled = append(enabled, retriever)
+		}
+	}
+	return enabled
+}
+
+// defaultRetrieverConfigs 未配置sources时使用的内置知识源
+func defaultRetrieverConfigs() []RetrieverConfig {
+	return []RetrieverConfig{
+		{Name: RetrieverTypeLocal, Type: RetrieverTypeLocal, Enabled: true},
+		{Name: RetrieverTypeHigress, Type: RetrieverTypeHigress, Enabled: true},
+		{Name: RetrieverTypeDeepWiki, Type: RetrieverTypeDeepWiki, Enabled: true},
+	}
+}
+
+// newRetrieverRegistry 根据配置创建检索器注册表，无效的配置项记录日志后跳过
+func (p *Processor) newRetrieverRegistry() *RetrieverRegistry {
+	configs := p.config.Retrieval.Sources
+	if len(configs) == 0 {
+		configs = defaultRetrieverConfigs()
+	}
+
+	registry := NewRetrieverRegistry()
+	for _, config := range configs {
+		retriever, err := newRetriever(p, config)
+		if err != nil {
+			p.logger.WithError(err).WithField("source", config.Name).Warn("创建知识源检索器失败")
+			continue
+		}
+		registry.Register(retriever)
+	}
+	return registry
+}
+
+// newRetriever 根据配置创建检索器，名称为空时使用类型名
+func newRetriever(p *Processor, config RetrieverConfig) (Retriever, error) {
+	if config.Type == "" {
+		config.Type = config.Name
+	}
+	if config.Name == "" {
+		config.Name = config.Type
+	}
+
+	factoriesMutex.RLock()
+	factory, ok := retrieverFactories[config.Type]
+	factoriesMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的检索器类型: %s", config.Type)
+	}
+
+	return factory(p, config)
+}
+
+// optionString 读取字符串类型的检索器选项
+func optionString(options map[string]interface{}, key, defaultValue string) string {
+	if value, ok := options[key].(string); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
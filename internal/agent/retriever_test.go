@@ -0,0 +1,72 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRetrieverRegistry 测试通过配置注册自定义知识源
+func TestRetrieverRegistry(t *testing.T) {
+	agent.RegisterRetrieverType("static", func(p *agent.Processor, config model.RetrieverConfig) (agent.Retriever, error) {
+		content, _ := config.Options["content"].(string)
+		return agent.NewFuncRetriever(config.Name, nil, func(ctx context.Context, question *model.Question) ([]model.KnowledgeItem, error) {
+			return []model.KnowledgeItem{{
+				ID:        "wiki-1",
+				Title:     "内部Wiki: 限流",
+				Content:   content,
+				Relevance: 1,
+			}}, nil
+		}), nil
+	})
+	assert.Contains(t, agent.RetrieverTypes(), "static")
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.Retrieval.Sources = []model.RetrieverConfig{
+		{Name: "internal-wiki", Type: "static", Enabled: true, Options: map[string]interface{}{
+			"content": "内部网关统一使用 key-rate-limit 插件限流，limit_keys 按租户配置。",
+		}},
+		{Name: "higress", Type: "higress", Enabled: false},
+		{Name: "broken", Type: "unknown", Enabled: true},
+		{Name: "team-mcp", Type: "mcp", Enabled: true},
+	}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	registry := processor.GetRetrieverRegistry()
+	_, ok := registry.Get("broken")
+	assert.False(t, ok)
+	_, ok = registry.Get("team-mcp")
+	assert.False(t, ok, "缺少server的MCP检索器不应被注册")
+	require.Len(t, registry.List(), 2)
+	require.Len(t, registry.Enabled(), 1)
+
+	// 运行时注册的检索器同样参与检索
+	registry.Register(agent.NewFuncRetriever("failing", nil, func(ctx context.Context, question *model.Question) ([]model.KnowledgeItem, error) {
+		return nil, fmt.Errorf("服务不可用")
+	}))
+
+	response, err := processor.ProcessQuestion(context.Background(), &model.ProcessRequest{
+		Type:    model.QuestionTypeText,
+		Content: "key-rate-limit 限流怎么配置",
+	})
+	require.NoError(t, err)
+
+	statuses := sourceStatusMap(response.SourceStatuses)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, model.RetrievalStatusOK, statuses["internal-wiki"].Status)
+	assert.Equal(t, model.RetrievalStatusError, statuses["failing"].Status)
+
+	require.NotEmpty(t, response.Sources)
+	assert.Equal(t, model.KnowledgeSource("internal-wiki"), response.Sources[0].Source)
+}
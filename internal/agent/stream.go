@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+)
+
+// EventHandler 流式事件处理函数
+type EventHandler func(event *StreamEvent)
+
+// eventHandlerKey 上下文中事件处理函数的键
+type eventHandlerKey struct{}
+
+// withEventHandler 将事件处理函数放入上下文
+func withEventHandler(ctx context.Context, handler EventHandler) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, handler)
+}
+
+// emitEvent 发送流式事件，非流式调用时为空操作
+func emitEvent(ctx context.Context, eventType StreamEventType, data interface{}) {
+	handler, ok := ctx.Value(eventHandlerKey{}).(EventHandler)
+	if !ok || handler == nil {
+		return
+	}
+	handler(&StreamEvent{Type: eventType, Data: data})
+}
+
+// isStreaming 判断当前调用是否为流式调用
+func isStreaming(ctx context.Context) bool {
+	handler, ok := ctx.Value(eventHandlerKey{}).(EventHandler)
+	return ok && handler != nil
+}
+
+// ProcessQuestionStream 流式处理用户问题
+// 处理流程与ProcessQuestion一致，各阶段完成时通过handler发送事件
+func (p *Processor) ProcessQuestionStream(ctx context.Context, request *ProcessRequest, handler EventHandler) (*ProcessResponse, error) {
+	return p.ProcessQuestion(withEventHandler(ctx, handler), request)
+}
+
+// emitAnswerText 将非LLM生成的回答内容（模板回答、参考来源）作为一个增量事件发送
+// LLM生成的内容由synthesizeAnswer逐个转发模型的流式增量
+func emitAnswerText(ctx context.Context, content string) {
+	if content == "" {
+		return
+	}
+	emitEvent(ctx, StreamEventToken, map[string]interface{}{
+		"delta": content,
+	})
+}
+
+// emitSourceEvent 发送单个知识源的检索结果事件
+func emitSourceEvent(ctx context.Context, source KnowledgeSource, items []KnowledgeItem, err error) {
+	data := map[string]interface{}{
+		"source": source,
+		"count":  len(items),
+		"items":  items,
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	emitEvent(ctx, StreamEventSource, data)
+}
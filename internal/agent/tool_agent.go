@@ -0,0 +1,412 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultToolAgentMaxSteps 未配置max_steps时的最大推理步数
+	DefaultToolAgentMaxSteps = 6
+	// MaxToolAgentSteps 单次运行允许的最大推理步数，请求中的max_steps超过该值时截断
+	MaxToolAgentSteps = 20
+	// defaultToolAgentMaxTokens 每次调用模型的默认最大输出Token数
+	defaultToolAgentMaxTokens = 1500
+	// defaultToolAgentTemperature 默认模型温度
+	defaultToolAgentTemperature = 0.2
+	// maxObservationLength 工具结果回填给模型时的最大字符数
+	maxObservationLength = 8000
+)
+
+const (
+	// ToolSourceLocal ToolLoader中的本地工具
+	ToolSourceLocal = "local"
+	// ToolSourceMCP MCP工具目录中的远程工具
+	ToolSourceMCP = "mcp"
+)
+
+const (
+	// AgentStopAnswered 模型不再调用工具并给出最终回答
+	AgentStopAnswered = "answered"
+	// AgentStopMaxSteps 达到步数上限，模型根据已有信息给出回答
+	AgentStopMaxSteps = "max_steps"
+)
+
+// ErrToolAgentUnavailable 未配置OpenAI时无法运行工具调用Agent
+var ErrToolAgentUnavailable = errors.New("未配置OpenAI，无法运行工具调用Agent")
+
+// toolAgentSystemPrompt 工具调用Agent的系统提示词
+const toolAgentSystemPrompt = `你是Higress社区治理助手，负责回答社区问题、分析Bug和Issue、查询社区数据。
+你可以调用提供的工具获取信息。根据工具返回的结果逐步推理，信息足够时直接给出最终回答，不要编造工具没有返回的信息。
+工具调用失败时可以调整参数重试或改用其他工具。最终回答使用用户提问的语言。`
+
+// maxStepsPrompt 达到步数上限时要求模型直接回答的提示
+const maxStepsPrompt = "已达到工具调用步数上限，请不要再调用工具，根据已有信息给出最终回答。"
+
+// AgentRunRequest 工具调用Agent运行请求
+type AgentRunRequest struct {
+	Query    string   `json:"query" binding:"required"` // 用户问题
+	Context  string   `json:"context,omitempty"`        // 补充上下文
+	MaxSteps int      `json:"max_steps,omitempty"`      // 最大推理步数，为0时使用配置值
+	Tools    []string `json:"tools,omitempty"`          // 允许使用的工具，MCP工具使用带服务器前缀的名称，为空时使用全部工具
+}
+
+// AgentRunResult 工具调用Agent运行结果
+type AgentRunResult struct {
+	Answer         string       `json:"answer"`          // 最终回答
+	StopReason     string       `json:"stop_reason"`     // 结束原因：answered、max_steps
+	Steps          []AgentStep  `json:"steps"`           // 每一步的模型输出和工具调用
+	Tools          []string     `json:"tools"`           // 提供给模型的工具
+	Usage          openai.Usage `json:"usage"`           // 全部步骤的Token用量
+	ProcessingTime string       `json:"processing_time"` // 处理时间
+}
+
+// AgentStep 一次模型调用及其发起的工具调用
+type AgentStep struct {
+	Index     int             `json:"index"`                // 步骤序号，从1开始
+	Content   string          `json:"content,omitempty"`    // 模型输出的文本
+	ToolCalls []AgentToolCall `json:"tool_calls,omitempty"` // 模型发起的工具调用
+	Usage     openai.Usage    `json:"usage"`                // 本步的Token用量
+	Duration  string          `json:"duration"`             // 本步耗时，包含工具执行时间
+}
+
+// AgentToolCall 工具调用记录
+type AgentToolCall struct {
+	ID        string                 `json:"id"`                  // 模型生成的调用ID
+	Tool      string                 `json:"tool"`                // 工具名，MCP工具为带服务器前缀的名称
+	Function  string                 `json:"function"`            // 提供给模型的函数名
+	Source    string                 `json:"source,omitempty"`    // 工具来源：local、mcp
+	Arguments map[string]interface{} `json:"arguments,omitempty"` // 调用参数
+	Output    string                 `json:"output,omitempty"`    // 回填给模型的工具结果
+	Error     string                 `json:"error,omitempty"`     // 调用失败的原因
+	Duration  string                 `json:"duration"`            // 调用耗时
+}
+
+// agentTool 提供给模型的工具
+type agentTool struct {
+	name       string
+	source     string
+	definition openai.Tool
+	call       func(ctx context.Context, args map[string]interface{}) (string, error)
+}
+
+// ToolAgent 工具调用Agent
+// 将ToolLoader工具和MCP工具目录中的工具作为函数提供给模型，由模型发起工具调用、观察结果并迭代，直到给出回答或达到步数上限
+type ToolAgent struct {
+	processor *Processor
+	loader    *ToolLoader
+	config    ToolAgentConfig
+	logger    *logrus.Logger
+}
+
+// NewToolAgent 创建工具调用Agent，loader为nil时只使用MCP工具
+func NewToolAgent(processor *Processor, loader *ToolLoader) *ToolAgent {
+	config := processor.config.ToolAgent
+	if config.MaxSteps <= 0 {
+		config.MaxSteps = DefaultToolAgentMaxSteps
+	}
+	if config.MaxSteps > MaxToolAgentSteps {
+		config.MaxSteps = MaxToolAgentSteps
+	}
+	if config.MaxTokens <= 0 {
+		config.MaxTokens = defaultToolAgentMaxTokens
+	}
+	if config.Temperature <= 0 {
+		config.Temperature = defaultToolAgentTemperature
+	}
+
+	return &ToolAgent{
+		processor: processor,
+		loader:    loader,
+		config:    config,
+		logger:    processor.logger,
+	}
+}
+
+// Run 运行工具调用Agent
+// 模型调用失败时返回已完成步骤的结果和错误，调用方可以据此排查
+func (a *ToolAgent) Run(ctx context.Context, request *AgentRunRequest) (*AgentRunResult, error) {
+	startTime := time.Now()
+	if request.Query == "" {
+		return nil, fmt.Errorf("query不能为空")
+	}
+	client := a.processor.openaiClient
+	if client == nil || !client.IsConfigured() {
+		return nil, ErrToolAgentUnavailable
+	}
+
+	if a.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.config.Timeout)
+		defer cancel()
+	}
+
+	maxSteps := a.config.MaxSteps
+	if request.MaxSteps > 0 {
+		maxSteps = request.MaxSteps
+		if maxSteps > MaxToolAgentSteps {
+			maxSteps = MaxToolAgentSteps
+		}
+	}
+
+	tools := a.availableTools(ctx, request.Tools)
+	toolsByFunction := make(map[string]*agentTool, len(tools))
+	definitions := make([]openai.Tool, 0, len(tools))
+	result := &AgentRunResult{Tools: make([]string, 0, len(tools))}
+	for _, tool := range tools {
+		toolsByFunction[tool.definition.Function.Name] = tool
+		definitions = append(definitions, tool.definition)
+		result.Tools = append(result.Tools, tool.name)
+	}
+
+	userContent := request.Query
+	if request.Context != "" {
+		userContent = fmt.Sprintf("%s\n\n补充上下文：\n%s", request.Query, request.Context)
+	}
+	messages := []openai.Message{
+		{Role: "system", Content: toolAgentSystemPrompt},
+		{Role: "user", Content: userContent},
+	}
+
+	finish := func(answer, stopReason string) *AgentRunResult {
+		result.Answer = answer
+		result.StopReason = stopReason
+		result.ProcessingTime = time.Since(startTime).String()
+		a.logger.WithFields(logrus.Fields{
+			"steps":       len(result.Steps),
+			"stop_reason": stopReason,
+			"tokens":      result.Usage.TotalTokens,
+		}).Info("工具调用Agent运行完成")
+		return result
+	}
+
+	for index := 1; index <= maxSteps; index++ {
+		stepStart := time.Now()
+		var (
+			response *openai.ChatResponse
+			err      error
+		)
+		if len(definitions) > 0 {
+			response, err = client.ChatWithTools(ctx, messages, definitions, a.config.MaxTokens, a.config.Temperature)
+		} else {
+			response, err = client.Chat(ctx, messages, a.config.MaxTokens, a.config.Temperature)
+		}
+		if err != nil {
+			result.ProcessingTime = time.Since(startTime).String()
+			return result, fmt.Errorf("第%d步调用模型失败: %w", index, err)
+		}
+		if len(response.Choices) == 0 {
+			result.ProcessingTime = time.Since(startTime).String()
+			return result, fmt.Errorf("第%d步模型没有返回任何内容", index)
+		}
+
+		message := response.Choices[0].Message
+		addUsage(&result.Usage, response.Usage)
+		step := AgentStep{Index: index, Content: message.Content, Usage: response.Usage}
+
+		if len(message.ToolCalls) == 0 {
+			step.Duration = time.Since(stepStart).String()
+			result.Steps = append(result.Steps, step)
+			return finish(message.Content, AgentStopAnswered), nil
+		}
+
+		messages = append(messages, openai.Message{
+			Role:      "assistant",
+			Content:   message.Content,
+			ToolCalls: message.ToolCalls,
+		})
+		for _, call := range message.ToolCalls {
+			record := a.executeToolCall(ctx, toolsByFunction, call)
+			step.ToolCalls = append(step.ToolCalls, record)
+
+			observation := record.Output
+			if record.Error != "" {
+				observation = "工具调用失败: " + record.Error
+			}
+			messages = append(messages, openai.Message{
+				Role:       "tool",
+				Content:    observation,
+				ToolCallID: call.ID,
+			})
+		}
+		step.Duration = time.Since(stepStart).String()
+		result.Steps = append(result.Steps, step)
+	}
+
+	// 达到步数上限，不再提供工具，要求模型根据已有信息回答
+	stepStart := time.Now()
+	messages = append(messages, openai.Message{Role: "user", Content: maxStepsPrompt})
+	response, err := client.Chat(ctx, messages, a.config.MaxTokens, a.config.Temperature)
+	if err != nil {
+		result.ProcessingTime = time.Since(startTime).String()
+		return result, fmt.Errorf("达到步数上限后生成回答失败: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		result.ProcessingTime = time.Since(startTime).String()
+		return result, fmt.Errorf("达到步数上限后模型没有返回任何内容")
+	}
+
+	answer := response.Choices[0].Message.Content
+	addUsage(&result.Usage, response.Usage)
+	result.Steps = append(result.Steps, AgentStep{
+		Index:    maxSteps + 1,
+		Content:  answer,
+		Usage:    response.Usage,
+		Duration: time.Since(stepStart).String(),
+	})
+	return finish(answer, AgentStopMaxSteps), nil
+}
+
+// executeToolCall 执行模型发起的工具调用，失败原因记录在返回值中并回填给模型
+func (a *ToolAgent) executeToolCall(ctx context.Context, toolsByFunction map[string]*agentTool, call openai.ToolCall) AgentToolCall {
+	startTime := time.Now()
+	record := AgentToolCall{
+		ID:       call.ID,
+		Tool:     call.Function.Name,
+		Function: call.Function.Name,
+	}
+
+	tool, exists := toolsByFunction[call.Function.Name]
+	if !exists {
+		record.Error = fmt.Sprintf("未知工具: %s", call.Function.Name)
+		record.Duration = time.Since(startTime).String()
+		return record
+	}
+	record.Tool = tool.name
+	record.Source = tool.source
+
+	arguments := map[string]interface{}{}
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+			record.Error = fmt.Sprintf("参数不是合法的JSON对象: %v", err)
+			record.Duration = time.Since(startTime).String()
+			return record
+		}
+	}
+	record.Arguments = arguments
+
+	output, err := tool.call(ctx, arguments)
+	record.Duration = time.Since(startTime).String()
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Output = truncateRunes(output, maxObservationLength)
+	}
+
+	entry := a.logger.WithFields(logrus.Fields{
+		"tool":     record.Tool,
+		"source":   record.Source,
+		"duration": record.Duration,
+	})
+	if err != nil {
+		entry.WithError(err).Warn("工具调用Agent执行工具失败")
+	} else {
+		entry.Debug("工具调用Agent执行工具")
+	}
+	return record
+}
+
+// availableTools 收集ToolLoader工具和MCP工具目录中的工具，allowed非空时只保留其中列出的工具
+// 函数名只能包含字母、数字、下划线和连字符，工具名转换后重名时追加序号
+func (a *ToolAgent) availableTools(ctx context.Context, allowed []string) []*agentTool {
+	allow := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		allow[name] = true
+	}
+
+	var tools []*agentTool
+	functionNames := make(map[string]bool)
+	add := func(tool *agentTool) {
+		if len(allow) > 0 && !allow[tool.name] {
+			return
+		}
+		base := openai.FunctionName(tool.name)
+		name := base
+		// 先截断再追加序号，超长的工具名截断后重名时序号不会被截掉
+		for n := 2; functionNames[name]; n++ {
+			suffix := fmt.Sprintf("_%d", n)
+			name = base
+			if len(name)+len(suffix) > openai.MaxFunctionNameLength {
+				name = name[:openai.MaxFunctionNameLength-len(suffix)]
+			}
+			name += suffix
+		}
+		functionNames[name] = true
+		tool.definition = openai.NewFunctionTool(name, tool.definition.Function.Description, tool.definition.Function.Parameters)
+		tools = append(tools, tool)
+	}
+
+	if a.loader != nil {
+		serverTools := buildLoaderTools(a.loader)
+		sort.Slice(serverTools, func(i, j int) bool { return serverTools[i].Name < serverTools[j].Name })
+		for _, serverTool := range serverTools {
+			handler := serverTool.Handler
+			add(&agentTool{
+				name:       serverTool.Name,
+				source:     ToolSourceLocal,
+				definition: openai.NewFunctionTool(serverTool.Name, serverTool.Description, serverTool.InputSchema),
+				call: func(ctx context.Context, args map[string]interface{}) (string, error) {
+					output, err := handler(ctx, args)
+					if err != nil {
+						return "", err
+					}
+					return toolOutputText(output)
+				},
+			})
+		}
+	}
+
+	if manager := a.processor.mcpManager; manager != nil {
+		catalog, err := manager.ToolCatalog(ctx)
+		if err != nil {
+			a.logger.WithError(err).Warn("获取MCP工具目录失败，工具调用Agent只使用本地工具")
+		}
+		for _, catalogTool := range catalog {
+			server, toolName := catalogTool.Server, catalogTool.Tool
+			add(&agentTool{
+				name:       catalogTool.Name,
+				source:     ToolSourceMCP,
+				definition: openai.NewFunctionTool(catalogTool.Name, catalogTool.Description, catalogTool.InputSchema),
+				call: func(ctx context.Context, args map[string]interface{}) (string, error) {
+					response, err := manager.CallTool(ctx, server, toolName, args)
+					if err != nil {
+						return "", err
+					}
+					if response.Error != "" {
+						return "", errors.New(response.Error)
+					}
+					return mcp.ToolResultText(response.Output), nil
+				},
+			})
+		}
+	}
+
+	return tools
+}
+
+// toolOutputText 将本地工具的返回值转换为回填给模型的文本
+func toolOutputText(output interface{}) (string, error) {
+	if text, ok := output.(string); ok {
+		return text, nil
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return "", fmt.Errorf("序列化工具结果失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// addUsage 累加Token用量
+func addUsage(total *openai.Usage, usage openai.Usage) {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
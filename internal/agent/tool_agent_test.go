@@ -0,0 +1,254 @@
+package agent_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedLLM 模拟支持函数调用的OpenAI服务，按请求返回脚本生成的响应
+type scriptedLLM struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []openai.ChatRequest
+}
+
+// newScriptedLLM 创建模拟OpenAI服务，reply根据请求序号和请求内容生成assistant消息
+func newScriptedLLM(t *testing.T, reply func(index int, request openai.ChatRequest) map[string]interface{}) *scriptedLLM {
+	llm := &scriptedLLM{}
+	llm.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request openai.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		llm.mutex.Lock()
+		llm.requests = append(llm.requests, request)
+		index := len(llm.requests) - 1
+		llm.mutex.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"choices": []map[string]interface{}{{"index": 0, "message": reply(index, request)}},
+			"usage":   map[string]interface{}{"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15},
+		})
+	}))
+	t.Cleanup(llm.Close)
+	return llm
+}
+
+// recorded 获取收到的全部请求
+func (l *scriptedLLM) recorded() []openai.ChatRequest {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]openai.ChatRequest(nil), l.requests...)
+}
+
+// toolCall 构造assistant消息中的工具调用
+func toolCall(id, name, arguments string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       id,
+		"type":     "function",
+		"function": map[string]interface{}{"name": name, "arguments": arguments},
+	}
+}
+
+// newToolAgent 创建使用模拟OpenAI服务、本地知识库和模拟MCP服务器的工具调用Agent
+func newToolAgent(t *testing.T, llmURL, mcpURL string) *agent.ToolAgent {
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.Knowledge.Enabled = true
+	config.Knowledge.StoragePath = t.TempDir()
+	config.Knowledge.Embedder = "hash"
+	config.MCP.Servers = map[string]model.MCPServer{
+		"wiki": {Enabled: true, ServerURL: mcpURL},
+	}
+	config.MCP.HealthCheck.Interval = "0"
+
+	client := openai.NewClient("test-key", "gpt-4o")
+	client.SetBaseURL(llmURL)
+	processor := agent.NewProcessor(client, config)
+	t.Cleanup(processor.Stop)
+
+	_, err := processor.GetKnowledgeBase().AddDocument(model.Document{
+		Title:   "key-rate-limit 插件",
+		Content: "key-rate-limit 插件按照请求头或参数对请求进行限流，配置 limit_by_header 和 limit_keys。",
+	})
+	require.NoError(t, err)
+
+	loader := agent.NewToolLoader()
+	loader.RegisterTool("knowledge_base", processor.GetKnowledgeBase())
+	return agent.NewToolAgent(processor, loader)
+}
+
+// TestToolAgentRun 测试模型通过函数调用使用本地工具和MCP工具，观察结果后给出回答
+func TestToolAgentRun(t *testing.T) {
+	wiki := mcptest.NewServer(t, "wiki")
+	wiki.AddTool("ask_question", "ask_question tool", nil)
+	llm := newScriptedLLM(t, func(index int, request openai.ChatRequest) map[string]interface{} {
+		switch index {
+		case 0:
+			return map[string]interface{}{
+				"role":    "assistant",
+				"content": "先查询本地知识库和DeepWiki",
+				"tool_calls": []map[string]interface{}{
+					toolCall("call_1", "knowledge_base", `{"query":"key-rate-limit"}`),
+					toolCall("call_2", "wiki_ask_question", `{"question":"如何配置key-rate-limit"}`),
+				},
+			}
+		case 1:
+			return map[string]interface{}{
+				"role":       "assistant",
+				"tool_calls": []map[string]interface{}{toolCall("call_3", "unknown_tool", `{}`)},
+			}
+		default:
+			return map[string]interface{}{"role": "assistant", "content": "配置limit_by_header和limit_keys即可"}
+		}
+	})
+	toolAgent := newToolAgent(t, llm.URL, wiki.URL)
+
+	result, err := toolAgent.Run(context.Background(), &agent.AgentRunRequest{Query: "key-rate-limit插件怎么配置？"})
+	require.NoError(t, err)
+	assert.Equal(t, agent.AgentStopAnswered, result.StopReason)
+	assert.Equal(t, "配置limit_by_header和limit_keys即可", result.Answer)
+	assert.Equal(t, []string{"knowledge_base", "wiki.ask_question"}, result.Tools)
+	assert.Equal(t, 45, result.Usage.TotalTokens)
+	assert.Equal(t, 1, wiki.ToolCalls())
+	assert.Equal(t, 1, wiki.Calls("ask_question"))
+
+	// 完整的执行轨迹
+	require.Len(t, result.Steps, 3)
+	first := result.Steps[0]
+	assert.Equal(t, "先查询本地知识库和DeepWiki", first.Content)
+	require.Len(t, first.ToolCalls, 2)
+	assert.Equal(t, "knowledge_base", first.ToolCalls[0].Tool)
+	assert.Equal(t, agent.ToolSourceLocal, first.ToolCalls[0].Source)
+	assert.Contains(t, first.ToolCalls[0].Output, "limit_by_header")
+	assert.Equal(t, "wiki.ask_question", first.ToolCalls[1].Tool)
+	assert.Equal(t, "wiki_ask_question", first.ToolCalls[1].Function)
+	assert.Equal(t, agent.ToolSourceMCP, first.ToolCalls[1].Source)
+	assert.Equal(t, map[string]interface{}{"question": "如何配置key-rate-limit"}, first.ToolCalls[1].Arguments)
+	assert.Equal(t, "ask_question", first.ToolCalls[1].Output)
+	require.Len(t, result.Steps[1].ToolCalls, 1)
+	assert.Contains(t, result.Steps[1].ToolCalls[0].Error, "未知工具")
+	assert.Empty(t, result.Steps[2].ToolCalls)
+
+	// 模型收到全部工具的函数定义，工具结果按调用ID回填
+	requests := llm.recorded()
+	require.Len(t, requests, 3)
+	require.Len(t, requests[0].Tools, 2)
+	assert.Equal(t, "function", requests[0].Tools[0].Type)
+	assert.Equal(t, "knowledge_base", requests[0].Tools[0].Function.Name)
+	assert.Equal(t, "wiki_ask_question", requests[0].Tools[1].Function.Name)
+	assert.Equal(t, "ask_question tool", requests[0].Tools[1].Function.Description)
+
+	messages := requests[1].Messages
+	require.Len(t, messages, 5)
+	assert.Equal(t, "assistant", messages[2].Role)
+	require.Len(t, messages[2].ToolCalls, 2)
+	assert.Equal(t, "tool", messages[3].Role)
+	assert.Equal(t, "call_1", messages[3].ToolCallID)
+	assert.Contains(t, messages[3].Content, "limit_by_header")
+	assert.Equal(t, "call_2", messages[4].ToolCallID)
+	assert.Equal(t, "ask_question", messages[4].Content)
+
+	last := requests[2].Messages
+	assert.Equal(t, "call_3", last[len(last)-1].ToolCallID)
+	assert.Contains(t, last[len(last)-1].Content, "工具调用失败")
+}
+
+// TestToolAgentMaxSteps 测试达到步数上限后不再提供工具并要求模型直接回答，以及工具白名单
+func TestToolAgentMaxSteps(t *testing.T) {
+	wiki := mcptest.NewServer(t, "wiki")
+	wiki.AddTool("ask_question", "ask_question tool", nil)
+	llm := newScriptedLLM(t, func(index int, request openai.ChatRequest) map[string]interface{} {
+		if len(request.Tools) == 0 {
+			return map[string]interface{}{"role": "assistant", "content": "根据已有信息回答"}
+		}
+		return map[string]interface{}{
+			"role":       "assistant",
+			"tool_calls": []map[string]interface{}{toolCall("call", "knowledge_base", `{"query":"限流"}`)},
+		}
+	})
+	toolAgent := newToolAgent(t, llm.URL, wiki.URL)
+
+	result, err := toolAgent.Run(context.Background(), &agent.AgentRunRequest{
+		Query:    "如何限流？",
+		MaxSteps: 2,
+		Tools:    []string{"knowledge_base"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, agent.AgentStopMaxSteps, result.StopReason)
+	assert.Equal(t, "根据已有信息回答", result.Answer)
+	assert.Equal(t, []string{"knowledge_base"}, result.Tools)
+	require.Len(t, result.Steps, 3)
+	assert.Len(t, result.Steps[0].ToolCalls, 1)
+	assert.Len(t, result.Steps[1].ToolCalls, 1)
+	assert.Zero(t, wiki.ToolCalls())
+
+	requests := llm.recorded()
+	require.Len(t, requests, 3)
+	require.Len(t, requests[0].Tools, 1)
+	assert.Empty(t, requests[2].Tools)
+	final := requests[2].Messages[len(requests[2].Messages)-1]
+	assert.Equal(t, "user", final.Role)
+	assert.Contains(t, final.Content, "步数上限")
+
+	// 未配置OpenAI时不可用
+	config := &model.AgentConfig{}
+	config.Memory.CleanupInterval = time.Minute
+	config.MCP.HealthCheck.Interval = "0"
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+	unconfigured := agent.NewToolAgent(processor, nil)
+	_, err = unconfigured.Run(context.Background(), &agent.AgentRunRequest{Query: "如何限流？"})
+	assert.ErrorIs(t, err, agent.ErrToolAgentUnavailable)
+}
+
+// TestToolAgentLongToolNames 测试超长工具名截断后重名时生成不同的函数名
+func TestToolAgentLongToolNames(t *testing.T) {
+	wiki := mcptest.NewServer(t, "wiki")
+	prefix := strings.Repeat("search_repository_", 4)
+	wiki.AddTool(prefix+"issues", "", nil)
+	wiki.AddTool(prefix+"pulls", "", nil)
+	wiki.AddTool(prefix+"discussions", "", nil)
+	llm := newScriptedLLM(t, func(int, openai.ChatRequest) map[string]interface{} {
+		return map[string]interface{}{"role": "assistant", "content": "无需调用工具"}
+	})
+	toolAgent := newToolAgent(t, llm.URL, wiki.URL)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := toolAgent.Run(context.Background(), &agent.AgentRunRequest{Query: "查找仓库"})
+		assert.NoError(t, err)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("生成函数名没有结束")
+	}
+
+	requests := llm.recorded()
+	require.Len(t, requests, 1)
+	names := make(map[string]bool)
+	for _, tool := range requests[0].Tools {
+		name := tool.Function.Name
+		assert.LessOrEqual(t, len(name), openai.MaxFunctionNameLength)
+		assert.False(t, names[name], name)
+		names[name] = true
+	}
+	assert.Len(t, names, 4)
+}
@@ -65,6 +65,14 @@ func (tl *ToolLoader) LoadTools(config *model.Config) error {
 	return nil
 }
 
+// RegisterTool 注册工具，同名工具会被替换
+func (tl *ToolLoader) RegisterTool(name string, tool interface{}) {
+	tl.mutex.Lock()
+	defer tl.mutex.Unlock()
+
+	tl.tools[name] = tool
+}
+
 // GetTool 获取工具
 func (tl *ToolLoader) GetTool(name string) (interface{}, error) {
 	tl.mutex.RLock()
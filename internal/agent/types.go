@@ -33,19 +33,37 @@ type KnowledgeConfig = model.KnowledgeConfig
 type MemoryConfig = model.MemoryConfig
 type FusionConfig = model.FusionConfig
 type LoggingConfig = model.LoggingConfig
+type StreamEventType = model.StreamEventType
+type StreamEvent = model.StreamEvent
+type RetrievalStatus = model.RetrievalStatus
+type SourceStatus = model.SourceStatus
+type RetrieverConfig = model.RetrieverConfig
+type ToolAgentConfig = model.ToolAgentConfig
 
 // 重新导出常量
 const (
-	QuestionTypeIssue       = model.QuestionTypeIssue
-	QuestionTypePR          = model.QuestionTypePR
-	QuestionTypeText        = model.QuestionTypeText
-	QuestionTypeUnknown     = model.QuestionTypeUnknown
-	PriorityLow             = model.PriorityLow
-	PriorityMedium          = model.PriorityMedium
-	PriorityHigh            = model.PriorityHigh
-	PriorityUrgent          = model.PriorityUrgent
-	KnowledgeSourceLocal    = model.KnowledgeSourceLocal
-	KnowledgeSourceHigress  = model.KnowledgeSourceHigress
-	KnowledgeSourceDeepWiki = model.KnowledgeSourceDeepWiki
-	KnowledgeSourceGitHub   = model.KnowledgeSourceGitHub
+	QuestionTypeIssue        = model.QuestionTypeIssue
+	QuestionTypePR           = model.QuestionTypePR
+	QuestionTypeText         = model.QuestionTypeText
+	QuestionTypeUnknown      = model.QuestionTypeUnknown
+	PriorityLow              = model.PriorityLow
+	PriorityMedium           = model.PriorityMedium
+	PriorityHigh             = model.PriorityHigh
+	PriorityUrgent           = model.PriorityUrgent
+	KnowledgeSourceLocal     = model.KnowledgeSourceLocal
+	KnowledgeSourceHigress   = model.KnowledgeSourceHigress
+	KnowledgeSourceDeepWiki  = model.KnowledgeSourceDeepWiki
+	KnowledgeSourceGitHub    = model.KnowledgeSourceGitHub
+	StreamEventMemory        = model.StreamEventMemory
+	StreamEventQuestion      = model.StreamEventQuestion
+	StreamEventSource        = model.StreamEventSource
+	StreamEventFusion        = model.StreamEventFusion
+	StreamEventToken         = model.StreamEventToken
+	StreamEventDone          = model.StreamEventDone
+	StreamEventError         = model.StreamEventError
+	RetrievalStatusOK        = model.RetrievalStatusOK
+	RetrievalStatusTimeout   = model.RetrievalStatusTimeout
+	RetrievalStatusFallback  = model.RetrievalStatusFallback
+	RetrievalStatusError     = model.RetrievalStatusError
+	RetrievalStatusCancelled = model.RetrievalStatusCancelled
 )
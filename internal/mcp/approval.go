@@ -0,0 +1,348 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/google/uuid"
+)
+
+const (
+	// ApprovalAlways 每次调用都需要审批
+	ApprovalAlways = "always"
+	// ApprovalNever 调用无需审批
+	ApprovalNever = "never"
+
+	// DefaultApprovalTimeout 未配置approval_timeout时等待审批的时间
+	DefaultApprovalTimeout = 10 * time.Minute
+	// approvalRetention 已处理的审批记录保留时间
+	approvalRetention = time.Hour
+)
+
+// ApprovalStatus 审批状态
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"  // 等待审批
+	ApprovalApproved ApprovalStatus = "approved" // 已批准
+	ApprovalDenied   ApprovalStatus = "denied"   // 已拒绝
+	ApprovalExpired  ApprovalStatus = "expired"  // 超时未处理
+)
+
+var (
+	// ErrApprovalNotFound 审批不存在或已清理
+	ErrApprovalNotFound = errors.New("审批不存在")
+	// ErrApprovalDecided 审批已处理，不能重复处理
+	ErrApprovalDecided = errors.New("审批已处理")
+	// ErrApproverRequired 批准或拒绝时未提供审批人
+	ErrApproverRequired = errors.New("缺少审批人")
+	// ErrApprovalMismatch 审批对应的服务器、工具或参数与本次调用不一致
+	ErrApprovalMismatch = errors.New("审批与工具调用不匹配")
+	// ErrApprovalUsed 已批准的调用已经执行过，每次审批只能执行一次
+	ErrApprovalUsed = errors.New("审批已使用")
+)
+
+// ToolNotAllowedError 调用的工具不在服务器的allowed_tools中
+type ToolNotAllowedError struct {
+	Server string
+	Tool   string
+}
+
+func (e *ToolNotAllowedError) Error() string {
+	return fmt.Sprintf("MCP服务器 %s 不允许调用工具 %s", e.Server, e.Tool)
+}
+
+// ApprovalError 需要审批的工具调用未获批准（被拒绝或超时）
+type ApprovalError struct {
+	Approval *Approval
+}
+
+func (e *ApprovalError) Error() string {
+	if e.Approval.Status == ApprovalDenied {
+		message := fmt.Sprintf("工具调用 %s/%s 被拒绝", e.Approval.Server, e.Approval.Tool)
+		if e.Approval.Reason != "" {
+			message += ": " + e.Approval.Reason
+		}
+		return message
+	}
+	return fmt.Sprintf("工具调用 %s/%s 等待审批超时", e.Approval.Server, e.Approval.Tool)
+}
+
+// ApprovalPendingError 工具调用已提交审批，批准后携带审批ID重新调用
+type ApprovalPendingError struct {
+	Approval *Approval
+}
+
+func (e *ApprovalPendingError) Error() string {
+	return fmt.Sprintf("工具调用 %s/%s 等待审批: %s", e.Approval.Server, e.Approval.Tool, e.Approval.ID)
+}
+
+// approvalIDKey 不等待审批的上下文键，值为已批准的审批ID
+type approvalIDKey struct{}
+
+// WithAsyncApproval 返回不等待审批的上下文
+// approvalID为空时需要审批的调用提交审批后立即返回*ApprovalPendingError；
+// 非空时使用该审批执行调用，审批需已批准且与调用的服务器、工具和参数一致
+func WithAsyncApproval(ctx context.Context, approvalID string) context.Context {
+	return context.WithValue(ctx, approvalIDKey{}, approvalID)
+}
+
+// asyncApproval 获取上下文中的审批ID，ok表示不等待审批
+func asyncApproval(ctx context.Context) (approvalID string, ok bool) {
+	approvalID, ok = ctx.Value(approvalIDKey{}).(string)
+	return approvalID, ok
+}
+
+// Approval 等待审批的工具调用
+type Approval struct {
+	ID         string                 `json:"id"`
+	Server     string                 `json:"server"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments"`
+	Status     ApprovalStatus         `json:"status"`
+	CreatedAt  time.Time              `json:"created_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+	DecidedAt  *time.Time             `json:"decided_at,omitempty"`
+	DecidedBy  string                 `json:"decided_by,omitempty"`
+	Reason     string                 `json:"reason,omitempty"`
+	ExecutedAt *time.Time             `json:"executed_at,omitempty"`
+
+	done chan struct{}
+}
+
+// ApprovalQueue 工具调用审批队列
+// 需要审批的调用在队列中等待维护者批准或拒绝，超时未处理视为过期
+type ApprovalQueue struct {
+	timeout   time.Duration
+	approvals map[string]*Approval
+	mutex     sync.Mutex
+}
+
+// NewApprovalQueue 创建审批队列，timeout为等待审批的最长时间
+func NewApprovalQueue(timeout time.Duration) *ApprovalQueue {
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	return &ApprovalQueue{
+		timeout:   timeout,
+		approvals: make(map[string]*Approval),
+	}
+}
+
+// Submit 提交工具调用并等待审批，批准时返回nil
+// 拒绝或超时返回*ApprovalError，ctx取消时撤回审批并返回ctx的错误
+func (q *ApprovalQueue) Submit(ctx context.Context, server, tool string, arguments map[string]interface{}) error {
+	approval := q.add(server, tool, arguments)
+
+	timer := time.NewTimer(time.Until(approval.ExpiresAt))
+	defer timer.Stop()
+
+	select {
+	case <-approval.done:
+	case <-timer.C:
+		q.decide(approval.ID, ApprovalExpired, "", "")
+	case <-ctx.Done():
+		q.decide(approval.ID, ApprovalExpired, "", "调用方已取消")
+		return ctx.Err()
+	}
+
+	<-approval.done
+	result := q.snapshot(approval)
+	if result.Status == ApprovalApproved {
+		return nil
+	}
+	return &ApprovalError{Approval: result}
+}
+
+// Request 提交工具调用审批但不等待，返回待审批的调用
+func (q *ApprovalQueue) Request(server, tool string, arguments map[string]interface{}) *Approval {
+	return q.snapshot(q.add(server, tool, arguments))
+}
+
+// Use 使用已批准的审批执行工具调用，每次审批只能使用一次
+// 等待中返回*ApprovalPendingError，被拒绝或过期返回*ApprovalError
+func (q *ApprovalQueue) Use(id, server, tool string, arguments map[string]interface{}) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.cleanup()
+	approval, exists := q.approvals[id]
+	if !exists {
+		return ErrApprovalNotFound
+	}
+	if approval.Server != server || approval.Tool != tool || !sameArguments(approval.Arguments, arguments) {
+		return ErrApprovalMismatch
+	}
+
+	switch approval.Status {
+	case ApprovalPending:
+		return &ApprovalPendingError{Approval: q.copyLocked(approval)}
+	case ApprovalApproved:
+	default:
+		return &ApprovalError{Approval: q.copyLocked(approval)}
+	}
+	if approval.ExecutedAt != nil {
+		return ErrApprovalUsed
+	}
+
+	now := time.Now()
+	approval.ExecutedAt = &now
+	return nil
+}
+
+// List 列出审批，status为空时返回全部，按创建时间排序
+func (q *ApprovalQueue) List(status ApprovalStatus) []*Approval {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.cleanup()
+	approvals := make([]*Approval, 0, len(q.approvals))
+	for _, approval := range q.approvals {
+		if status == "" || approval.Status == status {
+			approvals = append(approvals, q.copyLocked(approval))
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].CreatedAt.Before(approvals[j].CreatedAt)
+	})
+	return approvals
+}
+
+// Get 获取审批
+func (q *ApprovalQueue) Get(id string) (*Approval, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.cleanup()
+	approval, exists := q.approvals[id]
+	if !exists {
+		return nil, false
+	}
+	return q.copyLocked(approval), true
+}
+
+// Approve 批准工具调用
+func (q *ApprovalQueue) Approve(id, approver string) (*Approval, error) {
+	return q.decide(id, ApprovalApproved, approver, "")
+}
+
+// Deny 拒绝工具调用
+func (q *ApprovalQueue) Deny(id, approver, reason string) (*Approval, error) {
+	return q.decide(id, ApprovalDenied, approver, reason)
+}
+
+// add 添加待审批的调用
+func (q *ApprovalQueue) add(server, tool string, arguments map[string]interface{}) *Approval {
+	now := time.Now()
+	approval := &Approval{
+		ID:        uuid.New().String(),
+		Server:    server,
+		Tool:      tool,
+		Arguments: arguments,
+		Status:    ApprovalPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(q.timeout),
+		done:      make(chan struct{}),
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.cleanup()
+	q.approvals[approval.ID] = approval
+	return approval
+}
+
+// decide 处理审批，只有等待中的审批可以处理，批准和拒绝需要审批人
+func (q *ApprovalQueue) decide(id string, status ApprovalStatus, approver, reason string) (*Approval, error) {
+	if status != ApprovalExpired && approver == "" {
+		return nil, ErrApproverRequired
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.cleanup()
+	approval, exists := q.approvals[id]
+	if !exists {
+		return nil, ErrApprovalNotFound
+	}
+	if approval.Status != ApprovalPending {
+		return q.copyLocked(approval), ErrApprovalDecided
+	}
+
+	q.decideLocked(approval, status, approver, reason)
+	return q.copyLocked(approval), nil
+}
+
+// decideLocked 更新审批状态并唤醒等待的调用，调用方需持有锁
+func (q *ApprovalQueue) decideLocked(approval *Approval, status ApprovalStatus, approver, reason string) {
+	now := time.Now()
+	approval.Status = status
+	approval.DecidedAt = &now
+	approval.DecidedBy = approver
+	approval.Reason = reason
+	close(approval.done)
+}
+
+// snapshot 获取审批的副本
+func (q *ApprovalQueue) snapshot(approval *Approval) *Approval {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.copyLocked(approval)
+}
+
+// copyLocked 复制审批，调用方需持有锁
+func (q *ApprovalQueue) copyLocked(approval *Approval) *Approval {
+	copied := *approval
+	copied.done = nil
+	return &copied
+}
+
+// cleanup 将超时未处理的审批标记为过期，并清理处理完成超过保留时间的审批，调用方需持有锁
+func (q *ApprovalQueue) cleanup() {
+	now := time.Now()
+	for id, approval := range q.approvals {
+		if approval.Status == ApprovalPending && now.After(approval.ExpiresAt) {
+			q.decideLocked(approval, ApprovalExpired, "", "")
+		}
+		if approval.DecidedAt != nil && now.Sub(*approval.DecidedAt) > approvalRetention {
+			delete(q.approvals, id)
+		}
+	}
+}
+
+// sameArguments 判断两次调用的参数是否一致，按JSON编码比较（键有序，nil与空参数视为一致）
+func sameArguments(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
+
+// ToolAllowed 判断服务器配置是否允许调用工具，未配置allowed_tools时允许全部工具
+func ToolAllowed(config *model.MCPServer, tool string) bool {
+	if len(config.AllowedTools) == 0 {
+		return true
+	}
+	for _, allowed := range config.AllowedTools {
+		if allowed == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresApproval 判断服务器的工具调用是否需要审批
+// 未配置或配置为never时无需审批，其他取值按always处理
+func RequiresApproval(config *model.MCPServer) bool {
+	return config.RequireApproval != "" && config.RequireApproval != ApprovalNever
+}
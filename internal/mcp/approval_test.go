@@ -0,0 +1,151 @@
+package mcp_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitPendingApproval 等待审批队列中出现待审批的调用
+func waitPendingApproval(t *testing.T, queue *mcp.ApprovalQueue) *mcp.Approval {
+	var pending []*mcp.Approval
+	require.Eventually(t, func() bool {
+		pending = queue.List(mcp.ApprovalPending)
+		return len(pending) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	return pending[0]
+}
+
+// TestManagerToolPolicy 测试Manager拒绝调用allowed_tools之外的工具
+func TestManagerToolPolicy(t *testing.T) {
+	server := mcptest.NewServer(t, "deepwiki")
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"deepwiki": {Enabled: true, ServerURL: server.URL, AllowedTools: []string{"ask_question"}},
+		},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+
+	_, err := manager.CallTool(context.Background(), "deepwiki", "read_wiki_structure", map[string]interface{}{"repoName": "alibaba/higress"})
+	var notAllowed *mcp.ToolNotAllowedError
+	require.True(t, errors.As(err, &notAllowed))
+	assert.Equal(t, "read_wiki_structure", notAllowed.Tool)
+	assert.Zero(t, server.Calls("read_wiki_structure"))
+
+	_, err = manager.CallTool(context.Background(), "deepwiki", "ask_question", map[string]interface{}{"repoName": "alibaba/higress", "question": "key-rate-limit"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.Calls("ask_question"))
+}
+
+// TestManagerRequireApproval 测试需要审批的工具调用在批准后才执行
+func TestManagerRequireApproval(t *testing.T) {
+	server := mcptest.NewServer(t, "deepwiki")
+	manager := mcp.NewManager(&model.MCPConfig{
+		ApprovalTimeout: "5s",
+		Servers: map[string]model.MCPServer{
+			"deepwiki": {Enabled: true, ServerURL: server.URL, RequireApproval: mcp.ApprovalAlways},
+		},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+	arguments := map[string]interface{}{"repoName": "alibaba/higress", "question": "key-rate-limit"}
+
+	t.Run("Approve", func(t *testing.T) {
+		result := make(chan error, 1)
+		go func() {
+			_, err := manager.CallTool(context.Background(), "deepwiki", "ask_question", arguments)
+			result <- err
+		}()
+
+		approval := waitPendingApproval(t, manager.Approvals())
+		assert.Equal(t, "deepwiki", approval.Server)
+		assert.Equal(t, "ask_question", approval.Tool)
+		assert.Zero(t, server.Calls("ask_question"))
+
+		decided, err := manager.Approvals().Approve(approval.ID, "maintainer")
+		require.NoError(t, err)
+		assert.Equal(t, mcp.ApprovalApproved, decided.Status)
+		require.NoError(t, <-result)
+		assert.Equal(t, 1, server.Calls("ask_question"))
+
+		// 已处理的审批不能重复处理
+		_, err = manager.Approvals().Deny(approval.ID, "maintainer", "")
+		assert.ErrorIs(t, err, mcp.ErrApprovalDecided)
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		result := make(chan error, 1)
+		go func() {
+			_, err := manager.CallTool(context.Background(), "deepwiki", "ask_question", arguments)
+			result <- err
+		}()
+
+		approval := waitPendingApproval(t, manager.Approvals())
+		_, err := manager.Approvals().Deny(approval.ID, "maintainer", "写操作需要先讨论")
+		require.NoError(t, err)
+
+		var approvalErr *mcp.ApprovalError
+		require.True(t, errors.As(<-result, &approvalErr))
+		assert.Equal(t, mcp.ApprovalDenied, approvalErr.Approval.Status)
+		assert.Equal(t, "写操作需要先讨论", approvalErr.Approval.Reason)
+		assert.Equal(t, 1, server.Calls("ask_question"))
+	})
+}
+
+// TestApprovalQueueTimeout 测试超时未处理的审批过期
+func TestApprovalQueueTimeout(t *testing.T) {
+	queue := mcp.NewApprovalQueue(50 * time.Millisecond)
+
+	err := queue.Submit(context.Background(), "stripe", "create_refund", nil)
+	var approvalErr *mcp.ApprovalError
+	require.True(t, errors.As(err, &approvalErr))
+	assert.Equal(t, mcp.ApprovalExpired, approvalErr.Approval.Status)
+
+	expired := queue.List(mcp.ApprovalExpired)
+	require.Len(t, expired, 1)
+	_, err = queue.Approve(expired[0].ID, "maintainer")
+	assert.ErrorIs(t, err, mcp.ErrApprovalDecided)
+
+	_, err = queue.Approve("missing", "maintainer")
+	assert.ErrorIs(t, err, mcp.ErrApprovalNotFound)
+}
+
+// TestApprovalQueueAsync 测试不等待的审批：提交后立即返回，批准后使用一次，未处理的审批按时过期
+func TestApprovalQueueAsync(t *testing.T) {
+	queue := mcp.NewApprovalQueue(100 * time.Millisecond)
+	arguments := map[string]interface{}{"payment_intent": "pi_123"}
+
+	approval := queue.Request("stripe", "create_refund", arguments)
+	assert.Equal(t, mcp.ApprovalPending, approval.Status)
+
+	var pendingErr *mcp.ApprovalPendingError
+	require.True(t, errors.As(queue.Use(approval.ID, "stripe", "create_refund", arguments), &pendingErr))
+
+	// 批准和拒绝需要审批人
+	_, err := queue.Approve(approval.ID, "")
+	assert.ErrorIs(t, err, mcp.ErrApproverRequired)
+	_, err = queue.Approve(approval.ID, "alice")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, queue.Use(approval.ID, "stripe", "create_refund", map[string]interface{}{"payment_intent": "pi_999"}), mcp.ErrApprovalMismatch)
+	assert.ErrorIs(t, queue.Use(approval.ID, "stripe", "delete_customer", arguments), mcp.ErrApprovalMismatch)
+	require.NoError(t, queue.Use(approval.ID, "stripe", "create_refund", arguments))
+	assert.ErrorIs(t, queue.Use(approval.ID, "stripe", "create_refund", arguments), mcp.ErrApprovalUsed)
+
+	// 没有调用方等待的审批同样会过期
+	unattended := queue.Request("stripe", "create_refund", nil)
+	time.Sleep(150 * time.Millisecond)
+	expired, exists := queue.Get(unattended.ID)
+	require.True(t, exists)
+	assert.Equal(t, mcp.ApprovalExpired, expired.Status)
+	_, err = queue.Approve(unattended.ID, "alice")
+	assert.ErrorIs(t, err, mcp.ErrApprovalDecided)
+	var approvalErr *mcp.ApprovalError
+	assert.True(t, errors.As(queue.Use(unattended.ID, "stripe", "create_refund", nil), &approvalErr))
+}
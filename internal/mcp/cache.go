@@ -0,0 +1,241 @@
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultCacheMaxEntries 未配置cache_max_entries时响应缓存的最大条目数
+	DefaultCacheMaxEntries = 1000
+	// CacheBypassHeader 请求带有该请求头时跳过响应缓存，直接请求服务器并用结果更新缓存
+	CacheBypassHeader = "X-MCP-Cache-Bypass"
+	// cacheRefreshTimeout 后台刷新过期响应的超时时间
+	cacheRefreshTimeout = 30 * time.Second
+)
+
+// CacheEntry 缓存条目
+// ExpiresAt之前直接返回；ExpiresAt到StaleUntil之间返回旧响应并在后台刷新；StaleUntil之后视为未命中
+type CacheEntry struct {
+	Data       interface{}
+	ExpiresAt  time.Time
+	StaleUntil time.Time
+
+	key        string
+	element    *list.Element
+	refreshing bool
+}
+
+// cachePolicy 服务器的响应缓存策略
+type cachePolicy struct {
+	ttl      time.Duration
+	staleTTL time.Duration
+	tools    map[string]bool
+}
+
+// cacheBypassKey 跳过响应缓存的上下文键
+type cacheBypassKey struct{}
+
+// WithCacheBypass 返回跳过响应缓存的上下文
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheBypassed 判断上下文是否要求跳过响应缓存
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// newCachePolicy 解析服务器的缓存配置，未启用缓存或需要审批的服务器返回nil
+// 需要审批的服务器不缓存，避免后台刷新绕过审批
+func newCachePolicy(serverLabel string, config *model.MCPServer, logger *logrus.Logger) *cachePolicy {
+	if config.Cache.TTL == "" || len(config.Cache.Tools) == 0 || RequiresApproval(config) {
+		return nil
+	}
+
+	ttl, err := time.ParseDuration(config.Cache.TTL)
+	if err != nil {
+		logger.WithError(err).WithField("server", serverLabel).Warn("响应缓存时间配置无效，不启用缓存")
+		return nil
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	staleTTL := ttl
+	if config.Cache.StaleTTL != "" {
+		if value, err := time.ParseDuration(config.Cache.StaleTTL); err == nil && value >= 0 {
+			staleTTL = value
+		} else {
+			logger.WithField("server", serverLabel).Warn("过期响应保留时间配置无效，使用缓存时间")
+		}
+	}
+
+	tools := make(map[string]bool, len(config.Cache.Tools))
+	for _, tool := range config.Cache.Tools {
+		tools[tool] = true
+	}
+	return &cachePolicy{ttl: ttl, staleTTL: staleTTL, tools: tools}
+}
+
+// cacheKey 生成缓存键，参数按键排序序列化，相同参数不同顺序得到相同的键
+func cacheKey(serverLabel, toolName string, arguments map[string]interface{}) (string, error) {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	canonical, err := json.Marshal(arguments)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(serverLabel))
+	hash.Write([]byte{0})
+	hash.Write([]byte(toolName))
+	hash.Write([]byte{0})
+	hash.Write(canonical)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// cachedCall 执行工具调用，可缓存的工具优先使用缓存
+// 命中未过期响应直接返回；命中过期但仍在stale_ttl内的响应时返回旧响应并在后台刷新；
+// 只缓存成功的响应，刷新失败时保留旧响应
+func (m *Manager) cachedCall(ctx context.Context, serverLabel, toolName string, arguments map[string]interface{}, call func(context.Context) (*CallToolResponse, error)) (*CallToolResponse, error) {
+	policy := m.cachePolicies[serverLabel]
+	if policy == nil || !policy.tools[toolName] {
+		return call(ctx)
+	}
+	key, err := cacheKey(serverLabel, toolName, arguments)
+	if err != nil {
+		return call(ctx)
+	}
+
+	entry := logrus.Fields{"server": serverLabel, "tool": toolName}
+	if !cacheBypassed(ctx) {
+		if response, refresh, hit := m.cacheGet(key); hit {
+			if refresh {
+				m.logger.WithFields(entry).Debug("MCP响应缓存已过期，返回旧响应并在后台刷新")
+				go m.refreshCache(key, policy, entry, call)
+			} else {
+				m.logger.WithFields(entry).Debug("MCP响应缓存命中")
+			}
+			return response, nil
+		}
+	}
+
+	response, err := call(ctx)
+	if err == nil && response.Error == "" {
+		m.cachePut(key, response, policy)
+	}
+	return response, err
+}
+
+// refreshCache 在后台重新调用工具并更新缓存
+func (m *Manager) refreshCache(key string, policy *cachePolicy, fields logrus.Fields, call func(context.Context) (*CallToolResponse, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheRefreshTimeout)
+	defer cancel()
+
+	response, err := call(ctx)
+	if err == nil && response.Error == "" {
+		m.cachePut(key, response, policy)
+		return
+	}
+
+	m.cacheMutex.Lock()
+	if cached, exists := m.cache[key]; exists {
+		cached.refreshing = false
+	}
+	m.cacheMutex.Unlock()
+
+	if err != nil {
+		m.logger.WithError(err).WithFields(fields).Warn("刷新MCP响应缓存失败，继续使用旧响应")
+	} else {
+		m.logger.WithField("error", response.Error).WithFields(fields).Warn("刷新MCP响应缓存失败，继续使用旧响应")
+	}
+}
+
+// cacheGet 读取缓存，返回响应副本；refresh表示响应已过期且需要由调用方发起后台刷新
+func (m *Manager) cacheGet(key string) (response *CallToolResponse, refresh bool, hit bool) {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+
+	cached, exists := m.cache[key]
+	if !exists {
+		return nil, false, false
+	}
+
+	now := time.Now()
+	if now.After(cached.StaleUntil) {
+		m.cacheRemove(cached)
+		return nil, false, false
+	}
+
+	m.cacheOrder.MoveToFront(cached.element)
+	if now.After(cached.ExpiresAt) && !cached.refreshing {
+		cached.refreshing = true
+		refresh = true
+	}
+
+	data := *cached.Data.(*CallToolResponse)
+	return &data, refresh, true
+}
+
+// cachePut 写入缓存，超过容量时淘汰最久未使用的条目
+func (m *Manager) cachePut(key string, response *CallToolResponse, policy *cachePolicy) {
+	data := *response
+	now := time.Now()
+
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+
+	if cached, exists := m.cache[key]; exists {
+		cached.Data = &data
+		cached.ExpiresAt = now.Add(policy.ttl)
+		cached.StaleUntil = cached.ExpiresAt.Add(policy.staleTTL)
+		cached.refreshing = false
+		m.cacheOrder.MoveToFront(cached.element)
+		return
+	}
+
+	cached := &CacheEntry{
+		Data:       &data,
+		ExpiresAt:  now.Add(policy.ttl),
+		StaleUntil: now.Add(policy.ttl + policy.staleTTL),
+		key:        key,
+	}
+	cached.element = m.cacheOrder.PushFront(cached)
+	m.cache[key] = cached
+
+	for m.cacheOrder.Len() > m.cacheMaxEntries {
+		m.cacheRemove(m.cacheOrder.Back().Value.(*CacheEntry))
+	}
+}
+
+// cacheRemove 删除缓存条目，调用方需持有cacheMutex
+func (m *Manager) cacheRemove(cached *CacheEntry) {
+	m.cacheOrder.Remove(cached.element)
+	delete(m.cache, cached.key)
+}
+
+// CacheSize 获取响应缓存的条目数
+func (m *Manager) CacheSize() int {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	return len(m.cache)
+}
+
+// ClearCache 清空响应缓存
+func (m *Manager) ClearCache() {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+	m.cache = make(map[string]*CacheEntry)
+	m.cacheOrder.Init()
+}
@@ -0,0 +1,154 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingServer 创建模拟MCP服务器，工具返回该工具和参数组合被调用的次数
+// 名为broken的工具返回isError结果
+func newCountingServer(t *testing.T) *mcptest.Server {
+	server := mcptest.NewServer(t, "counting")
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	for _, name := range []string{"ask_question", "lookup", "search", "broken"} {
+		name := name
+		server.AddTool(name, "", func(_ *mcptest.Request, arguments map[string]interface{}) (string, error) {
+			key, _ := json.Marshal(arguments)
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls[name+string(key)]++
+			text := fmt.Sprintf("%s#%d", name, calls[name+string(key)])
+			if name == "broken" {
+				return "", errors.New(text)
+			}
+			return text, nil
+		})
+	}
+	return server
+}
+
+// newCachingManager 创建缓存ask_question、lookup和broken工具的MCP管理器
+func newCachingManager(t *testing.T, server *mcptest.Server, ttl, staleTTL string, maxEntries int) *mcp.Manager {
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"wiki": {
+				Enabled:   true,
+				ServerURL: server.URL,
+				Cache:     model.MCPCacheConfig{TTL: ttl, StaleTTL: staleTTL, Tools: []string{"ask_question", "lookup", "broken"}},
+			},
+		},
+		HealthCheck:     model.MCPHealthConfig{Interval: "0"},
+		CacheMaxEntries: maxEntries,
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+	return manager
+}
+
+// callText 调用工具并返回文本结果
+func callText(t *testing.T, ctx context.Context, manager *mcp.Manager, tool string, arguments map[string]interface{}) string {
+	response, err := manager.CallTool(ctx, "wiki", tool, arguments)
+	require.NoError(t, err)
+	return mcp.ToolResultText(response.Output)
+}
+
+// TestManagerResponseCache 测试按服务器、工具和参数缓存响应以及跳过缓存
+func TestManagerResponseCache(t *testing.T) {
+	server := newCountingServer(t)
+	manager := newCachingManager(t, server, "1m", "", 0)
+	ctx := context.Background()
+
+	// 参数顺序不同视为相同请求
+	assert.Equal(t, "lookup#1", callText(t, ctx, manager, "lookup", map[string]interface{}{"a": 1, "b": map[string]interface{}{"x": "1", "y": "2"}}))
+	assert.Equal(t, "lookup#1", callText(t, ctx, manager, "lookup", map[string]interface{}{"b": map[string]interface{}{"y": "2", "x": "1"}, "a": 1}))
+	assert.Equal(t, 1, server.ToolCalls())
+
+	// 参数不同或工具不可缓存时请求服务器
+	assert.Equal(t, "lookup#1", callText(t, ctx, manager, "lookup", map[string]interface{}{"a": 2}))
+	assert.Equal(t, "search#1", callText(t, ctx, manager, "search", nil))
+	assert.Equal(t, "search#2", callText(t, ctx, manager, "search", nil))
+	assert.Equal(t, 4, server.ToolCalls())
+
+	// 查询使用ask_question的缓存
+	for i := 0; i < 3; i++ {
+		response, err := manager.Query(ctx, "wiki", "how to configure key-rate-limit", "alibaba/higress")
+		require.NoError(t, err)
+		assert.Equal(t, "ask_question#1", mcp.ToolResultText(response.Output))
+	}
+	assert.Equal(t, 5, server.ToolCalls())
+
+	// 失败的响应不缓存
+	response, err := manager.CallTool(ctx, "wiki", "broken", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Error)
+	_, err = manager.CallTool(ctx, "wiki", "broken", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7, server.ToolCalls())
+
+	// 跳过缓存时请求服务器并更新缓存
+	bypass := mcp.WithCacheBypass(ctx)
+	assert.Equal(t, "lookup#2", callText(t, bypass, manager, "lookup", map[string]interface{}{"a": 2}))
+	assert.Equal(t, "lookup#2", callText(t, ctx, manager, "lookup", map[string]interface{}{"a": 2}))
+	assert.Equal(t, 8, server.ToolCalls())
+
+	assert.Equal(t, 3, manager.CacheSize())
+	manager.ClearCache()
+	assert.Equal(t, 0, manager.CacheSize())
+	assert.Equal(t, "lookup#3", callText(t, ctx, manager, "lookup", map[string]interface{}{"a": 2}))
+}
+
+// TestManagerResponseCacheStaleWhileRevalidate 测试缓存过期后返回旧响应并在后台刷新
+func TestManagerResponseCacheStaleWhileRevalidate(t *testing.T) {
+	server := newCountingServer(t)
+	manager := newCachingManager(t, server, "50ms", "100ms", 0)
+	ctx := context.Background()
+	arguments := map[string]interface{}{"page": "rate-limit"}
+
+	assert.Equal(t, "lookup#1", callText(t, ctx, manager, "lookup", arguments))
+
+	// 过期后仍返回旧响应，后台刷新
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, "lookup#1", callText(t, ctx, manager, "lookup", arguments))
+	require.Eventually(t, func() bool {
+		return server.ToolCalls() == 2
+	}, time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return callText(t, ctx, manager, "lookup", arguments) == "lookup#2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, server.ToolCalls())
+
+	// 超过stale_ttl后视为未命中
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, "lookup#3", callText(t, ctx, manager, "lookup", arguments))
+	assert.Equal(t, 3, server.ToolCalls())
+}
+
+// TestManagerResponseCacheEviction 测试缓存超过容量时淘汰最久未使用的条目
+func TestManagerResponseCacheEviction(t *testing.T) {
+	server := newCountingServer(t)
+	manager := newCachingManager(t, server, "1m", "", 2)
+	ctx := context.Background()
+
+	callText(t, ctx, manager, "lookup", map[string]interface{}{"page": "a"})
+	callText(t, ctx, manager, "lookup", map[string]interface{}{"page": "b"})
+	callText(t, ctx, manager, "lookup", map[string]interface{}{"page": "a"})
+	callText(t, ctx, manager, "lookup", map[string]interface{}{"page": "c"})
+	assert.Equal(t, 2, manager.CacheSize())
+	assert.Equal(t, 3, server.ToolCalls())
+
+	// a最近使用过仍在缓存中，b已被淘汰
+	assert.Equal(t, "lookup#1", callText(t, ctx, manager, "lookup", map[string]interface{}{"page": "a"}))
+	assert.Equal(t, "lookup#2", callText(t, ctx, manager, "lookup", map[string]interface{}{"page": "b"}))
+	assert.Equal(t, 4, server.ToolCalls())
+}
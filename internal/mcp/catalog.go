@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ToolNameSeparator 工具目录中服务器标签与工具名之间的分隔符
+const ToolNameSeparator = "."
+
+// catalogTTL 工具缓存的有效期，用于不提供GET SSE流、无法收到工具列表变化通知的服务器
+const catalogTTL = 10 * time.Minute
+
+// CatalogTool 工具目录中的工具，名称带服务器前缀
+type CatalogTool struct {
+	Name         string                 `json:"name"`   // 带服务器前缀的名称，如deepwiki.ask_question
+	Server       string                 `json:"server"` // 服务器标签
+	Tool         string                 `json:"tool"`   // 服务器上的工具名
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"input_schema"`
+	OutputSchema map[string]interface{} `json:"output_schema,omitempty"`
+}
+
+// NamespacedToolName 生成带服务器前缀的工具名
+func NamespacedToolName(server, tool string) string {
+	return server + ToolNameSeparator + tool
+}
+
+// SplitToolName 拆分带服务器前缀的工具名，按第一个分隔符拆分，工具名本身可以包含分隔符
+func SplitToolName(name string) (server, tool string, ok bool) {
+	server, tool, ok = strings.Cut(name, ToolNameSeparator)
+	if !ok || server == "" || tool == "" {
+		return "", "", false
+	}
+	return server, tool, true
+}
+
+// catalogEntry 单个服务器的工具缓存
+type catalogEntry struct {
+	tools     []CatalogTool
+	fetchedAt time.Time
+}
+
+// toolCatalog 全部服务器的工具目录
+// 按服务器缓存工具列表，服务器发送notifications/tools/list_changed或超过catalogTTL时失效，下次读取时重新获取；
+// 获取失败的服务器不缓存，下次读取时重试
+type toolCatalog struct {
+	entries     map[string]*catalogEntry
+	generations map[string]uint64
+	mutex       sync.Mutex
+}
+
+// newToolCatalog 创建工具目录
+func newToolCatalog() *toolCatalog {
+	return &toolCatalog{
+		entries:     make(map[string]*catalogEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+// get 获取服务器的缓存工具及当前版本
+func (c *toolCatalog) get(server string) (*catalogEntry, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.entries[server], c.generations[server]
+}
+
+// store 缓存服务器工具，获取期间缓存已失效时丢弃结果
+func (c *toolCatalog) store(server string, generation uint64, tools []CatalogTool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.generations[server] != generation {
+		return
+	}
+	c.entries[server] = &catalogEntry{tools: tools, fetchedAt: time.Now()}
+}
+
+// invalidate 使服务器的缓存失效
+func (c *toolCatalog) invalidate(server string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, server)
+	c.generations[server]++
+}
+
+// ToolCatalog 获取全部已启用服务器的工具目录，工具名带服务器前缀，按名称排序
+// 单个服务器获取失败时记录日志并跳过，全部服务器都失败时返回错误
+func (m *Manager) ToolCatalog(ctx context.Context) ([]CatalogTool, error) {
+	m.mutex.RLock()
+	servers := make([]string, 0, len(m.clients))
+	for serverLabel := range m.clients {
+		servers = append(servers, serverLabel)
+	}
+	m.mutex.RUnlock()
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		catalog  []CatalogTool
+		failures []string
+	)
+	for _, serverLabel := range servers {
+		wg.Add(1)
+		go func(serverLabel string) {
+			defer wg.Done()
+			tools, err := m.serverCatalog(ctx, serverLabel)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				m.logger.WithError(err).WithField("server", serverLabel).Warn("获取MCP服务器工具失败，工具目录中跳过该服务器")
+				failures = append(failures, fmt.Sprintf("%s: %v", serverLabel, err))
+				return
+			}
+			catalog = append(catalog, tools...)
+		}(serverLabel)
+	}
+	wg.Wait()
+
+	if len(servers) > 0 && len(failures) == len(servers) {
+		return nil, fmt.Errorf("获取工具目录失败: %s", strings.Join(failures, "; "))
+	}
+
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Name < catalog[j].Name
+	})
+	if catalog == nil {
+		catalog = []CatalogTool{}
+	}
+	return catalog, nil
+}
+
+// RefreshToolCatalog 使全部服务器的工具缓存失效并重新获取
+func (m *Manager) RefreshToolCatalog(ctx context.Context) ([]CatalogTool, error) {
+	m.mutex.RLock()
+	for serverLabel := range m.clients {
+		m.catalog.invalidate(serverLabel)
+	}
+	m.mutex.RUnlock()
+	return m.ToolCatalog(ctx)
+}
+
+// serverCatalog 获取单个服务器的工具，优先使用缓存
+func (m *Manager) serverCatalog(ctx context.Context, serverLabel string) ([]CatalogTool, error) {
+	entry, generation := m.catalog.get(serverLabel)
+	if entry != nil && time.Since(entry.fetchedAt) < catalogTTL {
+		return entry.tools, nil
+	}
+
+	response, err := m.ListTools(ctx, serverLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := make([]CatalogTool, 0, len(response.Tools))
+	for _, tool := range response.Tools {
+		tools = append(tools, CatalogTool{
+			Name:         NamespacedToolName(serverLabel, tool.Name),
+			Server:       serverLabel,
+			Tool:         tool.Name,
+			Description:  tool.Description,
+			InputSchema:  tool.InputSchema,
+			OutputSchema: tool.OutputSchema,
+		})
+	}
+	m.catalog.store(serverLabel, generation, tools)
+
+	m.logger.WithFields(logrus.Fields{
+		"server": serverLabel,
+		"tools":  len(tools),
+	}).Debug("MCP服务器工具已缓存")
+	return tools, nil
+}
+
+// handleServerNotification 处理服务器通知，工具列表变化时使工具目录缓存失效，资源变化时通知资源回调
+func (m *Manager) handleServerNotification(serverLabel, method string, params json.RawMessage) {
+	switch method {
+	case "notifications/tools/list_changed":
+		m.catalog.invalidate(serverLabel)
+		m.logger.WithField("server", serverLabel).Info("MCP服务器工具列表已变化，工具目录将重新获取")
+	case "notifications/resources/updated", "notifications/resources/list_changed":
+		m.logger.WithFields(logrus.Fields{
+			"server": serverLabel,
+			"method": method,
+		}).Debug("MCP服务器资源已变化")
+		m.notifyResourceListeners(serverLabel, params)
+	default:
+		m.logger.WithFields(logrus.Fields{
+			"server": serverLabel,
+			"method": method,
+		}).Debug("忽略MCP服务器通知")
+	}
+}
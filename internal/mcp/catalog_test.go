@@ -0,0 +1,134 @@
+package mcp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCatalogServer 创建提供指定工具的模拟MCP服务器
+// 调用add_tool时新增工具并在响应前发送notifications/tools/list_changed
+func newCatalogServer(t *testing.T, tools ...string) *mcptest.Server {
+	server := mcptest.NewServer(t, "catalog")
+	for _, name := range tools {
+		var handler mcptest.ToolFunc
+		if name == "add_tool" {
+			handler = func(request *mcptest.Request, _ map[string]interface{}) (string, error) {
+				server.AddTool("new_tool", "new_tool tool", nil)
+				request.Notify("notifications/tools/list_changed", nil)
+				return "added", nil
+			}
+		}
+		server.AddTool(name, name+" tool", handler)
+	}
+	return server
+}
+
+// TestManagerToolCatalog 测试聚合全部服务器的工具目录、按带前缀的工具名路由以及工具列表变化后刷新
+func TestManagerToolCatalog(t *testing.T) {
+	wiki := newCatalogServer(t, "ask_question", "read_wiki_structure")
+	github := newCatalogServer(t, "search_issues", "add_tool", "delete_repo")
+
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"deepwiki": {Enabled: true, ServerURL: wiki.URL},
+			"github":   {Enabled: true, ServerURL: github.URL, AllowedTools: []string{"search_issues", "add_tool", "new_tool"}},
+			"disabled": {Enabled: false, ServerURL: "http://127.0.0.1:1"},
+		},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+
+	catalog, err := manager.ToolCatalog(context.Background())
+	require.NoError(t, err)
+	names := make([]string, 0, len(catalog))
+	for _, tool := range catalog {
+		names = append(names, tool.Name)
+	}
+	// 未允许的工具不在目录中
+	assert.Equal(t, []string{"deepwiki.ask_question", "deepwiki.read_wiki_structure", "github.add_tool", "github.search_issues"}, names)
+	assert.Equal(t, "github", catalog[3].Server)
+	assert.Equal(t, "search_issues", catalog[3].Tool)
+
+	// 再次获取使用缓存
+	_, err = manager.ToolCatalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, wiki.Calls("tools/list"))
+	assert.Equal(t, 1, github.Calls("tools/list"))
+
+	// 按带前缀的工具名路由
+	response, err := manager.CallTool(context.Background(), "", "github.search_issues", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "search_issues", mcp.ToolResultText(response.Output))
+	_, err = manager.CallTool(context.Background(), "", "search_issues", nil)
+	assert.Error(t, err)
+
+	// 服务器通知工具列表变化后重新获取该服务器的工具
+	_, err = manager.CallTool(context.Background(), "", "github.add_tool", nil)
+	require.NoError(t, err)
+	catalog, err = manager.ToolCatalog(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, catalog, 5)
+	assert.Equal(t, "github.new_tool", catalog[3].Name)
+	assert.Equal(t, 1, wiki.Calls("tools/list"))
+	assert.Equal(t, 2, github.Calls("tools/list"))
+}
+
+// TestManagerToolCatalogPartialFailure 测试单个服务器不可用时工具目录跳过该服务器
+func TestManagerToolCatalogPartialFailure(t *testing.T) {
+	wiki := newCatalogServer(t, "ask_question")
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"deepwiki": {Enabled: true, ServerURL: wiki.URL},
+			"down":     {Enabled: true, ServerURL: down.URL},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	catalog, err := manager.ToolCatalog(ctx)
+	require.NoError(t, err)
+	require.Len(t, catalog, 1)
+	assert.Equal(t, "deepwiki.ask_question", catalog[0].Name)
+}
+
+// TestManagerToolCatalogStreamNotification 测试服务器在GET SSE流上异步发送工具列表变化通知后重新获取工具
+func TestManagerToolCatalogStreamNotification(t *testing.T) {
+	github := newCatalogServer(t, "search_issues")
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"github": {Enabled: true, ServerURL: github.URL},
+		},
+	})
+
+	catalog, err := manager.ToolCatalog(context.Background())
+	require.NoError(t, err)
+	require.Len(t, catalog, 1)
+
+	// 会话建立后客户端打开GET SSE流
+	require.Eventually(t, func() bool { return github.Streams() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	github.AddTool("new_tool", "new_tool tool", nil)
+	github.Notify("notifications/tools/list_changed", nil)
+	require.Eventually(t, func() bool {
+		catalog, err := manager.ToolCatalog(context.Background())
+		return err == nil && len(catalog) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, github.Calls("tools/list"))
+
+	// 关闭后断开GET SSE流
+	manager.Close(context.Background())
+	require.Eventually(t, func() bool { return github.Streams() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
@@ -1,23 +1,37 @@
 package mcp
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-// Client MCP客户端
+// Client MCP客户端（Streamable HTTP传输）
+// 每个服务器地址维护一个会话：首次请求前完成initialize握手，之后的请求携带会话ID和协商的协议版本
+// 通过NewStdioClient创建时改为与本地进程通信，请求中的服务器地址被忽略
 type Client struct {
 	httpClient *http.Client
 	logger     *logrus.Logger
+	clientInfo Implementation
+	nextID     atomic.Int64
+	sessions   map[string]*clientSession
+	mutex      sync.Mutex
+	stdio      *stdioTransport
+	notify     NotificationHandler
+	oauth      *OAuthClient
 }
 
+// NotificationHandler 服务器通知回调，method为通知方法，如notifications/tools/list_changed
+type NotificationHandler func(method string, params json.RawMessage)
+
 // NewClient 创建新的MCP客户端
 func NewClient(timeout time.Duration) *Client {
 	return &Client{
@@ -25,9 +39,29 @@ func NewClient(timeout time.Duration) *Client {
 			Timeout: timeout,
 		},
 		logger: logrus.New(),
+		clientInfo: Implementation{
+			Name:    DefaultClientName,
+			Version: DefaultClientVersion,
+		},
+		sessions: make(map[string]*clientSession),
 	}
 }
 
+// SetClientInfo 设置initialize时发送的客户端信息
+func (c *Client) SetClientInfo(name, version string) {
+	c.clientInfo = Implementation{Name: name, Version: version}
+}
+
+// SetNotificationHandler 设置服务器通知回调，需在发出请求前设置
+func (c *Client) SetNotificationHandler(handler NotificationHandler) {
+	c.notify = handler
+}
+
+// SetOAuth 设置OAuth客户端，请求携带其访问令牌，服务器返回401时获取新令牌后重试，需在发出请求前设置
+func (c *Client) SetOAuth(oauth *OAuthClient) {
+	c.oauth = oauth
+}
+
 // ListToolsRequest 列出工具请求
 type ListToolsRequest struct {
 	ServerLabel string            `json:"server_label"`
@@ -66,6 +100,7 @@ type CallToolResponse struct {
 // QueryRequest 查询请求
 type QueryRequest struct {
 	ServerLabel string            `json:"server_label"`
+	ServerURL   string            `json:"server_url,omitempty"`
 	Input       string            `json:"input"`
 	Headers     map[string]string `json:"headers,omitempty"`
 	RepoName    string            `json:"repo_name,omitempty"`
@@ -77,143 +112,94 @@ type QueryResponse struct {
 	Error  string `json:"error,omitempty"`
 }
 
-// ListTools 列出MCP服务器提供的工具
+// ListTools 列出MCP服务器提供的工具，服务器分页返回时读取全部页
 func (c *Client) ListTools(ctx context.Context, req *ListToolsRequest) (*ListToolsResponse, error) {
-	// 构建MCP协议请求
-	mcpReq := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "tools/list",
-		"params":  map[string]interface{}{},
-	}
-
-	reqBody, err := json.Marshal(mcpReq)
-	if err != nil {
-		return nil, fmt.Errorf("序列化请求失败: %w", err)
-	}
-
-	// 创建HTTP请求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", req.ServerURL, bytes.NewBuffer(reqBody))
-	if err != nil {
-		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
-	}
-
-	// 设置请求头
-	httpReq.Header.Set("Content-Type", "application/json")
-	for key, value := range req.Headers {
-		httpReq.Header.Set(key, value)
-	}
-
-	// 发送请求
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
-	}
-
-	// 解析MCP响应
-	var mcpResp map[string]interface{}
-	if err := json.Unmarshal(respBody, &mcpResp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w", err)
-	}
-
-	// 检查错误
-	if errorObj, exists := mcpResp["error"]; exists && errorObj != nil {
-		return nil, fmt.Errorf("MCP服务器错误: %v", errorObj)
-	}
-
-	// 提取工具列表
-	result, exists := mcpResp["result"]
-	if !exists {
-		return nil, fmt.Errorf("响应中缺少result字段")
-	}
-
-	resultBytes, err := json.Marshal(result)
+	tools := []Tool{}
+	err := c.paginate(ctx, req.ServerURL, req.Headers, "tools/list", func(result json.RawMessage) error {
+		var page struct {
+			Tools []struct {
+				Name         string                 `json:"name"`
+				Description  string                 `json:"description"`
+				InputSchema  map[string]interface{} `json:"inputSchema"`
+				OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+			} `json:"tools"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return fmt.Errorf("解析工具列表失败: %w", err)
+		}
+
+		for _, tool := range page.Tools {
+			tools = append(tools, Tool{
+				Name:         tool.Name,
+				Description:  tool.Description,
+				InputSchema:  tool.InputSchema,
+				OutputSchema: tool.OutputSchema,
+			})
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("序列化结果失败: %w", err)
-	}
-
-	var tools []Tool
-	if err := json.Unmarshal(resultBytes, &tools); err != nil {
-		return nil, fmt.Errorf("解析工具列表失败: %w", err)
+		return nil, err
 	}
 
 	return &ListToolsResponse{Tools: tools}, nil
 }
 
 // CallTool 调用MCP工具
+// 协议错误和工具执行错误（isError）通过响应的Error返回，网络等错误通过error返回
 func (c *Client) CallTool(ctx context.Context, req *CallToolRequest) (*CallToolResponse, error) {
-	// 构建MCP协议请求
-	mcpReq := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      1,
-		"method":  "tools/call",
-		"params": map[string]interface{}{
-			"name":      req.ToolName,
-			"arguments": req.Arguments,
-		},
+	params := CallToolParams{
+		Name:      req.ToolName,
+		Arguments: req.Arguments,
 	}
-
-	reqBody, err := json.Marshal(mcpReq)
-	if err != nil {
-		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	if params.Arguments == nil {
+		params.Arguments = map[string]interface{}{}
 	}
 
-	// 创建HTTP请求
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", req.ServerURL, bytes.NewBuffer(reqBody))
+	result, err := c.request(ctx, req.ServerURL, req.Headers, "tools/call", params)
 	if err != nil {
-		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+		var rpcErr *RPCError
+		if errors.As(err, &rpcErr) {
+			errorBytes, _ := json.Marshal(rpcErr)
+			return &CallToolResponse{Error: string(errorBytes)}, nil
+		}
+		return nil, err
 	}
 
-	// 设置请求头
-	httpReq.Header.Set("Content-Type", "application/json")
-	for key, value := range req.Headers {
-		httpReq.Header.Set(key, value)
+	// 工具执行失败时结果中isError为true，错误信息在content中
+	var toolResult struct {
+		IsError bool `json:"isError"`
 	}
-
-	// 发送请求
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %w", err)
+	if err := json.Unmarshal(result, &toolResult); err == nil && toolResult.IsError {
+		return &CallToolResponse{Error: ToolResultText(string(result))}, nil
 	}
-	defer resp.Body.Close()
 
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %w", err)
-	}
+	return &CallToolResponse{Output: string(result)}, nil
+}
 
-	// 解析MCP响应
-	var mcpResp map[string]interface{}
-	if err := json.Unmarshal(respBody, &mcpResp); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %w", err)
+// ToolResultText 提取工具调用结果中的文本内容
+// 结果为MCP格式{"content":[{"type":"text","text":"..."}]}时拼接所有文本块，否则原样返回
+func ToolResultText(output string) string {
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
 	}
-
-	// 检查错误
-	if errorObj, exists := mcpResp["error"]; exists && errorObj != nil {
-		errorBytes, _ := json.Marshal(errorObj)
-		return &CallToolResponse{Error: string(errorBytes)}, nil
+	if err := json.Unmarshal([]byte(output), &result); err != nil || len(result.Content) == 0 {
+		return output
 	}
 
-	// 提取结果
-	result, exists := mcpResp["result"]
-	if !exists {
-		return nil, fmt.Errorf("响应中缺少result字段")
+	var texts []string
+	for _, content := range result.Content {
+		if content.Type == "text" && content.Text != "" {
+			texts = append(texts, content.Text)
+		}
 	}
-
-	resultBytes, err := json.Marshal(result)
-	if err != nil {
-		return nil, fmt.Errorf("序列化结果失败: %w", err)
+	if len(texts) == 0 {
+		return output
 	}
-
-	return &CallToolResponse{Output: string(resultBytes)}, nil
+	return strings.Join(texts, "\n\n")
 }
 
 // Query 执行查询（针对DeepWiki等特定服务器）
@@ -223,15 +209,20 @@ func (c *Client) Query(ctx context.Context, req *QueryRequest) (*QueryResponse,
 		"question": req.Input,
 	}
 
-	// 如果是DeepWiki且有仓库名，添加仓库参数
-	if req.ServerLabel == "deepwiki" && req.RepoName != "" {
+	// 有仓库名时添加仓库参数（DeepWiki等按仓库问答的服务器）
+	if req.RepoName != "" {
 		arguments["repoName"] = req.RepoName
 	}
 
+	serverURL := req.ServerURL
+	if serverURL == "" {
+		serverURL = getServerURL(req.ServerLabel)
+	}
+
 	// 调用工具
 	callReq := &CallToolRequest{
 		ServerLabel: req.ServerLabel,
-		ServerURL:   getServerURL(req.ServerLabel),
+		ServerURL:   serverURL,
 		ToolName:    "ask_question",
 		Arguments:   arguments,
 		Headers:     req.Headers,
@@ -0,0 +1,178 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedRequest 记录客户端发给服务端的请求
+type recordedRequest struct {
+	Method          string
+	ID              string
+	SessionID       string
+	ProtocolVersion string
+}
+
+// newRecordingMCPServer 创建记录请求的MCP服务端
+func newRecordingMCPServer(t *testing.T) (*httptest.Server, *mcp.Server, func() []recordedRequest) {
+	mcpServer := mcp.NewServer("test-server", "1.0.0")
+	mcpServer.RegisterTool(&mcp.ServerTool{
+		Name:        "echo",
+		Description: "回显输入",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+			return arguments["text"], nil
+		},
+	})
+
+	var (
+		mutex    sync.Mutex
+		requests []recordedRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var message struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.Unmarshal(body, &message)
+
+		mutex.Lock()
+		requests = append(requests, recordedRequest{
+			Method:          message.Method,
+			ID:              string(message.ID),
+			SessionID:       r.Header.Get(mcp.SessionHeader),
+			ProtocolVersion: r.Header.Get(mcp.ProtocolVersionHeader),
+		})
+		mutex.Unlock()
+
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		mcpServer.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, mcpServer, func() []recordedRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]recordedRequest(nil), requests...)
+	}
+}
+
+// TestMCPClientSession 测试客户端会话：initialize握手、会话ID、协议版本和递增的请求ID
+func TestMCPClientSession(t *testing.T) {
+	server, _, recorded := newRecordingMCPServer(t)
+	client := mcp.NewClient(5 * time.Second)
+
+	tools, err := client.ListTools(context.Background(), &mcp.ListToolsRequest{ServerURL: server.URL})
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Equal(t, "echo", tools.Tools[0].Name)
+	assert.Equal(t, "object", tools.Tools[0].InputSchema["type"])
+
+	response, err := client.CallTool(context.Background(), &mcp.CallToolRequest{
+		ServerURL: server.URL,
+		ToolName:  "echo",
+		Arguments: map[string]interface{}{"text": "hello"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", mcp.ToolResultText(response.Output))
+
+	session, ok := client.Session(server.URL)
+	require.True(t, ok)
+	assert.NotEmpty(t, session.SessionID)
+	assert.Equal(t, mcp.LatestProtocolVersion, session.ProtocolVersion)
+	assert.Equal(t, "test-server", session.ServerInfo.Name)
+	assert.True(t, session.HasCapability("tools"))
+	assert.False(t, session.HasCapability("resources"))
+
+	requests := recorded()
+	require.Len(t, requests, 4)
+	assert.Equal(t, "initialize", requests[0].Method)
+	assert.Empty(t, requests[0].SessionID)
+	assert.Equal(t, "notifications/initialized", requests[1].Method)
+	assert.Empty(t, requests[1].ID)
+	assert.Equal(t, "tools/list", requests[2].Method)
+	assert.Equal(t, "tools/call", requests[3].Method)
+
+	// 初始化后的请求携带会话ID和协议版本，请求ID单调递增
+	for _, request := range requests[1:] {
+		assert.Equal(t, session.SessionID, request.SessionID)
+		assert.Equal(t, session.ProtocolVersion, request.ProtocolVersion)
+	}
+	assert.Less(t, requests[0].ID, requests[2].ID)
+	assert.Less(t, requests[2].ID, requests[3].ID)
+}
+
+// TestMCPClientSessionExpiry 测试会话过期（404）后自动重新初始化
+func TestMCPClientSessionExpiry(t *testing.T) {
+	server, _, recorded := newRecordingMCPServer(t)
+	client := mcp.NewClient(5 * time.Second)
+
+	first, err := client.Initialize(context.Background(), server.URL, nil)
+	require.NoError(t, err)
+
+	// 服务端删除会话
+	req, _ := http.NewRequest(http.MethodDelete, server.URL, nil)
+	req.Header.Set(mcp.SessionHeader, first.SessionID)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	response, err := client.CallTool(context.Background(), &mcp.CallToolRequest{
+		ServerURL: server.URL,
+		ToolName:  "echo",
+		Arguments: map[string]interface{}{"text": "again"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "again", mcp.ToolResultText(response.Output))
+
+	second, ok := client.Session(server.URL)
+	require.True(t, ok)
+	assert.NotEqual(t, first.SessionID, second.SessionID)
+
+	var methods []string
+	for _, request := range recorded() {
+		methods = append(methods, request.Method)
+	}
+	assert.Equal(t, []string{
+		"initialize", "notifications/initialized", "",
+		"tools/call", "initialize", "notifications/initialized", "tools/call",
+	}, methods)
+
+	// 关闭后会话被释放
+	require.NoError(t, client.Close(context.Background(), server.URL, nil))
+	_, ok = client.Session(server.URL)
+	assert.False(t, ok)
+}
+
+// TestMCPClientUnsupportedVersion 测试服务端返回不支持的协议版本时初始化失败
+func TestMCPClientUnsupportedVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message struct {
+			ID json.RawMessage `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&message)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      message.ID,
+			"result":  map[string]interface{}{"protocolVersion": "2023-01-01"},
+		})
+	}))
+	defer server.Close()
+
+	client := mcp.NewClient(5 * time.Second)
+	_, err := client.ListTools(context.Background(), &mcp.ListToolsRequest{ServerURL: server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2023-01-01")
+}
@@ -0,0 +1,376 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultHealthCheckInterval 未配置health_check.interval时的后台健康检查间隔
+	DefaultHealthCheckInterval = 30 * time.Second
+	// DefaultFailureThreshold 连续失败多少次后熔断
+	DefaultFailureThreshold = 5
+	// DefaultOpenTimeout 熔断后多久进入半开状态试探服务器
+	DefaultOpenTimeout = 30 * time.Second
+	// maxPingTimeout 单次健康检查的最长等待时间
+	maxPingTimeout = 10 * time.Second
+	// latencySmoothing 平均延迟的指数平滑系数
+	latencySmoothing = 0.2
+)
+
+// CircuitState 熔断器状态
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // 正常放行
+	CircuitOpen     CircuitState = "open"      // 熔断，请求直接失败
+	CircuitHalfOpen CircuitState = "half_open" // 试探中，只放行一个请求
+)
+
+// CircuitOpenError 服务器处于熔断状态，请求未发送
+type CircuitOpenError struct {
+	Server  string
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("MCP服务器 %s 已熔断，%s后重试", e.Server, e.RetryAt.Format(time.RFC3339))
+}
+
+// ServerHealth 服务器健康状态
+type ServerHealth struct {
+	Server              string       `json:"server"`
+	State               CircuitState `json:"state"`
+	Healthy             bool         `json:"healthy"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	TotalRequests       int64        `json:"total_requests"`
+	TotalFailures       int64        `json:"total_failures"`
+	ShortCircuited      int64        `json:"short_circuited"`
+	LastLatencyMs       float64      `json:"last_latency_ms"`
+	AvgLatencyMs        float64      `json:"avg_latency_ms"`
+	LastError           string       `json:"last_error,omitempty"`
+	LastCheckAt         *time.Time   `json:"last_check_at,omitempty"`
+	LastSuccessAt       *time.Time   `json:"last_success_at,omitempty"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+}
+
+// circuitBreaker 单个服务器的熔断器和延迟统计
+// 连续失败达到阈值后熔断；熔断超过openTimeout后进入半开状态，放行一个请求试探，成功则恢复，失败则重新熔断
+type circuitBreaker struct {
+	server           string
+	failureThreshold int
+	openTimeout      time.Duration
+	mutex            sync.Mutex
+	health           ServerHealth
+	probing          bool
+}
+
+// newCircuitBreaker 创建熔断器
+func newCircuitBreaker(server string, failureThreshold int, openTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		server:           server,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		health: ServerHealth{
+			Server:  server,
+			State:   CircuitClosed,
+			Healthy: true,
+		},
+	}
+}
+
+// allow 判断是否放行请求，熔断时返回*CircuitOpenError
+func (b *circuitBreaker) allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.health.State {
+	case CircuitOpen:
+		retryAt := b.health.OpenedAt.Add(b.openTimeout)
+		if time.Now().Before(retryAt) {
+			b.health.ShortCircuited++
+			return &CircuitOpenError{Server: b.server, RetryAt: retryAt}
+		}
+		b.health.State = CircuitHalfOpen
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			b.health.ShortCircuited++
+			return &CircuitOpenError{Server: b.server, RetryAt: time.Now().Add(b.openTimeout)}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record 记录请求结果，返回状态是否发生变化
+func (b *circuitBreaker) record(latency time.Duration, err error) (CircuitState, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	previous := b.health.State
+	b.probing = false
+	b.health.TotalRequests++
+	b.health.LastCheckAt = &now
+	b.health.LastLatencyMs = float64(latency) / float64(time.Millisecond)
+	if b.health.AvgLatencyMs == 0 {
+		b.health.AvgLatencyMs = b.health.LastLatencyMs
+	} else {
+		b.health.AvgLatencyMs = latencySmoothing*b.health.LastLatencyMs + (1-latencySmoothing)*b.health.AvgLatencyMs
+	}
+
+	if err == nil {
+		b.health.ConsecutiveFailures = 0
+		b.health.LastError = ""
+		b.health.LastSuccessAt = &now
+		b.health.State = CircuitClosed
+		b.health.OpenedAt = nil
+	} else {
+		b.health.ConsecutiveFailures++
+		b.health.TotalFailures++
+		b.health.LastError = err.Error()
+		if previous == CircuitHalfOpen || b.health.ConsecutiveFailures >= b.failureThreshold {
+			b.health.State = CircuitOpen
+			b.health.OpenedAt = &now
+		}
+	}
+	b.health.Healthy = b.health.State == CircuitClosed
+	return b.health.State, b.health.State != previous
+}
+
+// release 请求未产生可判断的结果（如调用方取消）时释放试探名额，下一个请求继续试探
+func (b *circuitBreaker) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.probing = false
+}
+
+// reset 关闭熔断器并清除连续失败次数，用于服务器的故障原因已排除（如完成授权）时
+func (b *circuitBreaker) reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.probing = false
+	b.health.State = CircuitClosed
+	b.health.Healthy = true
+	b.health.ConsecutiveFailures = 0
+	b.health.OpenedAt = nil
+}
+
+// snapshot 获取健康状态副本
+func (b *circuitBreaker) snapshot() ServerHealth {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.health
+}
+
+// healthSettings 解析健康检查配置，未配置的项使用默认值
+func healthSettings(config *model.MCPConfig, logger *logrus.Logger) (interval time.Duration, failureThreshold int, openTimeout time.Duration) {
+	interval, failureThreshold, openTimeout = DefaultHealthCheckInterval, DefaultFailureThreshold, DefaultOpenTimeout
+	if config == nil {
+		return
+	}
+
+	healthConfig := config.HealthCheck
+	if healthConfig.Interval != "" {
+		if value, err := time.ParseDuration(healthConfig.Interval); err == nil {
+			interval = value
+		} else {
+			logger.WithError(err).Warn("健康检查间隔配置无效，使用默认值")
+		}
+	}
+	if healthConfig.FailureThreshold > 0 {
+		failureThreshold = healthConfig.FailureThreshold
+	}
+	if healthConfig.OpenTimeout != "" {
+		if value, err := time.ParseDuration(healthConfig.OpenTimeout); err == nil && value > 0 {
+			openTimeout = value
+		} else {
+			logger.WithField("open_timeout", healthConfig.OpenTimeout).Warn("熔断恢复时间配置无效，使用默认值")
+		}
+	}
+	return
+}
+
+// healthProbeKey 健康检查的上下文键
+type healthProbeKey struct{}
+
+// withHealthProbe 返回健康检查使用的上下文，健康检查超时说明服务器无响应，计入服务器失败
+func withHealthProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, healthProbeKey{}, true)
+}
+
+// isHealthProbe 判断上下文是否来自健康检查
+func isHealthProbe(ctx context.Context) bool {
+	probe, _ := ctx.Value(healthProbeKey{}).(bool)
+	return probe
+}
+
+// guard 通过熔断器执行对服务器的请求，熔断时不发送请求直接返回*CircuitOpenError
+// 调用方取消或调用方的超时到期不计入服务器失败，健康检查超时除外
+func (m *Manager) guard(ctx context.Context, serverLabel string, request func() error) error {
+	breaker := m.breakers[serverLabel]
+	if breaker == nil {
+		return request()
+	}
+	if err := breaker.allow(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := request()
+	if err != nil && ctx.Err() != nil && !(isHealthProbe(ctx) && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+		breaker.release()
+		return err
+	}
+
+	state, changed := breaker.record(time.Since(start), err)
+	if changed {
+		entry := m.logger.WithFields(logrus.Fields{
+			"server": serverLabel,
+			"state":  state,
+		})
+		if state == CircuitOpen {
+			entry.WithError(err).Warn("MCP服务器熔断")
+		} else {
+			entry.Info("MCP服务器熔断状态变化")
+		}
+	}
+	return err
+}
+
+// ping 向服务器发送ping检查连通性
+func (m *Manager) ping(ctx context.Context, serverLabel string) error {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return err
+	}
+	return m.guard(ctx, serverLabel, func() error {
+		return client.Ping(ctx, serverConfig.ServerURL, serverConfig.Headers)
+	})
+}
+
+// startHealthMonitor 为每个服务器启动后台健康检查
+func (m *Manager) startHealthMonitor(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	timeout := interval
+	if timeout > maxPingTimeout {
+		timeout = maxPingTimeout
+	}
+	for serverLabel := range m.clients {
+		go m.monitorServer(serverLabel, interval, timeout)
+	}
+	m.logger.WithField("interval", interval).Info("MCP服务器健康检查已启动")
+}
+
+// monitorServer 定时检查服务器，熔断期间等待进入半开状态后再试探
+func (m *Manager) monitorServer(serverLabel string, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopMonitor:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(withHealthProbe(context.Background()), timeout)
+		err := m.ping(ctx, serverLabel)
+		cancel()
+
+		var openErr *CircuitOpenError
+		if err != nil && !errors.As(err, &openErr) {
+			m.logger.WithError(err).WithField("server", serverLabel).Debug("MCP服务器健康检查失败")
+		}
+	}
+}
+
+// stopHealthMonitor 停止后台健康检查
+func (m *Manager) stopHealthMonitor() {
+	m.stopOnce.Do(func() {
+		close(m.stopMonitor)
+	})
+}
+
+// Health 获取全部已启用服务器的健康状态
+func (m *Manager) Health() map[string]ServerHealth {
+	health := make(map[string]ServerHealth, len(m.breakers))
+	for serverLabel, breaker := range m.breakers {
+		health[serverLabel] = breaker.snapshot()
+	}
+	return health
+}
+
+// ServerHealth 获取服务器的健康状态
+func (m *Manager) ServerHealth(serverLabel string) (ServerHealth, bool) {
+	breaker, exists := m.breakers[serverLabel]
+	if !exists {
+		return ServerHealth{}, false
+	}
+	return breaker.snapshot(), true
+}
+
+// WriteMetrics 以Prometheus文本格式输出服务器健康指标
+func (m *Manager) WriteMetrics(w io.Writer) {
+	health := m.Health()
+	servers := make([]string, 0, len(health))
+	for serverLabel := range health {
+		servers = append(servers, serverLabel)
+	}
+	sort.Strings(servers)
+
+	metrics := []struct {
+		name, help, kind string
+		value            func(ServerHealth) float64
+	}{
+		{"mcp_server_up", "MCP服务器熔断器是否处于关闭状态", "gauge", func(h ServerHealth) float64 { return boolMetric(h.Healthy) }},
+		{"mcp_server_circuit_open", "MCP服务器是否熔断（半开视为0.5）", "gauge", func(h ServerHealth) float64 { return circuitMetric(h.State) }},
+		{"mcp_server_consecutive_failures", "MCP服务器连续失败次数", "gauge", func(h ServerHealth) float64 { return float64(h.ConsecutiveFailures) }},
+		{"mcp_server_requests_total", "发送到MCP服务器的请求数", "counter", func(h ServerHealth) float64 { return float64(h.TotalRequests) }},
+		{"mcp_server_failures_total", "MCP服务器请求失败数", "counter", func(h ServerHealth) float64 { return float64(h.TotalFailures) }},
+		{"mcp_server_short_circuited_total", "因熔断未发送的请求数", "counter", func(h ServerHealth) float64 { return float64(h.ShortCircuited) }},
+		{"mcp_server_last_latency_seconds", "最近一次请求的延迟", "gauge", func(h ServerHealth) float64 { return h.LastLatencyMs / 1000 }},
+		{"mcp_server_avg_latency_seconds", "请求延迟的指数平滑平均值", "gauge", func(h ServerHealth) float64 { return h.AvgLatencyMs / 1000 }},
+	}
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, serverLabel := range servers {
+			fmt.Fprintf(w, "%s{server=%q} %g\n", metric.name, serverLabel, metric.value(health[serverLabel]))
+		}
+	}
+}
+
+// boolMetric 布尔值指标
+func boolMetric(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// circuitMetric 熔断状态指标
+func circuitMetric(state CircuitState) float64 {
+	switch state {
+	case CircuitOpen:
+		return 1
+	case CircuitHalfOpen:
+		return 0.5
+	default:
+		return 0
+	}
+}
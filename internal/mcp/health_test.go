@@ -0,0 +1,172 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlakyServer 创建模拟MCP服务器，echo工具返回ok
+func newFlakyServer(t *testing.T) *mcptest.Server {
+	server := mcptest.NewServer(t, "flaky")
+	server.AddTool("echo", "", func(*mcptest.Request, map[string]interface{}) (string, error) {
+		return "ok", nil
+	})
+	return server
+}
+
+// TestManagerCircuitBreaker 测试连续失败后熔断、熔断期间走备用方案、半开试探后恢复以及健康指标
+func TestManagerCircuitBreaker(t *testing.T) {
+	server := newFlakyServer(t)
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"flaky": {Enabled: true, ServerURL: server.URL},
+		},
+		HealthCheck: model.MCPHealthConfig{Interval: "0", FailureThreshold: 2, OpenTimeout: "200ms"},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+	ctx := context.Background()
+
+	_, err := manager.CallTool(ctx, "flaky", "echo", nil)
+	require.NoError(t, err)
+
+	// 连续失败达到阈值后熔断
+	server.SetFailing(http.StatusBadGateway)
+	for i := 0; i < 2; i++ {
+		_, err = manager.CallTool(ctx, "flaky", "echo", nil)
+		require.Error(t, err)
+	}
+	health, exists := manager.ServerHealth("flaky")
+	require.True(t, exists)
+	assert.Equal(t, mcp.CircuitOpen, health.State)
+	assert.False(t, health.Healthy)
+	assert.Equal(t, 2, health.ConsecutiveFailures)
+
+	// 熔断期间请求不发送到服务器，检索走备用方案
+	requests := server.Requests()
+	_, err = manager.CallTool(ctx, "flaky", "echo", nil)
+	var openErr *mcp.CircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	assert.Equal(t, "flaky", openErr.Server)
+
+	fallbackUsed := false
+	items, err := manager.QueryWithFallback(ctx, "flaky", "question", "", func() ([]model.KnowledgeItem, error) {
+		fallbackUsed = true
+		return []model.KnowledgeItem{{Title: "fallback"}}, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, fallbackUsed)
+	assert.Equal(t, "fallback", items[0].Title)
+	assert.Equal(t, requests, server.Requests())
+
+	// 半开试探失败后重新熔断
+	time.Sleep(250 * time.Millisecond)
+	_, err = manager.CallTool(ctx, "flaky", "echo", nil)
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &openErr))
+	health, _ = manager.ServerHealth("flaky")
+	assert.Equal(t, mcp.CircuitOpen, health.State)
+
+	// 服务器恢复后半开试探成功，熔断器关闭
+	server.SetFailing(0)
+	time.Sleep(250 * time.Millisecond)
+	response, err := manager.CallTool(ctx, "flaky", "echo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", mcp.ToolResultText(response.Output))
+
+	health, _ = manager.ServerHealth("flaky")
+	assert.Equal(t, mcp.CircuitClosed, health.State)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, 0, health.ConsecutiveFailures)
+	assert.Equal(t, int64(3), health.TotalFailures)
+	assert.Equal(t, int64(2), health.ShortCircuited)
+	assert.NotNil(t, health.LastSuccessAt)
+
+	var metrics bytes.Buffer
+	manager.WriteMetrics(&metrics)
+	assert.Contains(t, metrics.String(), `mcp_server_up{server="flaky"} 1`)
+	assert.Contains(t, metrics.String(), `mcp_server_failures_total{server="flaky"} 3`)
+	assert.Contains(t, metrics.String(), `mcp_server_short_circuited_total{server="flaky"} 2`)
+	assert.Contains(t, metrics.String(), "# TYPE mcp_server_requests_total counter")
+}
+
+// TestManagerHealthMonitor 测试后台健康检查在没有业务请求时发现服务器故障和恢复
+func TestManagerHealthMonitor(t *testing.T) {
+	server := newFlakyServer(t)
+	server.SetFailing(http.StatusBadGateway)
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"flaky": {Enabled: true, ServerURL: server.URL},
+		},
+		HealthCheck: model.MCPHealthConfig{Interval: "20ms", FailureThreshold: 2, OpenTimeout: "50ms"},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+
+	require.Eventually(t, func() bool {
+		health, _ := manager.ServerHealth("flaky")
+		return health.State == mcp.CircuitOpen
+	}, 2*time.Second, 10*time.Millisecond)
+
+	server.SetFailing(0)
+	require.Eventually(t, func() bool {
+		health, _ := manager.ServerHealth("flaky")
+		return health.State == mcp.CircuitClosed && health.LastSuccessAt != nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	health := manager.Health()
+	require.Contains(t, health, "flaky")
+	assert.Greater(t, health["flaky"].AvgLatencyMs, 0.0)
+	assert.Equal(t, map[string]bool{"flaky": true}, manager.HealthCheck(context.Background()))
+}
+
+// TestManagerCircuitBreakerCallerDeadline 测试调用方超时不计入服务器失败，健康检查超时计入
+func TestManagerCircuitBreakerCallerDeadline(t *testing.T) {
+	server := newFlakyServer(t)
+	server.AddTool("slow", "", func(*mcptest.Request, map[string]interface{}) (string, error) {
+		time.Sleep(200 * time.Millisecond)
+		return "ok", nil
+	})
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"flaky": {Enabled: true, ServerURL: server.URL},
+		},
+		HealthCheck: model.MCPHealthConfig{Interval: "0", FailureThreshold: 2, OpenTimeout: "1m"},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := manager.CallTool(ctx, "flaky", "slow", nil)
+		cancel()
+		require.Error(t, err)
+	}
+	health, _ := manager.ServerHealth("flaky")
+	assert.Equal(t, mcp.CircuitClosed, health.State)
+	assert.Equal(t, 0, health.ConsecutiveFailures)
+
+	// 服务器对健康检查无响应时熔断
+	server.Handle("ping", func(*mcptest.Request) (interface{}, error) {
+		time.Sleep(200 * time.Millisecond)
+		return nil, nil
+	})
+	monitored := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"flaky": {Enabled: true, ServerURL: server.URL},
+		},
+		HealthCheck: model.MCPHealthConfig{Interval: "20ms", FailureThreshold: 2, OpenTimeout: "1m"},
+	})
+	t.Cleanup(func() { monitored.Close(context.Background()) })
+	require.Eventually(t, func() bool {
+		health, _ := monitored.ServerHealth("flaky")
+		return health.State == mcp.CircuitOpen
+	}, 5*time.Second, 10*time.Millisecond)
+}
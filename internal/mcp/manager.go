@@ -1,9 +1,12 @@
 package mcp
 
 import (
+	"container/list"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -13,42 +16,134 @@ import (
 
 // Manager MCP管理器
 type Manager struct {
-	clients    map[string]*Client
-	config     *model.MCPConfig
-	logger     *logrus.Logger
-	mutex      sync.RWMutex
-	cache      map[string]*CacheEntry
-}
-
-// CacheEntry 缓存条目
-type CacheEntry struct {
-	Data      interface{}
-	ExpiresAt time.Time
+	clients   map[string]*Client
+	config    *model.MCPConfig
+	logger    *logrus.Logger
+	mutex     sync.RWMutex
+	approvals *ApprovalQueue
+	catalog   *toolCatalog
+	breakers  map[string]*circuitBreaker
+
+	stopMonitor chan struct{}
+	stopOnce    sync.Once
+
+	cache           map[string]*CacheEntry
+	cacheOrder      *list.List
+	cacheMaxEntries int
+	cachePolicies   map[string]*cachePolicy
+	cacheMutex      sync.Mutex
+
+	oauth map[string]*OAuthClient
+
+	resourceListeners []ResourceListener
 }
 
 // NewManager 创建新的MCP管理器
 func NewManager(config *model.MCPConfig) *Manager {
+	config = expandConfig(config)
 	manager := &Manager{
 		clients: make(map[string]*Client),
 		config:  config,
 		logger:  logrus.New(),
-		cache:   make(map[string]*CacheEntry),
+		catalog: newToolCatalog(),
+
+		breakers:    make(map[string]*circuitBreaker),
+		stopMonitor: make(chan struct{}),
+
+		cache:           make(map[string]*CacheEntry),
+		cacheOrder:      list.New(),
+		cacheMaxEntries: DefaultCacheMaxEntries,
+		cachePolicies:   make(map[string]*cachePolicy),
+
+		oauth: make(map[string]*OAuthClient),
+	}
+
+	// 需要审批的工具调用在队列中等待，超时时间可配置
+	approvalTimeout := DefaultApprovalTimeout
+	if config != nil && config.ApprovalTimeout != "" {
+		if timeout, err := time.ParseDuration(config.ApprovalTimeout); err == nil {
+			approvalTimeout = timeout
+		} else {
+			manager.logger.WithError(err).Warn("审批超时时间配置无效，使用默认值")
+		}
+	}
+	manager.approvals = NewApprovalQueue(approvalTimeout)
+
+	// 每个服务器一个熔断器，后台定时健康检查
+	healthInterval, failureThreshold, openTimeout := healthSettings(config, manager.logger)
+
+	if config != nil && config.CacheMaxEntries > 0 {
+		manager.cacheMaxEntries = config.CacheMaxEntries
+	}
+
+	// 启用OAuth的远程服务器共享凭据存储，配置了文件时令牌在重启后保留
+	var tokenStore TokenStore = NewMemoryTokenStore()
+	if config != nil && config.OAuthTokenFile != "" {
+		tokenStore = NewFileTokenStore(config.OAuthTokenFile)
 	}
 
 	// 初始化已启用的MCP服务器客户端
 	if config != nil {
 		for serverLabel, serverConfig := range config.Servers {
 			if serverConfig.Enabled {
-				client := NewClient(30 * time.Second)
+				var client *Client
+				if serverConfig.Command != "" {
+					// 配置了命令的服务器作为本地进程启动，通过stdio通信
+					client = NewStdioClient(StdioConfig{
+						Command: serverConfig.Command,
+						Args:    serverConfig.Args,
+						Env:     serverConfig.Env,
+						Cwd:     serverConfig.Cwd,
+					}, 30*time.Second)
+				} else {
+					client = NewClient(30 * time.Second)
+					if serverConfig.OAuth.Enabled {
+						oauth := NewOAuthClient(serverLabel, serverConfig.ServerURL, serverConfig.OAuth, tokenStore, 30*time.Second)
+						client.SetOAuth(oauth)
+						manager.oauth[serverLabel] = oauth
+					}
+				}
+				label := serverLabel
+				client.SetNotificationHandler(func(method string, params json.RawMessage) {
+					manager.handleServerNotification(label, method, params)
+				})
 				manager.clients[serverLabel] = client
+				manager.breakers[serverLabel] = newCircuitBreaker(serverLabel, failureThreshold, openTimeout)
+				if policy := newCachePolicy(serverLabel, &serverConfig, manager.logger); policy != nil {
+					manager.cachePolicies[serverLabel] = policy
+				}
 				manager.logger.WithField("server", serverLabel).Info("MCP服务器客户端已初始化")
 			}
 		}
 	}
+	manager.startHealthMonitor(healthInterval)
 
 	return manager
 }
 
+// expandConfig 复制配置并展开请求头和OAuth客户端信息中的${ENV}占位符
+func expandConfig(config *model.MCPConfig) *model.MCPConfig {
+	if config == nil {
+		return nil
+	}
+
+	expanded := *config
+	expanded.Servers = make(map[string]model.MCPServer, len(config.Servers))
+	for serverLabel, serverConfig := range config.Servers {
+		if serverConfig.Headers != nil {
+			headers := make(map[string]string, len(serverConfig.Headers))
+			for key, value := range serverConfig.Headers {
+				headers[key] = os.ExpandEnv(value)
+			}
+			serverConfig.Headers = headers
+		}
+		serverConfig.OAuth.ClientID = os.ExpandEnv(serverConfig.OAuth.ClientID)
+		serverConfig.OAuth.ClientSecret = os.ExpandEnv(serverConfig.OAuth.ClientSecret)
+		expanded.Servers[serverLabel] = serverConfig
+	}
+	return &expanded
+}
+
 // GetClient 获取MCP客户端
 func (m *Manager) GetClient(serverLabel string) (*Client, error) {
 	m.mutex.RLock()
@@ -63,21 +158,18 @@ func (m *Manager) GetClient(serverLabel string) (*Client, error) {
 }
 
 // Query 执行MCP查询
+// 查询通过ask_question工具完成，同样受工具策略、熔断器和响应缓存约束
 func (m *Manager) Query(ctx context.Context, serverLabel, input string, repoName string) (*QueryResponse, error) {
-	client, err := m.GetClient(serverLabel)
-	if err != nil {
-		return nil, err
+	arguments := map[string]interface{}{"question": input}
+	if repoName != "" {
+		arguments["repoName"] = repoName
 	}
 
-	// 构建查询请求
-	req := &QueryRequest{
-		ServerLabel: serverLabel,
-		Input:       input,
-		RepoName:    repoName,
+	response, err := m.CallTool(ctx, serverLabel, "ask_question", arguments)
+	if err != nil {
+		return nil, err
 	}
-
-	// 执行查询
-	return client.Query(ctx, req)
+	return &QueryResponse{Output: response.Output, Error: response.Error}, nil
 }
 
 // ListTools 获取MCP服务器工具列表
@@ -101,11 +193,41 @@ func (m *Manager) ListTools(ctx context.Context, serverLabel string) (*ListTools
 	}
 
 	// 执行请求
-	return client.ListTools(ctx, req)
+	var response *ListToolsResponse
+	err = m.guard(ctx, serverLabel, func() error {
+		var listErr error
+		response, listErr = client.ListTools(ctx, req)
+		return listErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 只返回允许调用的工具
+	allowed := response.Tools[:0]
+	for _, tool := range response.Tools {
+		if ToolAllowed(&serverConfig, tool.Name) {
+			allowed = append(allowed, tool)
+		}
+	}
+	response.Tools = allowed
+	return response, nil
 }
 
 // CallTool 调用MCP工具
+// 服务器配置了cache时，cache.tools中的工具按服务器、工具名和参数缓存响应。
+// serverLabel为空时toolName需为工具目录中带服务器前缀的名称（如deepwiki.ask_question），按前缀路由到服务器。
+// 工具不在allowed_tools中时返回*ToolNotAllowedError；服务器要求审批时等待审批，
+// 被拒绝或超时返回*ApprovalError；ctx由WithAsyncApproval创建时不等待审批
 func (m *Manager) CallTool(ctx context.Context, serverLabel, toolName string, arguments map[string]interface{}) (*CallToolResponse, error) {
+	if serverLabel == "" {
+		server, tool, ok := SplitToolName(toolName)
+		if !ok {
+			return nil, fmt.Errorf("工具名缺少服务器前缀: %s", toolName)
+		}
+		serverLabel, toolName = server, tool
+	}
+
 	client, err := m.GetClient(serverLabel)
 	if err != nil {
 		return nil, err
@@ -117,6 +239,10 @@ func (m *Manager) CallTool(ctx context.Context, serverLabel, toolName string, ar
 		return nil, fmt.Errorf("服务器配置未找到: %s", serverLabel)
 	}
 
+	if err := m.authorize(ctx, serverLabel, &serverConfig, toolName, arguments); err != nil {
+		return nil, err
+	}
+
 	// 构建请求
 	req := &CallToolRequest{
 		ServerLabel: serverLabel,
@@ -126,14 +252,66 @@ func (m *Manager) CallTool(ctx context.Context, serverLabel, toolName string, ar
 		Headers:     serverConfig.Headers,
 	}
 
-	// 执行请求
-	return client.CallTool(ctx, req)
+	// 执行请求，可缓存的工具优先使用缓存，服务器熔断时直接返回*CircuitOpenError
+	return m.cachedCall(ctx, serverLabel, toolName, arguments, func(ctx context.Context) (*CallToolResponse, error) {
+		var response *CallToolResponse
+		err := m.guard(ctx, serverLabel, func() error {
+			var callErr error
+			response, callErr = client.CallTool(ctx, req)
+			return callErr
+		})
+		return response, err
+	})
+}
+
+// Approvals 获取工具调用审批队列
+func (m *Manager) Approvals() *ApprovalQueue {
+	return m.approvals
+}
+
+// authorize 检查工具调用是否符合服务器的工具策略，需要审批时阻塞直到审批完成，
+// ctx由WithAsyncApproval创建时提交审批后立即返回，或使用已批准的审批
+func (m *Manager) authorize(ctx context.Context, serverLabel string, serverConfig *model.MCPServer, toolName string, arguments map[string]interface{}) error {
+	if !ToolAllowed(serverConfig, toolName) {
+		m.logger.WithFields(logrus.Fields{
+			"server": serverLabel,
+			"tool":   toolName,
+		}).Warn("拒绝调用未允许的MCP工具")
+		return &ToolNotAllowedError{Server: serverLabel, Tool: toolName}
+	}
+	if !RequiresApproval(serverConfig) {
+		return nil
+	}
+
+	if approvalID, ok := asyncApproval(ctx); ok {
+		if approvalID != "" {
+			return m.approvals.Use(approvalID, serverLabel, toolName, arguments)
+		}
+		approval := m.approvals.Request(serverLabel, toolName, arguments)
+		m.logger.WithFields(logrus.Fields{
+			"server":   serverLabel,
+			"tool":     toolName,
+			"approval": approval.ID,
+		}).Info("MCP工具调用已提交审批")
+		return &ApprovalPendingError{Approval: approval}
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"server": serverLabel,
+		"tool":   toolName,
+	}).Info("MCP工具调用等待审批")
+	return m.approvals.Submit(ctx, serverLabel, toolName, arguments)
 }
 
 // QueryWithFallback 执行带备用方案的查询
 func (m *Manager) QueryWithFallback(ctx context.Context, serverLabel, input string, repoName string, fallbackFunc func() ([]model.KnowledgeItem, error)) ([]model.KnowledgeItem, error) {
 	// 尝试MCP查询
 	queryResp, err := m.Query(ctx, serverLabel, input, repoName)
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		m.logger.WithField("server", serverLabel).Debug("MCP服务器已熔断，使用备用方案")
+		return fallbackFunc()
+	}
 	if err != nil {
 		m.logger.WithError(err).Warn("MCP查询失败，使用备用方案")
 		return fallbackFunc()
@@ -144,15 +322,31 @@ func (m *Manager) QueryWithFallback(ctx context.Context, serverLabel, input stri
 		return fallbackFunc()
 	}
 
-	// 解析MCP响应为KnowledgeItem
-	items := m.parseMCPResponseToKnowledgeItems(queryResp.Output, model.KnowledgeSourceDeepWiki)
+	// 解析MCP响应为KnowledgeItem，来源由服务器配置决定
+	items := m.parseMCPResponseToKnowledgeItems(queryResp.Output, m.SourceType(serverLabel))
 	return items, nil
 }
 
+// SourceType 获取服务器返回知识的来源类型，未配置source_type时使用服务器标签
+func (m *Manager) SourceType(serverLabel string) model.KnowledgeSource {
+	if config, exists := m.GetServerConfig(serverLabel); exists && config.SourceType != "" {
+		return model.KnowledgeSource(config.SourceType)
+	}
+	return model.KnowledgeSource(serverLabel)
+}
+
+// ParseKnowledgeItems 将MCP输出解析为指定知识源的知识项
+func (m *Manager) ParseKnowledgeItems(output string, source model.KnowledgeSource) []model.KnowledgeItem {
+	return m.parseMCPResponseToKnowledgeItems(output, source)
+}
+
 // parseMCPResponseToKnowledgeItems 解析MCP响应为KnowledgeItem
 func (m *Manager) parseMCPResponseToKnowledgeItems(mcpOutput string, source model.KnowledgeSource) []model.KnowledgeItem {
 	var items []model.KnowledgeItem
 
+	// 工具调用结果先提取文本内容
+	mcpOutput = ToolResultText(mcpOutput)
+
 	// 尝试解析JSON响应
 	var response struct {
 		Results []struct {
@@ -232,15 +426,28 @@ func (m *Manager) GetEnabledServers() []string {
 	return enabledServers
 }
 
-// HealthCheck 健康检查
+// Close 结束与所有MCP服务器的会话
+func (m *Manager) Close(ctx context.Context) {
+	m.stopHealthMonitor()
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for serverLabel, client := range m.clients {
+		serverConfig := m.config.Servers[serverLabel]
+		if err := client.Close(ctx, serverConfig.ServerURL, serverConfig.Headers); err != nil {
+			m.logger.WithError(err).WithField("server", serverLabel).Warn("结束MCP会话失败")
+		}
+	}
+}
+
+// HealthCheck 立即检查全部服务器的连通性，结果同时计入熔断器
 func (m *Manager) HealthCheck(ctx context.Context) map[string]bool {
 	results := make(map[string]bool)
 
 	for serverLabel := range m.clients {
-		// 尝试列出工具来检查连接
-		_, err := m.ListTools(ctx, serverLabel)
-		results[serverLabel] = err == nil
+		results[serverLabel] = m.ping(ctx, serverLabel) == nil
 	}
 
 	return results
-} 
\ No newline at end of file
+}
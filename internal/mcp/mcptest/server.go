@@ -0,0 +1,394 @@
+// Package mcptest 提供测试用的MCP服务器，实现Streamable HTTP传输
+//
+// 服务器默认处理initialize、ping、tools/list和tools/call，其他方法通过Handle注册。
+// 处理函数可以在响应之前发送通知（POST响应改为SSE流），也可以通过Notify
+// 在客户端建立的GET SSE流上异步发送通知。
+package mcptest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+)
+
+// HandlerFunc 方法处理函数，返回值作为result；返回*mcp.RPCError时作为JSON-RPC错误
+type HandlerFunc func(request *Request) (interface{}, error)
+
+// ToolFunc 工具处理函数，返回文本结果；返回错误时结果标记为isError
+type ToolFunc func(request *Request, arguments map[string]interface{}) (string, error)
+
+// Request 服务器收到的JSON-RPC请求
+type Request struct {
+	Method string
+	Params json.RawMessage
+	Header http.Header
+
+	notifications []mcp.JSONRPCRequest
+}
+
+// Bind 解析请求参数
+func (r *Request) Bind(v interface{}) error {
+	if len(r.Params) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Params, v)
+}
+
+// Notify 在本次响应之前发送通知，响应改为SSE流
+func (r *Request) Notify(method string, params interface{}) {
+	r.notifications = append(r.notifications, notification(method, params))
+}
+
+// tool 已注册的工具
+type tool struct {
+	name        string
+	description string
+	handler     ToolFunc
+}
+
+// Server 测试用MCP服务器
+type Server struct {
+	*httptest.Server
+	mux *http.ServeMux
+
+	mutex        sync.Mutex
+	name         string
+	capabilities map[string]interface{}
+	tools        []tool
+	handlers     map[string]HandlerFunc
+	authorize    func(w http.ResponseWriter, r *http.Request) bool
+	failStatus   int
+	noStream     bool
+	calls        map[string]int
+	toolCalls    int
+	requests     int
+	sessions     int
+	streams      map[chan []byte]struct{}
+	done         chan struct{}
+}
+
+// NewServer 创建并启动测试用MCP服务器，MCP端点为除HandleHTTP注册路径外的全部路径，测试结束时关闭
+func NewServer(t testing.TB, name string) *Server {
+	server := &Server{
+		mux:          http.NewServeMux(),
+		name:         name,
+		capabilities: map[string]interface{}{"tools": map[string]interface{}{"listChanged": true}},
+		handlers:     make(map[string]HandlerFunc),
+		calls:        make(map[string]int),
+		streams:      make(map[chan []byte]struct{}),
+		done:         make(chan struct{}),
+	}
+	server.mux.HandleFunc("/", server.serveMCP)
+	server.Server = httptest.NewServer(server.mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Close 断开GET SSE流并关闭服务器
+func (s *Server) Close() {
+	s.mutex.Lock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.mutex.Unlock()
+	s.Server.Close()
+}
+
+// HandleHTTP 注册MCP端点之外的HTTP路径，例如OAuth元数据
+func (s *Server) HandleHTTP(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// SetCapability 设置initialize响应中的服务器能力
+func (s *Server) SetCapability(name string, value map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.capabilities[name] = value
+}
+
+// SetAuthorizer 设置请求鉴权函数，返回false时请求不再处理，鉴权函数负责写入响应
+func (s *Server) SetAuthorizer(authorize func(w http.ResponseWriter, r *http.Request) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.authorize = authorize
+}
+
+// SetFailing 设置故障状态，status非0时全部请求返回该状态码
+func (s *Server) SetFailing(status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failStatus = status
+}
+
+// DisableStream 不支持GET SSE流，GET请求返回405
+func (s *Server) DisableStream() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.noStream = true
+}
+
+// AddTool 注册工具，handler为nil时返回工具名；可以在处理请求期间调用
+func (s *Server) AddTool(name, description string, handler ToolFunc) {
+	if handler == nil {
+		handler = func(*Request, map[string]interface{}) (string, error) { return name, nil }
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tools = append(s.tools, tool{name: name, description: description, handler: handler})
+}
+
+// Handle 注册方法处理函数
+func (s *Server) Handle(method string, handler HandlerFunc) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers[method] = handler
+}
+
+// Calls 获取方法被调用的次数，工具调用按工具名统计
+func (s *Server) Calls(name string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls[name]
+}
+
+// ToolCalls 获取全部工具调用的次数
+func (s *Server) ToolCalls() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.toolCalls
+}
+
+// Requests 获取收到的POST请求数，包括故障时被拒绝的请求
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+// Streams 获取当前打开的GET SSE流数量
+func (s *Server) Streams() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.streams)
+}
+
+// Notify 在全部GET SSE流上发送通知
+func (s *Server) Notify(method string, params interface{}) {
+	data, _ := json.Marshal(notification(method, params))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for stream := range s.streams {
+		select {
+		case stream <- data:
+		default:
+		}
+	}
+}
+
+// serveMCP 处理MCP端点请求
+func (s *Server) serveMCP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	authorize, failStatus := s.authorize, s.failStatus
+	if r.Method == http.MethodPost {
+		s.requests++
+	}
+	s.mutex.Unlock()
+
+	if failStatus != 0 {
+		w.WriteHeader(failStatus)
+		return
+	}
+	if authorize != nil && !authorize(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		s.servePost(w, r)
+	case http.MethodGet:
+		s.serveStream(w, r)
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// servePost 处理JSON-RPC消息
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request) {
+	var message mcp.JSONRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message.IsNotification() || message.Method == "" {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	request := &Request{Method: message.Method, Params: message.Params, Header: r.Header}
+	result, err := s.dispatch(w, request)
+
+	response := mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPCVersion, ID: message.ID, Result: result}
+	if err != nil {
+		var rpcErr *mcp.RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = mcp.NewRPCError(-32603, err.Error())
+		}
+		response.Result, response.Error = nil, rpcErr
+	} else if result == nil {
+		response.Result = map[string]interface{}{}
+	}
+
+	if len(request.notifications) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, notification := range request.notifications {
+		data, _ := json.Marshal(notification)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	data, _ := json.Marshal(response)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// dispatch 按方法处理请求
+func (s *Server) dispatch(w http.ResponseWriter, request *Request) (interface{}, error) {
+	s.mutex.Lock()
+	if request.Method != "tools/call" {
+		s.calls[request.Method]++
+	}
+	handler, exists := s.handlers[request.Method]
+	s.mutex.Unlock()
+	if exists {
+		return handler(request)
+	}
+
+	switch request.Method {
+	case "initialize":
+		return s.initialize(w), nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(request)
+	}
+	return nil, mcp.NewRPCError(-32601, "方法不存在: "+request.Method)
+}
+
+// initialize 创建会话并返回服务器能力
+func (s *Server) initialize(w http.ResponseWriter) mcp.InitializeResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions++
+	w.Header().Set(mcp.SessionHeader, fmt.Sprintf("%s-session-%d", s.name, s.sessions))
+
+	capabilities := make(map[string]interface{}, len(s.capabilities))
+	for name, value := range s.capabilities {
+		capabilities[name] = value
+	}
+	return mcp.InitializeResult{
+		ProtocolVersion: mcp.LatestProtocolVersion,
+		Capabilities:    capabilities,
+		ServerInfo:      mcp.Implementation{Name: s.name, Version: "test"},
+	}
+}
+
+// listTools 返回已注册的工具
+func (s *Server) listTools() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tools := make([]map[string]interface{}, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, map[string]interface{}{
+			"name":        tool.name,
+			"description": tool.description,
+			"inputSchema": map[string]interface{}{"type": "object"},
+		})
+	}
+	return map[string]interface{}{"tools": tools}
+}
+
+// callTool 调用已注册的工具，未注册的工具返回工具名
+func (s *Server) callTool(request *Request) (interface{}, error) {
+	var params mcp.CallToolParams
+	if err := request.Bind(&params); err != nil {
+		return nil, mcp.NewRPCError(-32602, err.Error())
+	}
+
+	s.mutex.Lock()
+	s.calls[params.Name]++
+	s.toolCalls++
+	handler := func(*Request, map[string]interface{}) (string, error) { return params.Name, nil }
+	for _, tool := range s.tools {
+		if tool.name == params.Name {
+			handler = tool.handler
+		}
+	}
+	s.mutex.Unlock()
+
+	text, err := handler(request, params.Arguments)
+	if err != nil {
+		return mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return mcp.CallToolResult{Content: []mcp.Content{{Type: "text", Text: text}}}, nil
+}
+
+// serveStream 打开GET SSE流，直到客户端断开或服务器关闭
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	if s.noStream {
+		s.mutex.Unlock()
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	stream := make(chan []byte, 16)
+	s.streams[stream] = struct{}{}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.streams, stream)
+		s.mutex.Unlock()
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case data := <-stream:
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// notification 构造JSON-RPC通知
+func notification(method string, params interface{}) mcp.JSONRPCRequest {
+	message := mcp.JSONRPCRequest{JSONRPC: mcp.JSONRPCVersion, Method: method}
+	if params != nil {
+		message.Params, _ = json.Marshal(params)
+	}
+	return message
+}
@@ -0,0 +1,858 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// OAuthGrantAuthorizationCode 授权码+PKCE流程，需要用户在浏览器中完成授权
+	OAuthGrantAuthorizationCode = "authorization_code"
+	// OAuthGrantClientCredentials 客户端凭据流程，无需用户参与
+	OAuthGrantClientCredentials = "client_credentials"
+	// DefaultOAuthRedirectURL 未配置redirect_url时的授权码回调地址
+	DefaultOAuthRedirectURL = "http://localhost:8080/api/v1/mcp/oauth/callback"
+	// PendingAuthorizationTTL 发起授权后等待回调的时间
+	PendingAuthorizationTTL = 10 * time.Minute
+
+	// tokenExpirySkew 令牌在到期前多久视为过期，提前刷新
+	tokenExpirySkew = 30 * time.Second
+	// maxOAuthResponseBytes 元数据和令牌响应的最大字节数
+	maxOAuthResponseBytes = 1 << 20
+)
+
+// AuthorizationRequiredError 服务器需要用户完成OAuth授权码授权
+type AuthorizationRequiredError struct {
+	Server string
+}
+
+func (e *AuthorizationRequiredError) Error() string {
+	return fmt.Sprintf("MCP服务器 %s 需要OAuth授权，请通过 /api/v1/mcp/oauth/%s/authorize 完成授权", e.Server, e.Server)
+}
+
+// unauthorizedError 服务器返回401，challenge为WWW-Authenticate响应头，token为被拒绝的访问令牌
+type unauthorizedError struct {
+	challenge string
+	token     string
+}
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("MCP服务器返回错误状态: %d 未授权", http.StatusUnauthorized)
+}
+
+// ProtectedResourceMetadata 受保护资源元数据（RFC 9728）
+type ProtectedResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
+}
+
+// AuthorizationServerMetadata 授权服务器元数据（RFC 8414）
+type AuthorizationServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	GrantTypesSupported           []string `json:"grant_types_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+// OAuthToken 访问令牌
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+}
+
+// valid 判断令牌是否可用，未返回有效期的令牌视为长期有效，直到服务器返回401
+func (t *OAuthToken) valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.ExpiresAt.IsZero() || time.Now().Add(tokenExpirySkew).Before(t.ExpiresAt)
+}
+
+// OAuthCredentials 服务器的OAuth客户端信息和令牌，动态注册得到的客户端信息与令牌一起保存
+type OAuthCredentials struct {
+	ClientID     string      `json:"client_id,omitempty"`
+	ClientSecret string      `json:"client_secret,omitempty"`
+	Token        *OAuthToken `json:"token,omitempty"`
+}
+
+// TokenStore OAuth凭据存储，按服务器标签保存
+type TokenStore interface {
+	// Load 读取服务器的凭据，没有保存过时返回nil
+	Load(server string) (*OAuthCredentials, error)
+	// Save 保存服务器的凭据
+	Save(server string, credentials *OAuthCredentials) error
+}
+
+// memoryTokenStore 内存凭据存储，进程重启后需要重新授权
+type memoryTokenStore struct {
+	credentials map[string]OAuthCredentials
+	mutex       sync.Mutex
+}
+
+// NewMemoryTokenStore 创建内存凭据存储
+func NewMemoryTokenStore() TokenStore {
+	return &memoryTokenStore{credentials: make(map[string]OAuthCredentials)}
+}
+
+// Load 读取服务器的凭据
+func (s *memoryTokenStore) Load(server string) (*OAuthCredentials, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	credentials, exists := s.credentials[server]
+	if !exists {
+		return nil, nil
+	}
+	return copyCredentials(&credentials), nil
+}
+
+// Save 保存服务器的凭据
+func (s *memoryTokenStore) Save(server string, credentials *OAuthCredentials) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.credentials[server] = *copyCredentials(credentials)
+	return nil
+}
+
+// fileTokenStore 文件凭据存储，全部服务器的凭据保存在一个JSON文件中，文件权限为0600
+type fileTokenStore struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFileTokenStore 创建文件凭据存储
+func NewFileTokenStore(path string) TokenStore {
+	return &fileTokenStore{path: path}
+}
+
+// Load 读取服务器的凭据
+func (s *fileTokenStore) Load(server string) (*OAuthCredentials, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return all[server], nil
+}
+
+// Save 保存服务器的凭据
+func (s *fileTokenStore) Save(server string, credentials *OAuthCredentials) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+	all[server] = credentials
+
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化OAuth凭据失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("创建OAuth凭据目录失败: %w", err)
+	}
+	// 先写临时文件再重命名，避免写入中断时损坏已有凭据
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("写入OAuth凭据失败: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// read 读取全部凭据，文件不存在时返回空集合
+func (s *fileTokenStore) read() (map[string]*OAuthCredentials, error) {
+	all := make(map[string]*OAuthCredentials)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取OAuth凭据失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("解析OAuth凭据失败: %w", err)
+	}
+	return all, nil
+}
+
+// copyCredentials 复制凭据，避免存储与调用方共享令牌
+func copyCredentials(credentials *OAuthCredentials) *OAuthCredentials {
+	copied := *credentials
+	if credentials.Token != nil {
+		token := *credentials.Token
+		copied.Token = &token
+	}
+	return &copied
+}
+
+// OAuthStatus 服务器的OAuth授权状态
+type OAuthStatus struct {
+	Server     string     `json:"server"`
+	GrantType  string     `json:"grant_type"`
+	ClientID   string     `json:"client_id,omitempty"`
+	Authorized bool       `json:"authorized"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Scope      string     `json:"scope,omitempty"`
+}
+
+// pendingAuthorization 等待回调的授权请求
+type pendingAuthorization struct {
+	verifier  string
+	createdAt time.Time
+}
+
+// OAuthClient 远程MCP服务器的OAuth 2.1客户端
+// 首次请求不带令牌，服务器返回401后从WWW-Authenticate中的resource_metadata（或well-known地址）发现授权服务器；
+// 未配置client_id时通过动态客户端注册获取。client_credentials流程自动获取令牌，
+// authorization_code流程需要用户访问AuthorizationURL完成授权。令牌过期或被拒绝时优先使用refresh_token刷新
+type OAuthClient struct {
+	server     string
+	resource   string
+	config     model.MCPOAuthConfig
+	store      TokenStore
+	httpClient *http.Client
+	logger     *logrus.Logger
+
+	mutex               sync.Mutex
+	credentials         *OAuthCredentials
+	metadata            *AuthorizationServerMetadata
+	resourceMetadataURL string
+	pending             map[string]*pendingAuthorization
+}
+
+// NewOAuthClient 创建OAuth客户端，resource为MCP服务器地址，作为令牌请求的resource参数（RFC 8707）
+func NewOAuthClient(server, resource string, config model.MCPOAuthConfig, store TokenStore, timeout time.Duration) *OAuthClient {
+	if config.GrantType == "" {
+		config.GrantType = OAuthGrantAuthorizationCode
+	}
+	if config.RedirectURL == "" {
+		config.RedirectURL = DefaultOAuthRedirectURL
+	}
+	return &OAuthClient{
+		server:     server,
+		resource:   resource,
+		config:     config,
+		store:      store,
+		httpClient: &http.Client{Timeout: timeout},
+		logger:     logrus.New(),
+		pending:    make(map[string]*pendingAuthorization),
+	}
+}
+
+// accessToken 获取当前访问令牌，过期且有refresh_token时先刷新；没有可用令牌时返回空字符串，
+// 请求不带令牌发送，由服务器返回的401触发获取令牌
+func (o *OAuthClient) accessToken(ctx context.Context) string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	credentials := o.load()
+	if credentials.Token.valid() {
+		return credentials.Token.AccessToken
+	}
+	if credentials.Token == nil || credentials.Token.RefreshToken == "" {
+		return ""
+	}
+	if err := o.refresh(ctx); err != nil {
+		o.logger.WithError(err).WithField("server", o.server).Warn("刷新OAuth令牌失败")
+		return ""
+	}
+	return o.credentials.Token.AccessToken
+}
+
+// handleUnauthorized 处理服务器返回的401：其他请求已更新令牌时直接重试；
+// 否则依次尝试刷新令牌、客户端凭据流程，授权码流程需要用户重新授权时返回*AuthorizationRequiredError
+func (o *OAuthClient) handleUnauthorized(ctx context.Context, challenge, rejected string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	params := parseChallenge(challenge)
+	if metadataURL := params["resource_metadata"]; metadataURL != "" && metadataURL != o.resourceMetadataURL {
+		o.resourceMetadataURL = metadataURL
+		o.metadata = nil
+	}
+
+	credentials := o.load()
+	if credentials.Token.valid() && credentials.Token.AccessToken != rejected {
+		return nil
+	}
+
+	if credentials.Token != nil && credentials.Token.RefreshToken != "" {
+		err := o.refresh(ctx)
+		if err == nil {
+			return nil
+		}
+		o.logger.WithError(err).WithField("server", o.server).Warn("刷新OAuth令牌失败，重新获取令牌")
+	}
+	o.credentials.Token = nil
+	o.save()
+
+	if o.config.GrantType == OAuthGrantClientCredentials {
+		return o.clientCredentials(ctx)
+	}
+	return &AuthorizationRequiredError{Server: o.server}
+}
+
+// AuthorizationURL 生成授权码流程的授权地址，用户访问该地址完成授权后授权服务器回调redirect_url
+func (o *OAuthClient) AuthorizationURL(ctx context.Context) (string, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.config.GrantType != OAuthGrantAuthorizationCode {
+		return "", fmt.Errorf("MCP服务器 %s 使用%s流程，无需用户授权", o.server, o.config.GrantType)
+	}
+
+	metadata, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	if metadata.AuthorizationEndpoint == "" {
+		return "", fmt.Errorf("授权服务器未提供authorization_endpoint")
+	}
+	// OAuth 2.1要求PKCE，授权服务器声明了支持的方法但不包含S256时拒绝授权
+	if len(metadata.CodeChallengeMethodsSupported) > 0 && !containsString(metadata.CodeChallengeMethodsSupported, "S256") {
+		return "", fmt.Errorf("授权服务器不支持S256 PKCE")
+	}
+	if err := o.register(ctx, metadata); err != nil {
+		return "", err
+	}
+
+	verifier, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	state, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	for key, pending := range o.pending {
+		if time.Since(pending.createdAt) > PendingAuthorizationTTL {
+			delete(o.pending, key)
+		}
+	}
+	o.pending[state] = &pendingAuthorization{verifier: verifier, createdAt: time.Now()}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.credentials.ClientID},
+		"redirect_uri":          {o.config.RedirectURL},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"resource":              {o.resource},
+	}
+	if len(o.config.Scopes) > 0 {
+		query.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// hasPending 判断state是否属于本客户端发起的授权
+func (o *OAuthClient) hasPending(state string) bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	_, exists := o.pending[state]
+	return exists
+}
+
+// Exchange 用授权回调中的code换取令牌
+func (o *OAuthClient) Exchange(ctx context.Context, state, code string) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	pending, exists := o.pending[state]
+	if !exists {
+		return fmt.Errorf("授权请求不存在或已使用")
+	}
+	delete(o.pending, state)
+	if time.Since(pending.createdAt) > PendingAuthorizationTTL {
+		return fmt.Errorf("授权请求已过期，请重新发起授权")
+	}
+
+	metadata, err := o.discover(ctx)
+	if err != nil {
+		return err
+	}
+	o.load()
+	token, err := o.tokenRequest(ctx, metadata, url.Values{
+		"grant_type":    {OAuthGrantAuthorizationCode},
+		"code":          {code},
+		"redirect_uri":  {o.config.RedirectURL},
+		"code_verifier": {pending.verifier},
+	})
+	if err != nil {
+		return err
+	}
+	o.credentials.Token = token
+	o.save()
+
+	o.logger.WithField("server", o.server).Info("MCP服务器OAuth授权完成")
+	return nil
+}
+
+// Status 获取授权状态
+func (o *OAuthClient) Status() OAuthStatus {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	credentials := o.load()
+	status := OAuthStatus{
+		Server:     o.server,
+		GrantType:  o.config.GrantType,
+		ClientID:   credentials.ClientID,
+		Authorized: credentials.Token.valid() || (credentials.Token != nil && credentials.Token.RefreshToken != ""),
+	}
+	if credentials.Token != nil {
+		status.Scope = credentials.Token.Scope
+		if !credentials.Token.ExpiresAt.IsZero() {
+			expiresAt := credentials.Token.ExpiresAt
+			status.ExpiresAt = &expiresAt
+		}
+	}
+	return status
+}
+
+// load 从存储中读取凭据，配置了client_id时使用配置的客户端信息；调用方需持有mutex
+func (o *OAuthClient) load() *OAuthCredentials {
+	if o.credentials != nil {
+		return o.credentials
+	}
+
+	credentials, err := o.store.Load(o.server)
+	if err != nil {
+		o.logger.WithError(err).WithField("server", o.server).Warn("读取OAuth凭据失败")
+	}
+	if credentials == nil {
+		credentials = &OAuthCredentials{}
+	}
+	if o.config.ClientID != "" {
+		if credentials.ClientID != o.config.ClientID {
+			credentials.Token = nil
+		}
+		credentials.ClientID = o.config.ClientID
+		credentials.ClientSecret = o.config.ClientSecret
+	}
+	o.credentials = credentials
+	return credentials
+}
+
+// save 保存凭据，保存失败只记录日志，令牌仍在内存中可用；调用方需持有mutex
+func (o *OAuthClient) save() {
+	if err := o.store.Save(o.server, o.credentials); err != nil {
+		o.logger.WithError(err).WithField("server", o.server).Warn("保存OAuth凭据失败")
+	}
+}
+
+// clientCredentials 通过客户端凭据流程获取令牌；调用方需持有mutex
+func (o *OAuthClient) clientCredentials(ctx context.Context) error {
+	metadata, err := o.discover(ctx)
+	if err != nil {
+		return err
+	}
+	if err := o.register(ctx, metadata); err != nil {
+		return err
+	}
+
+	form := url.Values{"grant_type": {OAuthGrantClientCredentials}}
+	if len(o.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+	token, err := o.tokenRequest(ctx, metadata, form)
+	if err != nil {
+		return err
+	}
+	o.credentials.Token = token
+	o.save()
+
+	o.logger.WithField("server", o.server).Info("已通过客户端凭据获取MCP服务器OAuth令牌")
+	return nil
+}
+
+// refresh 使用refresh_token刷新令牌，授权服务器未返回新的refresh_token时沿用旧的；调用方需持有mutex
+func (o *OAuthClient) refresh(ctx context.Context) error {
+	metadata, err := o.discover(ctx)
+	if err != nil {
+		return err
+	}
+
+	refreshToken := o.credentials.Token.RefreshToken
+	token, err := o.tokenRequest(ctx, metadata, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	o.credentials.Token = token
+	o.save()
+
+	o.logger.WithField("server", o.server).Debug("MCP服务器OAuth令牌已刷新")
+	return nil
+}
+
+// register 未配置client_id时通过动态客户端注册（RFC 7591）获取客户端信息；调用方需持有mutex
+func (o *OAuthClient) register(ctx context.Context, metadata *AuthorizationServerMetadata) error {
+	if o.load().ClientID != "" {
+		return nil
+	}
+	if metadata.RegistrationEndpoint == "" {
+		return fmt.Errorf("MCP服务器 %s 未配置client_id，且授权服务器不支持动态客户端注册", o.server)
+	}
+
+	registration := map[string]interface{}{
+		"client_name": DefaultClientName,
+	}
+	if o.config.GrantType == OAuthGrantClientCredentials {
+		registration["grant_types"] = []string{OAuthGrantClientCredentials}
+		registration["token_endpoint_auth_method"] = "client_secret_basic"
+	} else {
+		registration["grant_types"] = []string{OAuthGrantAuthorizationCode, "refresh_token"}
+		registration["response_types"] = []string{"code"}
+		registration["redirect_uris"] = []string{o.config.RedirectURL}
+		registration["token_endpoint_auth_method"] = "none"
+	}
+	if len(o.config.Scopes) > 0 {
+		registration["scope"] = strings.Join(o.config.Scopes, " ")
+	}
+
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("序列化客户端注册请求失败: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.RegistrationEndpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	var registered struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	if err := o.doJSON(httpReq, &registered); err != nil {
+		return fmt.Errorf("动态客户端注册失败: %w", err)
+	}
+	if registered.ClientID == "" {
+		return fmt.Errorf("动态客户端注册失败: 响应中缺少client_id")
+	}
+
+	o.credentials.ClientID = registered.ClientID
+	o.credentials.ClientSecret = registered.ClientSecret
+	o.credentials.Token = nil
+	o.save()
+
+	o.logger.WithFields(logrus.Fields{
+		"server":    o.server,
+		"client_id": registered.ClientID,
+	}).Info("已完成OAuth动态客户端注册")
+	return nil
+}
+
+// tokenRequest 向令牌端点发送请求，有client_secret时使用client_secret_basic认证，否则作为公共客户端发送client_id
+func (o *OAuthClient) tokenRequest(ctx context.Context, metadata *AuthorizationServerMetadata, form url.Values) (*OAuthToken, error) {
+	if metadata.TokenEndpoint == "" {
+		return nil, fmt.Errorf("授权服务器未提供token_endpoint")
+	}
+	form.Set("resource", o.resource)
+	if o.credentials.ClientSecret == "" {
+		form.Set("client_id", o.credentials.ClientID)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if o.credentials.ClientSecret != "" {
+		httpReq.SetBasicAuth(url.QueryEscape(o.credentials.ClientID), url.QueryEscape(o.credentials.ClientSecret))
+	}
+
+	var response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+	if err := o.doJSON(httpReq, &response); err != nil {
+		return nil, fmt.Errorf("获取OAuth令牌失败: %w", err)
+	}
+	if response.AccessToken == "" {
+		return nil, fmt.Errorf("获取OAuth令牌失败: 响应中缺少access_token")
+	}
+
+	token := &OAuthToken{
+		AccessToken:  response.AccessToken,
+		TokenType:    response.TokenType,
+		RefreshToken: response.RefreshToken,
+		Scope:        response.Scope,
+	}
+	if response.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// discover 发现授权服务器元数据：优先使用配置的authorization_server，否则从受保护资源元数据中获取；
+// 元数据中的issuer必须与授权服务器地址一致（RFC 8414 §3.3），授权服务器不提供元数据时使用默认端点（/authorize、/token、/register）。
+// 配置了client_secret时必须同时配置authorization_server，避免把密钥发送给MCP服务器指定的授权服务器；调用方需持有mutex
+func (o *OAuthClient) discover(ctx context.Context) (*AuthorizationServerMetadata, error) {
+	if o.metadata != nil {
+		return o.metadata, nil
+	}
+
+	issuer := o.config.AuthorizationServer
+	if issuer == "" && o.config.ClientSecret != "" {
+		return nil, fmt.Errorf("MCP服务器 %s 配置了client_secret，必须同时配置authorization_server", o.server)
+	}
+	if issuer == "" {
+		resourceMetadata, err := o.protectedResourceMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(resourceMetadata.AuthorizationServers) == 0 {
+			return nil, fmt.Errorf("受保护资源元数据中没有授权服务器")
+		}
+		issuer = resourceMetadata.AuthorizationServers[0]
+	}
+
+	var metadata AuthorizationServerMetadata
+	found := false
+	candidates := append(wellKnownURLs(issuer, "oauth-authorization-server"), wellKnownURLs(issuer, "openid-configuration")...)
+	for _, candidate := range candidates {
+		var document AuthorizationServerMetadata
+		if err := o.getJSON(ctx, candidate, &document); err != nil || document.TokenEndpoint == "" {
+			continue
+		}
+		if !sameURL(document.Issuer, issuer) {
+			return nil, fmt.Errorf("授权服务器元数据的issuer %q 与授权服务器地址 %q 不一致", document.Issuer, issuer)
+		}
+		metadata, found = document, true
+		break
+	}
+	if !found {
+		base := strings.TrimSuffix(issuer, "/")
+		metadata = AuthorizationServerMetadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: base + "/authorize",
+			TokenEndpoint:         base + "/token",
+			RegistrationEndpoint:  base + "/register",
+		}
+		o.logger.WithField("issuer", issuer).Debug("授权服务器未提供元数据，使用默认端点")
+	}
+
+	o.metadata = &metadata
+	return o.metadata, nil
+}
+
+// protectedResourceMetadata 获取MCP服务器的受保护资源元数据，优先使用401响应中的resource_metadata地址
+// 元数据中的resource必须与MCP服务器地址一致（RFC 9728 §3.3），不一致的元数据不使用
+func (o *OAuthClient) protectedResourceMetadata(ctx context.Context) (*ProtectedResourceMetadata, error) {
+	var candidates []string
+	if o.resourceMetadataURL != "" {
+		candidates = append(candidates, o.resourceMetadataURL)
+	}
+	candidates = append(candidates, wellKnownURLs(o.resource, "oauth-protected-resource")...)
+
+	// 返回第一个地址的错误，后面的地址只是备选
+	var firstErr error
+	for _, candidate := range candidates {
+		var metadata ProtectedResourceMetadata
+		err := o.getJSON(ctx, candidate, &metadata)
+		if err == nil && !sameURL(metadata.Resource, o.resource) {
+			err = fmt.Errorf("resource %q 与MCP服务器地址 %q 不一致", metadata.Resource, o.resource)
+		}
+		if err == nil {
+			return &metadata, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, fmt.Errorf("获取受保护资源元数据失败: %w", firstErr)
+}
+
+// getJSON 获取并解析JSON文档
+func (o *OAuthClient) getJSON(ctx context.Context, target string, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set(ProtocolVersionHeader, LatestProtocolVersion)
+	return o.doJSON(httpReq, out)
+}
+
+// doJSON 发送请求并解析JSON响应，错误响应中的error和error_description作为错误信息
+func (o *OAuthClient) doJSON(httpReq *http.Request, out interface{}) error {
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOAuthResponseBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error != "" {
+			return fmt.Errorf("%d %s %s", resp.StatusCode, oauthErr.Error, oauthErr.ErrorDescription)
+		}
+		return fmt.Errorf("%d %s", resp.StatusCode, truncateBody(body))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// wellKnownURLs 生成well-known地址，带路径的地址先尝试在well-known后拼接路径，再尝试根路径
+func wellKnownURLs(base, suffix string) []string {
+	parsed, err := url.Parse(base)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+	path := strings.TrimSuffix(parsed.Path, "/")
+	if path == "" {
+		return []string{origin + "/.well-known/" + suffix}
+	}
+	return []string{origin + "/.well-known/" + suffix + path, origin + "/.well-known/" + suffix}
+}
+
+// sameURL 判断两个地址是否相同，忽略末尾的斜杠
+func sameURL(a, b string) bool {
+	return a != "" && strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
+
+// parseChallenge 解析WWW-Authenticate中Bearer认证的参数
+func parseChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return params
+	}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = strings.TrimPrefix(strings.TrimSpace(value[end+2:]), ",")
+			continue
+		}
+		value, rest, _ = strings.Cut(value, ",")
+		params[key] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+// randomToken 生成用于state和code_verifier的随机字符串
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// truncateBody 截断错误响应内容
+func truncateBody(body []byte) string {
+	if len(body) > maxErrorBodyBytes {
+		body = body[:maxErrorBodyBytes]
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// containsString 判断切片是否包含字符串
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthClient 获取服务器的OAuth客户端，未启用OAuth时返回false
+func (m *Manager) OAuthClient(serverLabel string) (*OAuthClient, bool) {
+	client, exists := m.oauth[serverLabel]
+	return client, exists
+}
+
+// OAuthStatus 获取全部启用OAuth的服务器的授权状态，按服务器标签排序
+func (m *Manager) OAuthStatus() []OAuthStatus {
+	statuses := make([]OAuthStatus, 0, len(m.oauth))
+	for _, client := range m.oauth {
+		statuses = append(statuses, client.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Server < statuses[j].Server
+	})
+	return statuses
+}
+
+// CompleteAuthorization 处理授权回调，按state找到发起授权的服务器并换取令牌，返回服务器标签
+func (m *Manager) CompleteAuthorization(ctx context.Context, state, code string) (string, error) {
+	for serverLabel, client := range m.oauth {
+		if !client.hasPending(state) {
+			continue
+		}
+		if err := client.Exchange(ctx, state, code); err != nil {
+			return serverLabel, err
+		}
+		// 授权前的失败不应让服务器继续处于熔断状态
+		if breaker := m.breakers[serverLabel]; breaker != nil {
+			breaker.reset()
+		}
+		return serverLabel, nil
+	}
+	return "", fmt.Errorf("授权请求不存在或已过期")
+}
@@ -0,0 +1,441 @@
+package mcp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authorizationServer 模拟OAuth 2.1授权服务器，支持动态客户端注册、授权码+PKCE、客户端凭据和刷新令牌
+type authorizationServer struct {
+	*httptest.Server
+	t        *testing.T
+	resource string
+	issuer   string // 元数据中的issuer，为空时使用服务器地址
+
+	mutex         sync.Mutex
+	clients       map[string]string // client_id -> client_secret
+	codes         map[string]string // code -> code_challenge
+	refreshTokens map[string]bool
+	accessTokens  map[string]bool
+	issued        int
+	expiresIn     int
+	registrations int
+	tokenRequests map[string]int
+}
+
+// newAuthorizationServer 创建模拟授权服务器，resource为受保护的MCP服务器地址
+func newAuthorizationServer(t *testing.T) *authorizationServer {
+	server := &authorizationServer{
+		t:             t,
+		clients:       make(map[string]string),
+		codes:         make(map[string]string),
+		refreshTokens: make(map[string]bool),
+		accessTokens:  make(map[string]bool),
+		tokenRequests: make(map[string]int),
+		expiresIn:     3600,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		issuer := server.URL
+		if server.issuer != "" {
+			issuer = server.issuer
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                           issuer,
+			"authorization_endpoint":           server.URL + "/authorize",
+			"token_endpoint":                   server.URL + "/token",
+			"registration_endpoint":            server.URL + "/register",
+			"code_challenge_methods_supported": []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/register", func(w http.ResponseWriter, r *http.Request) {
+		var registration struct {
+			GrantTypes              []string `json:"grant_types"`
+			TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&registration))
+
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.registrations++
+		clientID := fmt.Sprintf("client-%d", server.registrations)
+		response := map[string]interface{}{"client_id": clientID}
+		if registration.TokenEndpointAuthMethod == "client_secret_basic" {
+			server.clients[clientID] = clientID + "-secret"
+			response["client_secret"] = clientID + "-secret"
+		} else {
+			server.clients[clientID] = ""
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(response)
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		assert.Equal(t, server.resource, query.Get("resource"))
+
+		server.mutex.Lock()
+		server.issued++
+		code := fmt.Sprintf("code-%d", server.issued)
+		server.codes[code] = query.Get("code_challenge")
+		server.mutex.Unlock()
+
+		redirect := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+		http.Redirect(w, r, redirect, http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		grantType := r.PostForm.Get("grant_type")
+		server.tokenRequests[grantType]++
+		if r.PostForm.Get("resource") != server.resource {
+			server.tokenError(w, "invalid_target")
+			return
+		}
+
+		refresh := true
+		switch grantType {
+		case "client_credentials":
+			clientID, secret, ok := r.BasicAuth()
+			if !ok || server.clients[clientID] == "" || server.clients[clientID] != secret {
+				server.tokenError(w, "invalid_client")
+				return
+			}
+			refresh = false
+		case "authorization_code":
+			challenge, exists := server.codes[r.PostForm.Get("code")]
+			verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if !exists || base64.RawURLEncoding.EncodeToString(verifier[:]) != challenge {
+				server.tokenError(w, "invalid_grant")
+				return
+			}
+			delete(server.codes, r.PostForm.Get("code"))
+		case "refresh_token":
+			if !server.refreshTokens[r.PostForm.Get("refresh_token")] {
+				server.tokenError(w, "invalid_grant")
+				return
+			}
+			delete(server.refreshTokens, r.PostForm.Get("refresh_token"))
+		default:
+			server.tokenError(w, "unsupported_grant_type")
+			return
+		}
+
+		server.issued++
+		response := map[string]interface{}{
+			"access_token": fmt.Sprintf("access-%d", server.issued),
+			"token_type":   "Bearer",
+			"expires_in":   server.expiresIn,
+		}
+		server.accessTokens[response["access_token"].(string)] = true
+		if refresh {
+			refreshToken := fmt.Sprintf("refresh-%d", server.issued)
+			server.refreshTokens[refreshToken] = true
+			response["refresh_token"] = refreshToken
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+
+	server.Server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// tokenError 返回令牌端点错误
+func (s *authorizationServer) tokenError(w http.ResponseWriter, code string) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+// validToken 判断访问令牌是否有效
+func (s *authorizationServer) validToken(token string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.accessTokens[token]
+}
+
+// revoke 使全部访问令牌失效，模拟令牌在服务端过期
+func (s *authorizationServer) revoke() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accessTokens = make(map[string]bool)
+}
+
+// setExpiresIn 设置之后签发的令牌有效期
+func (s *authorizationServer) setExpiresIn(seconds int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expiresIn = seconds
+}
+
+// counts 获取动态注册次数和各授权类型的令牌请求次数
+func (s *authorizationServer) counts() (int, map[string]int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	requests := make(map[string]int, len(s.tokenRequests))
+	for grantType, count := range s.tokenRequests {
+		requests[grantType] = count
+	}
+	return s.registrations, requests
+}
+
+// protectedServer 模拟需要OAuth令牌的MCP服务器
+type protectedServer struct {
+	*mcptest.Server
+	mutex         sync.Mutex
+	unauthorized  int
+	apiKeyHeaders []string
+	resource      string // 受保护资源元数据中的resource，为空时使用MCP端点地址
+}
+
+// newProtectedServer 创建受保护的MCP服务器，MCP端点为/mcp，受保护资源元数据指向授权服务器
+// search工具返回请求使用的访问令牌
+func newProtectedServer(t *testing.T, authServer *authorizationServer) *protectedServer {
+	server := &protectedServer{Server: mcptest.NewServer(t, "protected")}
+	server.HandleHTTP("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		resource := server.URL + "/mcp"
+		if server.resource != "" {
+			resource = server.resource
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"resource":              resource,
+			"authorization_servers": []string{authServer.URL},
+		})
+	})
+	server.SetAuthorizer(func(w http.ResponseWriter, r *http.Request) bool {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		server.apiKeyHeaders = append(server.apiKeyHeaders, r.Header.Get("X-Api-Key"))
+
+		token, ok := bearerToken(r.Header)
+		if !ok || !authServer.validToken(token) {
+			server.unauthorized++
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="mcp", resource_metadata="%s/.well-known/oauth-protected-resource/mcp"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	})
+	server.AddTool("search", "", func(request *mcptest.Request, _ map[string]interface{}) (string, error) {
+		token, _ := bearerToken(request.Header)
+		return "issues for " + token, nil
+	})
+	authServer.resource = server.URL + "/mcp"
+	return server
+}
+
+// unauthorizedCount 获取返回401的次数
+func (s *protectedServer) unauthorizedCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.unauthorized
+}
+
+// bearerToken 读取请求头中的Bearer令牌
+func bearerToken(headers http.Header) (string, bool) {
+	const prefix = "Bearer "
+	header := headers.Get("Authorization")
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		return "", false
+	}
+	return header[len(prefix):], true
+}
+
+// TestManagerOAuthClientCredentials 测试元数据发现、动态客户端注册、客户端凭据流程、401后重新获取令牌以及请求头环境变量展开
+func TestManagerOAuthClientCredentials(t *testing.T) {
+	t.Setenv("TEST_MCP_API_KEY", "key-from-env")
+	authServer := newAuthorizationServer(t)
+	server := newProtectedServer(t, authServer)
+
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"tracker": {
+				Enabled:   true,
+				ServerURL: server.URL + "/mcp",
+				Headers:   map[string]string{"X-Api-Key": "${TEST_MCP_API_KEY}"},
+				OAuth:     model.MCPOAuthConfig{Enabled: true, GrantType: mcp.OAuthGrantClientCredentials, Scopes: []string{"read"}},
+			},
+		},
+		HealthCheck: model.MCPHealthConfig{Interval: "0"},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+	ctx := context.Background()
+
+	response, err := manager.CallTool(ctx, "tracker", "search", nil)
+	require.NoError(t, err)
+	assert.Contains(t, mcp.ToolResultText(response.Output), "access-1")
+	registrations, requests := authServer.counts()
+	assert.Equal(t, 1, registrations)
+	assert.Equal(t, 1, requests["client_credentials"])
+	assert.Equal(t, 1, server.unauthorizedCount())
+
+	// 令牌在服务端失效后重新获取并重试
+	authServer.revoke()
+	response, err = manager.CallTool(ctx, "tracker", "search", nil)
+	require.NoError(t, err)
+	assert.Contains(t, mcp.ToolResultText(response.Output), "access-2")
+	registrations, requests = authServer.counts()
+	assert.Equal(t, 1, registrations)
+	assert.Equal(t, 2, requests["client_credentials"])
+
+	status := manager.OAuthStatus()
+	require.Len(t, status, 1)
+	assert.True(t, status[0].Authorized)
+	assert.Equal(t, "client-1", status[0].ClientID)
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	for _, header := range server.apiKeyHeaders {
+		assert.Equal(t, "key-from-env", header)
+	}
+}
+
+// TestManagerOAuthAuthorizationCode 测试授权码+PKCE流程、令牌刷新以及令牌持久化
+func TestManagerOAuthAuthorizationCode(t *testing.T) {
+	authServer := newAuthorizationServer(t)
+	server := newProtectedServer(t, authServer)
+	tokenFile := filepath.Join(t.TempDir(), "oauth.json")
+
+	config := &model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"tracker": {
+				Enabled:   true,
+				ServerURL: server.URL + "/mcp",
+				OAuth:     model.MCPOAuthConfig{Enabled: true, RedirectURL: "http://localhost:8080/api/v1/mcp/oauth/callback"},
+			},
+		},
+		HealthCheck:    model.MCPHealthConfig{Interval: "0"},
+		OAuthTokenFile: tokenFile,
+	}
+	manager := mcp.NewManager(config)
+	t.Cleanup(func() { manager.Close(context.Background()) })
+	ctx := context.Background()
+
+	// 授权前调用返回需要授权
+	_, err := manager.CallTool(ctx, "tracker", "search", nil)
+	var authErr *mcp.AuthorizationRequiredError
+	require.True(t, errors.As(err, &authErr))
+	assert.Equal(t, "tracker", authErr.Server)
+
+	// 用户访问授权地址，授权服务器回调时用code换取令牌
+	oauth, exists := manager.OAuthClient("tracker")
+	require.True(t, exists)
+	authorizationURL, err := oauth.AuthorizationURL(ctx)
+	require.NoError(t, err)
+
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := noRedirect.Get(authorizationURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+
+	authServer.setExpiresIn(10)
+	serverLabel, err := manager.CompleteAuthorization(ctx, callback.Query().Get("state"), callback.Query().Get("code"))
+	require.NoError(t, err)
+	assert.Equal(t, "tracker", serverLabel)
+	_, err = manager.CompleteAuthorization(ctx, callback.Query().Get("state"), callback.Query().Get("code"))
+	assert.Error(t, err)
+
+	// 令牌即将过期时请求前先刷新
+	authServer.setExpiresIn(3600)
+	unauthorized := server.unauthorizedCount()
+	response, err := manager.CallTool(ctx, "tracker", "search", nil)
+	require.NoError(t, err)
+	assert.Contains(t, mcp.ToolResultText(response.Output), "access-3")
+	assert.Equal(t, unauthorized, server.unauthorizedCount())
+
+	// 令牌被服务器拒绝时刷新后重试
+	authServer.revoke()
+	response, err = manager.CallTool(ctx, "tracker", "search", nil)
+	require.NoError(t, err)
+	assert.Contains(t, mcp.ToolResultText(response.Output), "access-4")
+	registrations, requests := authServer.counts()
+	assert.Equal(t, 1, registrations)
+	assert.Equal(t, 1, requests["authorization_code"])
+	assert.Equal(t, 2, requests["refresh_token"])
+
+	// 重启后从文件读取令牌，无需重新授权
+	restarted := mcp.NewManager(config)
+	t.Cleanup(func() { restarted.Close(context.Background()) })
+	response, err = restarted.CallTool(ctx, "tracker", "search", nil)
+	require.NoError(t, err)
+	assert.Contains(t, mcp.ToolResultText(response.Output), "access-4")
+	assert.True(t, restarted.OAuthStatus()[0].Authorized)
+}
+
+// TestManagerOAuthMetadataValidation 测试不使用resource或issuer不一致的元数据，以及配置了client_secret时不使用发现的授权服务器
+func TestManagerOAuthMetadataValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(authServer *authorizationServer, server *protectedServer)
+		oauth     model.MCPOAuthConfig
+		message   string
+	}{
+		{
+			name: "ResourceMismatch",
+			configure: func(_ *authorizationServer, server *protectedServer) {
+				server.resource = "https://other.example.com/mcp"
+			},
+			oauth:   model.MCPOAuthConfig{Enabled: true, GrantType: mcp.OAuthGrantClientCredentials},
+			message: "resource",
+		},
+		{
+			name: "IssuerMismatch",
+			configure: func(authServer *authorizationServer, _ *protectedServer) {
+				authServer.issuer = "https://other.example.com"
+			},
+			oauth:   model.MCPOAuthConfig{Enabled: true, GrantType: mcp.OAuthGrantClientCredentials},
+			message: "issuer",
+		},
+		{
+			name:    "SecretWithDiscoveredServer",
+			oauth:   model.MCPOAuthConfig{Enabled: true, GrantType: mcp.OAuthGrantClientCredentials, ClientID: "configured", ClientSecret: "secret"},
+			message: "authorization_server",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authServer := newAuthorizationServer(t)
+			server := newProtectedServer(t, authServer)
+			if tt.configure != nil {
+				tt.configure(authServer, server)
+			}
+			manager := mcp.NewManager(&model.MCPConfig{
+				Servers: map[string]model.MCPServer{
+					"tracker": {Enabled: true, ServerURL: server.URL + "/mcp", OAuth: tt.oauth},
+				},
+				HealthCheck: model.MCPHealthConfig{Interval: "0"},
+			})
+			t.Cleanup(func() { manager.Close(context.Background()) })
+
+			_, err := manager.CallTool(context.Background(), "tracker", "search", nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+			registrations, requests := authServer.counts()
+			assert.Zero(t, registrations)
+			assert.Empty(t, requests)
+		})
+	}
+}
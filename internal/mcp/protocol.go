@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSON-RPC 2.0 版本号
+const JSONRPCVersion = "2.0"
+
+// LatestProtocolVersion 当前支持的最新MCP协议版本
+const LatestProtocolVersion = "2025-06-18"
+
+// SupportedProtocolVersions 支持的MCP协议版本（按新旧排序）
+var SupportedProtocolVersions = []string{
+	"2025-06-18",
+	"2025-03-26",
+	"2024-11-05",
+}
+
+// JSON-RPC 标准错误码
+const (
+	ErrCodeParseError     = -32700 // 解析错误
+	ErrCodeInvalidRequest = -32600 // 无效请求
+	ErrCodeMethodNotFound = -32601 // 方法不存在
+	ErrCodeInvalidParams  = -32602 // 无效参数
+	ErrCodeInternalError  = -32603 // 内部错误
+)
+
+// JSONRPCRequest JSON-RPC请求（ID为空时表示通知）
+type JSONRPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification 判断是否为通知消息
+func (r *JSONRPCRequest) IsNotification() bool {
+	return len(r.ID) == 0 || string(r.ID) == "null"
+}
+
+// JSONRPCResponse JSON-RPC响应
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError JSON-RPC错误对象
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error 实现error接口
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("JSON-RPC错误 %d: %s", e.Code, e.Message)
+}
+
+// NewRPCError 创建JSON-RPC错误
+func NewRPCError(code int, message string) *RPCError {
+	return &RPCError{Code: code, Message: message}
+}
+
+// Implementation 客户端/服务端实现信息
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// InitializeParams initialize请求参数
+type InitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// InitializeResult initialize响应结果
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      Implementation         `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+}
+
+// CallToolParams tools/call请求参数
+type CallToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// Content 工具返回的内容块
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// CallToolResult tools/call响应结果
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// NegotiateProtocolVersion 协商协议版本，客户端版本不受支持时返回最新版本
+func NegotiateProtocolVersion(requested string) string {
+	for _, version := range SupportedProtocolVersions {
+		if version == requested {
+			return version
+		}
+	}
+	return LatestProtocolVersion
+}
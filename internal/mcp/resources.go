@@ -0,0 +1,475 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+)
+
+// Resource 服务器提供的资源
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// ResourceTemplate 参数化资源模板（RFC 6570 URI模板）
+type ResourceTemplate struct {
+	URITemplate string `json:"uri_template"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+}
+
+// ResourceContents 资源内容，文本资源使用Text，二进制资源使用Base64编码的Blob
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mime_type,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// Prompt 服务器提供的提示模板
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument 提示模板参数
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage 提示模板展开后的消息
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// ListResourcesRequest 列出资源或资源模板请求
+type ListResourcesRequest struct {
+	ServerLabel string            `json:"server_label"`
+	ServerURL   string            `json:"server_url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// ListResourcesResponse 列出资源响应
+type ListResourcesResponse struct {
+	Resources []Resource `json:"resources"`
+}
+
+// ListResourceTemplatesResponse 列出资源模板响应
+type ListResourceTemplatesResponse struct {
+	ResourceTemplates []ResourceTemplate `json:"resource_templates"`
+}
+
+// ResourceRequest 读取、订阅或取消订阅资源请求
+type ResourceRequest struct {
+	ServerLabel string            `json:"server_label"`
+	ServerURL   string            `json:"server_url"`
+	URI         string            `json:"uri"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// ReadResourceResponse 读取资源响应，一个资源可以包含多段内容
+type ReadResourceResponse struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ListPromptsRequest 列出提示模板请求
+type ListPromptsRequest struct {
+	ServerLabel string            `json:"server_label"`
+	ServerURL   string            `json:"server_url"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// ListPromptsResponse 列出提示模板响应
+type ListPromptsResponse struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// GetPromptRequest 获取提示模板请求
+type GetPromptRequest struct {
+	ServerLabel string            `json:"server_label"`
+	ServerURL   string            `json:"server_url"`
+	Name        string            `json:"name"`
+	Arguments   map[string]string `json:"arguments,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// GetPromptResponse 获取提示模板响应
+type GetPromptResponse struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// ListResources 列出服务器提供的资源，服务器分页返回时读取全部页
+func (c *Client) ListResources(ctx context.Context, req *ListResourcesRequest) (*ListResourcesResponse, error) {
+	resources := []Resource{}
+	err := c.paginate(ctx, req.ServerURL, req.Headers, "resources/list", func(result json.RawMessage) error {
+		var page struct {
+			Resources []struct {
+				URI         string `json:"uri"`
+				Name        string `json:"name"`
+				Title       string `json:"title"`
+				Description string `json:"description"`
+				MimeType    string `json:"mimeType"`
+				Size        int64  `json:"size"`
+			} `json:"resources"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return fmt.Errorf("解析资源列表失败: %w", err)
+		}
+		for _, resource := range page.Resources {
+			resources = append(resources, Resource(resource))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ListResourcesResponse{Resources: resources}, nil
+}
+
+// ListResourceTemplates 列出服务器提供的资源模板，服务器分页返回时读取全部页
+func (c *Client) ListResourceTemplates(ctx context.Context, req *ListResourcesRequest) (*ListResourceTemplatesResponse, error) {
+	templates := []ResourceTemplate{}
+	err := c.paginate(ctx, req.ServerURL, req.Headers, "resources/templates/list", func(result json.RawMessage) error {
+		var page struct {
+			ResourceTemplates []struct {
+				URITemplate string `json:"uriTemplate"`
+				Name        string `json:"name"`
+				Title       string `json:"title"`
+				Description string `json:"description"`
+				MimeType    string `json:"mimeType"`
+			} `json:"resourceTemplates"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return fmt.Errorf("解析资源模板列表失败: %w", err)
+		}
+		for _, template := range page.ResourceTemplates {
+			templates = append(templates, ResourceTemplate(template))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ListResourceTemplatesResponse{ResourceTemplates: templates}, nil
+}
+
+// ReadResource 读取资源内容
+func (c *Client) ReadResource(ctx context.Context, req *ResourceRequest) (*ReadResourceResponse, error) {
+	result, err := c.request(ctx, req.ServerURL, req.Headers, "resources/read", map[string]interface{}{"uri": req.URI})
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Contents []struct {
+			URI      string `json:"uri"`
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Blob     string `json:"blob"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("解析资源内容失败: %w", err)
+	}
+
+	contents := make([]ResourceContents, 0, len(response.Contents))
+	for _, content := range response.Contents {
+		contents = append(contents, ResourceContents(content))
+	}
+	return &ReadResourceResponse{Contents: contents}, nil
+}
+
+// SubscribeResource 订阅资源变化，资源更新时服务器发送notifications/resources/updated，
+// 通知交给SetNotificationHandler设置的回调
+func (c *Client) SubscribeResource(ctx context.Context, req *ResourceRequest) error {
+	_, err := c.request(ctx, req.ServerURL, req.Headers, "resources/subscribe", map[string]interface{}{"uri": req.URI})
+	return err
+}
+
+// UnsubscribeResource 取消订阅资源变化
+func (c *Client) UnsubscribeResource(ctx context.Context, req *ResourceRequest) error {
+	_, err := c.request(ctx, req.ServerURL, req.Headers, "resources/unsubscribe", map[string]interface{}{"uri": req.URI})
+	return err
+}
+
+// ListPrompts 列出服务器提供的提示模板，服务器分页返回时读取全部页
+func (c *Client) ListPrompts(ctx context.Context, req *ListPromptsRequest) (*ListPromptsResponse, error) {
+	prompts := []Prompt{}
+	err := c.paginate(ctx, req.ServerURL, req.Headers, "prompts/list", func(result json.RawMessage) error {
+		var page struct {
+			Prompts []Prompt `json:"prompts"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return fmt.Errorf("解析提示模板列表失败: %w", err)
+		}
+		prompts = append(prompts, page.Prompts...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ListPromptsResponse{Prompts: prompts}, nil
+}
+
+// GetPrompt 按参数展开提示模板
+func (c *Client) GetPrompt(ctx context.Context, req *GetPromptRequest) (*GetPromptResponse, error) {
+	params := map[string]interface{}{"name": req.Name}
+	if len(req.Arguments) > 0 {
+		params["arguments"] = req.Arguments
+	}
+
+	result, err := c.request(ctx, req.ServerURL, req.Headers, "prompts/get", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var response GetPromptResponse
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("解析提示模板失败: %w", err)
+	}
+	if response.Messages == nil {
+		response.Messages = []PromptMessage{}
+	}
+	return &response, nil
+}
+
+// maxListPages 分页列表最多读取的页数，防止服务器返回的游标无法结束
+const maxListPages = 100
+
+// paginate 按nextCursor读取分页列表的全部页，每页结果交给handle处理
+// 服务器返回已读取过的游标或超过maxListPages页时返回错误
+func (c *Client) paginate(ctx context.Context, serverURL string, headers map[string]string, method string, handle func(result json.RawMessage) error) error {
+	cursor := ""
+	seen := make(map[string]bool)
+	for pages := 0; ; pages++ {
+		if pages == maxListPages {
+			return fmt.Errorf("%s分页超过%d页", method, maxListPages)
+		}
+
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		result, err := c.request(ctx, serverURL, headers, method, params)
+		if err != nil {
+			return err
+		}
+		if err := handle(result); err != nil {
+			return err
+		}
+
+		var page struct {
+			NextCursor string `json:"nextCursor,omitempty"`
+		}
+		json.Unmarshal(result, &page)
+		if page.NextCursor == "" {
+			return nil
+		}
+		if seen[page.NextCursor] || page.NextCursor == cursor {
+			return fmt.Errorf("%s返回重复的分页游标: %s", method, page.NextCursor)
+		}
+		seen[cursor] = true
+		cursor = page.NextCursor
+	}
+}
+
+// ResourceListener 资源变化回调，uri为空表示服务器的资源列表发生变化
+type ResourceListener func(serverLabel, uri string)
+
+// AddResourceListener 注册资源变化回调，服务器发送notifications/resources/updated或
+// notifications/resources/list_changed时调用
+func (m *Manager) AddResourceListener(listener ResourceListener) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.resourceListeners = append(m.resourceListeners, listener)
+}
+
+// ListResources 列出服务器提供的资源
+func (m *Manager) ListResources(ctx context.Context, serverLabel string) (*ListResourcesResponse, error) {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return nil, err
+	}
+	var response *ListResourcesResponse
+	err = m.guard(ctx, serverLabel, func() error {
+		var requestErr error
+		response, requestErr = client.ListResources(ctx, &ListResourcesRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			Headers:     serverConfig.Headers,
+		})
+		return requestErr
+	})
+	return response, err
+}
+
+// ListResourceTemplates 列出服务器提供的资源模板
+func (m *Manager) ListResourceTemplates(ctx context.Context, serverLabel string) (*ListResourceTemplatesResponse, error) {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return nil, err
+	}
+	var response *ListResourceTemplatesResponse
+	err = m.guard(ctx, serverLabel, func() error {
+		var requestErr error
+		response, requestErr = client.ListResourceTemplates(ctx, &ListResourcesRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			Headers:     serverConfig.Headers,
+		})
+		return requestErr
+	})
+	return response, err
+}
+
+// ReadResource 读取资源内容
+func (m *Manager) ReadResource(ctx context.Context, serverLabel, uri string) (*ReadResourceResponse, error) {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return nil, err
+	}
+	var response *ReadResourceResponse
+	err = m.guard(ctx, serverLabel, func() error {
+		var requestErr error
+		response, requestErr = client.ReadResource(ctx, &ResourceRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			URI:         uri,
+			Headers:     serverConfig.Headers,
+		})
+		return requestErr
+	})
+	return response, err
+}
+
+// SubscribeResource 订阅资源变化，变化通过AddResourceListener注册的回调通知
+// 服务器未声明resources.subscribe能力时返回错误
+func (m *Manager) SubscribeResource(ctx context.Context, serverLabel, uri string) error {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return err
+	}
+	if !m.supportsSubscribe(ctx, client, serverConfig) {
+		return fmt.Errorf("MCP服务器 %s 不支持资源订阅", serverLabel)
+	}
+	return m.guard(ctx, serverLabel, func() error {
+		return client.SubscribeResource(ctx, &ResourceRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			URI:         uri,
+			Headers:     serverConfig.Headers,
+		})
+	})
+}
+
+// UnsubscribeResource 取消订阅资源变化
+func (m *Manager) UnsubscribeResource(ctx context.Context, serverLabel, uri string) error {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return err
+	}
+	return m.guard(ctx, serverLabel, func() error {
+		return client.UnsubscribeResource(ctx, &ResourceRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			URI:         uri,
+			Headers:     serverConfig.Headers,
+		})
+	})
+}
+
+// ListPrompts 列出服务器提供的提示模板
+func (m *Manager) ListPrompts(ctx context.Context, serverLabel string) (*ListPromptsResponse, error) {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return nil, err
+	}
+	var response *ListPromptsResponse
+	err = m.guard(ctx, serverLabel, func() error {
+		var requestErr error
+		response, requestErr = client.ListPrompts(ctx, &ListPromptsRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			Headers:     serverConfig.Headers,
+		})
+		return requestErr
+	})
+	return response, err
+}
+
+// GetPrompt 按参数展开提示模板
+func (m *Manager) GetPrompt(ctx context.Context, serverLabel, name string, arguments map[string]string) (*GetPromptResponse, error) {
+	client, serverConfig, err := m.clientWithConfig(serverLabel)
+	if err != nil {
+		return nil, err
+	}
+	var response *GetPromptResponse
+	err = m.guard(ctx, serverLabel, func() error {
+		var requestErr error
+		response, requestErr = client.GetPrompt(ctx, &GetPromptRequest{
+			ServerLabel: serverLabel,
+			ServerURL:   serverConfig.ServerURL,
+			Name:        name,
+			Arguments:   arguments,
+			Headers:     serverConfig.Headers,
+		})
+		return requestErr
+	})
+	return response, err
+}
+
+// clientWithConfig 获取服务器的客户端和配置
+func (m *Manager) clientWithConfig(serverLabel string) (*Client, *model.MCPServer, error) {
+	client, err := m.GetClient(serverLabel)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverConfig, exists := m.GetServerConfig(serverLabel)
+	if !exists {
+		return nil, nil, fmt.Errorf("服务器配置未找到: %s", serverLabel)
+	}
+	return client, serverConfig, nil
+}
+
+// supportsSubscribe 判断服务器是否声明了resources.subscribe能力，尚未建立会话时先完成握手
+func (m *Manager) supportsSubscribe(ctx context.Context, client *Client, serverConfig *model.MCPServer) bool {
+	info, err := client.Initialize(ctx, serverConfig.ServerURL, serverConfig.Headers)
+	if err != nil {
+		return false
+	}
+	resources, _ := info.Capabilities["resources"].(map[string]interface{})
+	subscribe, _ := resources["subscribe"].(bool)
+	return subscribe
+}
+
+// notifyResourceListeners 通知资源变化
+func (m *Manager) notifyResourceListeners(serverLabel string, params json.RawMessage) {
+	var notification struct {
+		URI string `json:"uri"`
+	}
+	json.Unmarshal(params, &notification)
+
+	m.mutex.RLock()
+	listeners := append([]ResourceListener(nil), m.resourceListeners...)
+	m.mutex.RUnlock()
+
+	for _, listener := range listeners {
+		listener(serverLabel, notification.URI)
+	}
+}
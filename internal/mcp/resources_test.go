@@ -0,0 +1,312 @@
+package mcp_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/agent"
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/mcp/mcptest"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resourceServer 模拟提供资源和提示模板的MCP服务器
+// 读取notify://资源时在响应前发送wiki页面的更新通知
+type resourceServer struct {
+	*mcptest.Server
+	mutex      sync.Mutex
+	reads      map[string]int
+	subscribed []string
+}
+
+// newResourceServer 创建模拟MCP服务器
+func newResourceServer(t *testing.T) *resourceServer {
+	server := &resourceServer{Server: mcptest.NewServer(t, "resources"), reads: make(map[string]int)}
+	server.SetCapability("resources", map[string]interface{}{"subscribe": true, "listChanged": true})
+	server.SetCapability("prompts", map[string]interface{}{})
+
+	server.Handle("resources/list", func(request *mcptest.Request) (interface{}, error) {
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		require.NoError(t, request.Bind(&params))
+		// 分两页返回
+		if params.Cursor == "" {
+			return map[string]interface{}{
+				"resources": []map[string]interface{}{
+					{"uri": "wiki://higress/rate-limit", "name": "rate-limit", "title": "Rate Limiting Plugins", "description": "key-rate-limit plugin configuration", "mimeType": "text/markdown"},
+				},
+				"nextCursor": "page-2",
+			}, nil
+		}
+		return map[string]interface{}{
+			"resources": []map[string]interface{}{
+				{"uri": "wiki://higress/deploy", "name": "deploy", "title": "Deployment", "description": "Install Higress with helm，使用Helm部署网关"},
+				{"uri": "notify://trigger", "name": "trigger"},
+			},
+		}, nil
+	})
+	server.Handle("resources/templates/list", func(*mcptest.Request) (interface{}, error) {
+		return map[string]interface{}{
+			"resourceTemplates": []map[string]interface{}{
+				{"uriTemplate": "repo://alibaba/higress/{path}", "name": "repo-file", "mimeType": "text/plain"},
+			},
+		}, nil
+	})
+	server.Handle("resources/read", func(request *mcptest.Request) (interface{}, error) {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		require.NoError(t, request.Bind(&params))
+		if params.URI == "notify://trigger" {
+			request.Notify("notifications/resources/updated", map[string]string{"uri": "wiki://higress/rate-limit"})
+			return map[string]interface{}{"contents": []map[string]interface{}{{"uri": params.URI, "text": "ok"}}}, nil
+		}
+
+		server.mutex.Lock()
+		server.reads[params.URI]++
+		version := server.reads[params.URI]
+		server.mutex.Unlock()
+		return map[string]interface{}{
+			"contents": []map[string]interface{}{
+				{"uri": params.URI, "mimeType": "text/markdown", "text": fmt.Sprintf("key-rate-limit limits requests by limit_by_header (v%d)", version)},
+				{"uri": params.URI, "mimeType": "image/png", "blob": "iVBORw0KGgo="},
+			},
+		}, nil
+	})
+	server.Handle("resources/subscribe", func(request *mcptest.Request) (interface{}, error) {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		require.NoError(t, request.Bind(&params))
+		server.mutex.Lock()
+		server.subscribed = append(server.subscribed, params.URI)
+		server.mutex.Unlock()
+		return nil, nil
+	})
+	server.Handle("prompts/list", func(*mcptest.Request) (interface{}, error) {
+		return map[string]interface{}{
+			"prompts": []map[string]interface{}{
+				{"name": "triage", "description": "Triage an issue", "arguments": []map[string]interface{}{{"name": "issue", "required": true}}},
+			},
+		}, nil
+	})
+	server.Handle("prompts/get", func(request *mcptest.Request) (interface{}, error) {
+		var params struct {
+			Arguments map[string]string `json:"arguments"`
+		}
+		require.NoError(t, request.Bind(&params))
+		return map[string]interface{}{
+			"description": "Triage an issue",
+			"messages": []map[string]interface{}{
+				{"role": "user", "content": map[string]interface{}{"type": "text", "text": "Triage issue " + params.Arguments["issue"]}},
+			},
+		}, nil
+	})
+	return server
+}
+
+// readCount 获取资源被读取的次数
+func (s *resourceServer) readCount(uri string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.reads[uri]
+}
+
+// subscriptions 获取已订阅的资源
+func (s *resourceServer) subscriptions() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.subscribed...)
+}
+
+// TestManagerResourcesAndPrompts 测试资源列表分页、资源模板、读取、订阅和提示模板
+func TestManagerResourcesAndPrompts(t *testing.T) {
+	server := newResourceServer(t)
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"wiki": {Enabled: true, ServerURL: server.URL},
+		},
+	})
+	ctx := context.Background()
+
+	resources, err := manager.ListResources(ctx, "wiki")
+	require.NoError(t, err)
+	require.Len(t, resources.Resources, 3)
+	assert.Equal(t, "wiki://higress/rate-limit", resources.Resources[0].URI)
+	assert.Equal(t, "text/markdown", resources.Resources[0].MimeType)
+	assert.Equal(t, "Deployment", resources.Resources[1].Title)
+
+	templates, err := manager.ListResourceTemplates(ctx, "wiki")
+	require.NoError(t, err)
+	require.Len(t, templates.ResourceTemplates, 1)
+	assert.Equal(t, "repo://alibaba/higress/{path}", templates.ResourceTemplates[0].URITemplate)
+
+	contents, err := manager.ReadResource(ctx, "wiki", "wiki://higress/deploy")
+	require.NoError(t, err)
+	require.Len(t, contents.Contents, 2)
+	assert.Contains(t, contents.Contents[0].Text, "key-rate-limit")
+	assert.Equal(t, "iVBORw0KGgo=", contents.Contents[1].Blob)
+
+	// 资源更新通知交给资源回调
+	var updated []string
+	manager.AddResourceListener(func(serverLabel, uri string) {
+		updated = append(updated, serverLabel+" "+uri)
+	})
+	require.NoError(t, manager.SubscribeResource(ctx, "wiki", "wiki://higress/rate-limit"))
+	_, err = manager.ReadResource(ctx, "wiki", "notify://trigger")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"wiki wiki://higress/rate-limit"}, updated)
+	assert.Equal(t, []string{"wiki://higress/rate-limit"}, server.subscriptions())
+
+	prompts, err := manager.ListPrompts(ctx, "wiki")
+	require.NoError(t, err)
+	require.Len(t, prompts.Prompts, 1)
+	assert.Equal(t, "triage", prompts.Prompts[0].Name)
+	assert.True(t, prompts.Prompts[0].Arguments[0].Required)
+
+	prompt, err := manager.GetPrompt(ctx, "wiki", "triage", map[string]string{"issue": "#1234"})
+	require.NoError(t, err)
+	require.Len(t, prompt.Messages, 1)
+	assert.Equal(t, "user", prompt.Messages[0].Role)
+	assert.Equal(t, "Triage issue #1234", prompt.Messages[0].Content.Text)
+}
+
+// TestMCPResourceRetriever 测试资源检索器选出相关资源、缓存内容并在资源更新后重新读取
+func TestMCPResourceRetriever(t *testing.T) {
+	server := newResourceServer(t)
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.MCP.Servers = map[string]model.MCPServer{
+		"wiki": {Enabled: true, ServerURL: server.URL},
+	}
+	config.Retrieval.Sources = []model.RetrieverConfig{{
+		Name:    "wiki-pages",
+		Type:    agent.RetrieverTypeMCPResource,
+		Enabled: true,
+		Options: map[string]interface{}{"server": "wiki", "max_results": 1},
+	}}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+
+	retriever, exists := processor.GetRetrieverRegistry().Get("wiki-pages")
+	require.True(t, exists)
+	require.True(t, retriever.Enabled())
+
+	question := &model.Question{Title: "key-rate-limit", Content: "how to configure key-rate-limit plugin"}
+	items, err := retriever.Retrieve(context.Background(), question)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Rate Limiting Plugins", items[0].Title)
+	assert.Contains(t, items[0].Content, "(v1)")
+	assert.Equal(t, "wiki://higress/rate-limit", items[0].Metadata["uri"])
+	assert.Greater(t, items[0].Relevance, 0.0)
+	assert.Equal(t, []string{"wiki://higress/rate-limit"}, server.subscriptions())
+
+	// 内容已缓存
+	_, err = retriever.Retrieve(context.Background(), question)
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.readCount("wiki://higress/rate-limit"))
+
+	// 资源更新后重新读取
+	_, err = processor.GetMCPManager().ReadResource(context.Background(), "wiki", "notify://trigger")
+	require.NoError(t, err)
+	items, err = retriever.Retrieve(context.Background(), question)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Contains(t, items[0].Content, "(v2)")
+	assert.Equal(t, 2, server.readCount("wiki://higress/rate-limit"))
+}
+
+// TestMCPResourceRetrieverStreamUpdates 测试中文问题选出相关资源，订阅失败后重试，以及GET SSE流上的资源更新通知
+func TestMCPResourceRetrieverStreamUpdates(t *testing.T) {
+	server := newResourceServer(t)
+	// 第一次订阅失败
+	var subscribeCalls int
+	server.Handle("resources/subscribe", func(request *mcptest.Request) (interface{}, error) {
+		var params struct {
+			URI string `json:"uri"`
+		}
+		require.NoError(t, request.Bind(&params))
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+		subscribeCalls++
+		if subscribeCalls == 1 {
+			return nil, mcp.NewRPCError(-32603, "subscription unavailable")
+		}
+		server.subscribed = append(server.subscribed, params.URI)
+		return nil, nil
+	})
+
+	config := &model.AgentConfig{}
+	config.Logging.Level = "error"
+	config.Memory.CleanupInterval = time.Minute
+	config.MCP.Servers = map[string]model.MCPServer{
+		"wiki": {Enabled: true, ServerURL: server.URL},
+	}
+	config.Retrieval.Sources = []model.RetrieverConfig{{
+		Name:    "wiki-pages",
+		Type:    agent.RetrieverTypeMCPResource,
+		Enabled: true,
+		Options: map[string]interface{}{"server": "wiki", "max_results": 1},
+	}}
+
+	processor := agent.NewProcessor(openai.NewClient("", ""), config)
+	t.Cleanup(processor.Stop)
+	retriever, exists := processor.GetRetrieverRegistry().Get("wiki-pages")
+	require.True(t, exists)
+
+	question := &model.Question{Title: "网关部署", Content: "怎么部署网关？"}
+	items, err := retriever.Retrieve(context.Background(), question)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Deployment", items[0].Title)
+	assert.Contains(t, items[0].Content, "(v1)")
+	assert.Empty(t, server.subscriptions())
+
+	// 服务器在GET SSE流上发送资源更新通知，重新读取时再次订阅
+	require.Eventually(t, func() bool { return server.Streams() == 1 }, 5*time.Second, 10*time.Millisecond)
+	server.Notify("notifications/resources/updated", map[string]string{"uri": "wiki://higress/deploy"})
+	require.Eventually(t, func() bool {
+		items, err := retriever.Retrieve(context.Background(), question)
+		return err == nil && len(items) == 1 && strings.Contains(items[0].Content, "(v2)")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"wiki://higress/deploy"}, server.subscriptions())
+}
+
+// TestManagerPaginationCursorLoop 测试服务器循环返回分页游标时返回错误
+func TestManagerPaginationCursorLoop(t *testing.T) {
+	server := mcptest.NewServer(t, "loop")
+	server.Handle("resources/list", func(request *mcptest.Request) (interface{}, error) {
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		require.NoError(t, request.Bind(&params))
+		next := "a"
+		if params.Cursor == "a" {
+			next = "b"
+		}
+		return map[string]interface{}{"resources": []map[string]interface{}{}, "nextCursor": next}, nil
+	})
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{
+			"loop": {Enabled: true, ServerURL: server.URL},
+		},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+
+	_, err := manager.ListResources(context.Background(), "loop")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "重复的分页游标")
+	assert.Equal(t, 3, server.Calls("resources/list"))
+}
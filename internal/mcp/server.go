@@ -0,0 +1,379 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// SessionHeader Streamable HTTP会话头
+const SessionHeader = "Mcp-Session-Id"
+
+const (
+	// DefaultSessionTTL 会话空闲超过该时间后过期
+	DefaultSessionTTL = 30 * time.Minute
+	// DefaultMaxSessions 最多保留的会话数，超出时淘汰最久未活动的会话
+	DefaultMaxSessions = 1000
+)
+
+// ToolHandler 工具处理函数
+type ToolHandler func(ctx context.Context, arguments map[string]interface{}) (interface{}, error)
+
+// ServerTool 服务端发布的工具
+type ServerTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	Handler     ToolHandler            `json:"-"`
+}
+
+// Server MCP服务端（JSON-RPC 2.0）
+type Server struct {
+	info         Implementation
+	instructions string
+	tools        map[string]*ServerTool
+	sessions     map[string]time.Time // 会话ID -> 最近活动时间
+	sessionTTL   time.Duration
+	maxSessions  int
+	logger       *logrus.Logger
+	mutex        sync.RWMutex
+}
+
+// NewServer 创建新的MCP服务端
+func NewServer(name, version string) *Server {
+	return &Server{
+		info: Implementation{
+			Name:    name,
+			Version: version,
+		},
+		tools:       make(map[string]*ServerTool),
+		sessions:    make(map[string]time.Time),
+		sessionTTL:  DefaultSessionTTL,
+		maxSessions: DefaultMaxSessions,
+		logger:      logrus.New(),
+	}
+}
+
+// SetSessionLimits 设置会话空闲过期时间和最大会话数，非正数时使用默认值
+func (s *Server) SetSessionLimits(ttl time.Duration, maxSessions int) {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	if maxSessions <= 0 {
+		maxSessions = DefaultMaxSessions
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessionTTL = ttl
+	s.maxSessions = maxSessions
+}
+
+// SetLogger 设置日志器
+func (s *Server) SetLogger(logger *logrus.Logger) {
+	s.logger = logger
+}
+
+// SetInstructions 设置initialize时返回的使用说明
+func (s *Server) SetInstructions(instructions string) {
+	s.instructions = instructions
+}
+
+// RegisterTool 注册工具，同名工具会被覆盖
+func (s *Server) RegisterTool(tool *ServerTool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tools[tool.Name] = tool
+}
+
+// GetTools 获取已注册的工具（按名称排序）
+func (s *Server) GetTools() []*ServerTool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tools := make([]*ServerTool, 0, len(s.tools))
+	for _, tool := range s.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+
+	return tools
+}
+
+// HandleMessage 处理单条JSON-RPC消息，通知消息返回nil
+func (s *Server) HandleMessage(ctx context.Context, req *JSONRPCRequest) *JSONRPCResponse {
+	if req.JSONRPC != JSONRPCVersion || req.Method == "" {
+		return s.errorResponse(req.ID, NewRPCError(ErrCodeInvalidRequest, "无效的JSON-RPC请求"))
+	}
+
+	if req.IsNotification() {
+		s.logger.WithField("method", req.Method).Debug("收到MCP通知")
+		return nil
+	}
+
+	var result interface{}
+	var rpcErr *RPCError
+
+	switch req.Method {
+	case "initialize":
+		result, rpcErr = s.handleInitialize(req.Params)
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": s.GetTools()}
+	case "tools/call":
+		result, rpcErr = s.handleCallTool(ctx, req.Params)
+	default:
+		rpcErr = NewRPCError(ErrCodeMethodNotFound, fmt.Sprintf("方法不存在: %s", req.Method))
+	}
+
+	if rpcErr != nil {
+		return s.errorResponse(req.ID, rpcErr)
+	}
+
+	return &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// HandleRaw 处理原始JSON-RPC消息，通知消息返回nil
+func (s *Server) HandleRaw(ctx context.Context, data []byte) []byte {
+	var req JSONRPCRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		resp := s.errorResponse(nil, NewRPCError(ErrCodeParseError, "解析JSON失败: "+err.Error()))
+		respBytes, _ := json.Marshal(resp)
+		return respBytes
+	}
+
+	resp := s.HandleMessage(ctx, &req)
+	if resp == nil {
+		return nil
+	}
+
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		s.logger.WithError(err).Error("序列化MCP响应失败")
+		respBytes, _ = json.Marshal(s.errorResponse(req.ID, NewRPCError(ErrCodeInternalError, "序列化响应失败")))
+	}
+
+	return respBytes
+}
+
+// ServeHTTP 实现Streamable HTTP传输
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.servePost(w, r)
+	case http.MethodDelete:
+		s.mutex.Lock()
+		delete(s.sessions, r.Header.Get(SessionHeader))
+		s.mutex.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		// 暂不支持服务端主动推送的GET流
+		w.Header().Set("Allow", "POST, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// servePost 处理POST请求
+func (s *Server) servePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "读取请求失败", http.StatusBadRequest)
+		return
+	}
+
+	var req JSONRPCRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.errorResponse(nil, NewRPCError(ErrCodeParseError, "解析JSON失败: "+err.Error())))
+		return
+	}
+
+	// 校验会话，initialize请求除外
+	sessionID := r.Header.Get(SessionHeader)
+	if req.Method != "initialize" {
+		if sessionID == "" {
+			http.Error(w, "缺少"+SessionHeader+"请求头", http.StatusBadRequest)
+			return
+		}
+		if !s.touchSession(sessionID) {
+			http.Error(w, "会话不存在或已过期", http.StatusNotFound)
+			return
+		}
+	}
+
+	resp := s.HandleMessage(r.Context(), &req)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if req.Method == "initialize" && resp.Error == nil {
+		sessionID = s.createSession()
+	}
+	if sessionID != "" {
+		w.Header().Set(SessionHeader, sessionID)
+	}
+
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+// handleInitialize 处理initialize请求
+func (s *Server) handleInitialize(params json.RawMessage) (interface{}, *RPCError) {
+	var initParams InitializeParams
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &initParams); err != nil {
+			return nil, NewRPCError(ErrCodeInvalidParams, "无效的initialize参数: "+err.Error())
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"client":           initParams.ClientInfo.Name,
+		"client_version":   initParams.ClientInfo.Version,
+		"protocol_version": initParams.ProtocolVersion,
+	}).Info("MCP客户端初始化")
+
+	return &InitializeResult{
+		ProtocolVersion: NegotiateProtocolVersion(initParams.ProtocolVersion),
+		Capabilities: map[string]interface{}{
+			"tools": map[string]interface{}{
+				"listChanged": false,
+			},
+		},
+		ServerInfo:   s.info,
+		Instructions: s.instructions,
+	}, nil
+}
+
+// handleCallTool 处理tools/call请求
+func (s *Server) handleCallTool(ctx context.Context, params json.RawMessage) (interface{}, *RPCError) {
+	var callParams CallToolParams
+	if err := json.Unmarshal(params, &callParams); err != nil {
+		return nil, NewRPCError(ErrCodeInvalidParams, "无效的tools/call参数: "+err.Error())
+	}
+
+	s.mutex.RLock()
+	tool, exists := s.tools[callParams.Name]
+	s.mutex.RUnlock()
+
+	if !exists {
+		return nil, NewRPCError(ErrCodeInvalidParams, fmt.Sprintf("工具不存在: %s", callParams.Name))
+	}
+
+	if callParams.Arguments == nil {
+		callParams.Arguments = map[string]interface{}{}
+	}
+
+	s.logger.WithField("tool", callParams.Name).Info("执行MCP工具调用")
+
+	output, err := tool.Handler(ctx, callParams.Arguments)
+	if err != nil {
+		// 工具执行错误通过isError返回给调用方，而不是协议错误
+		s.logger.WithError(err).WithField("tool", callParams.Name).Warn("MCP工具执行失败")
+		return &CallToolResult{
+			Content: []Content{{Type: "text", Text: err.Error()}},
+			IsError: true,
+		}, nil
+	}
+
+	text, err := formatToolOutput(output)
+	if err != nil {
+		return nil, NewRPCError(ErrCodeInternalError, "序列化工具结果失败: "+err.Error())
+	}
+
+	return &CallToolResult{
+		Content: []Content{{Type: "text", Text: text}},
+	}, nil
+}
+
+// createSession 创建会话，先清理过期会话，会话数达到上限时淘汰最久未活动的会话
+func (s *Server) createSession() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for id, lastActive := range s.sessions {
+		if now.Sub(lastActive) > s.sessionTTL {
+			delete(s.sessions, id)
+		}
+	}
+	for len(s.sessions) >= s.maxSessions {
+		oldestID, oldest := "", now
+		for id, lastActive := range s.sessions {
+			if !lastActive.After(oldest) {
+				oldestID, oldest = id, lastActive
+			}
+		}
+		delete(s.sessions, oldestID)
+	}
+
+	sessionID := uuid.New().String()
+	s.sessions[sessionID] = now
+	return sessionID
+}
+
+// touchSession 检查会话是否存在且未过期，并刷新最近活动时间
+func (s *Server) touchSession(sessionID string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	lastActive, exists := s.sessions[sessionID]
+	if !exists {
+		return false
+	}
+	if time.Since(lastActive) > s.sessionTTL {
+		delete(s.sessions, sessionID)
+		return false
+	}
+	s.sessions[sessionID] = time.Now()
+	return true
+}
+
+// errorResponse 构建错误响应
+func (s *Server) errorResponse(id json.RawMessage, rpcErr *RPCError) *JSONRPCResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		ID:      id,
+		Error:   rpcErr,
+	}
+}
+
+// writeJSON 写入JSON响应
+func (s *Server) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.logger.WithError(err).Error("写入MCP响应失败")
+	}
+}
+
+// formatToolOutput 将工具输出转换为文本
+func formatToolOutput(output interface{}) (string, error) {
+	if text, ok := output.(string); ok {
+		return text, nil
+	}
+
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
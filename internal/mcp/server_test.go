@@ -0,0 +1,186 @@
+package mcp_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMCPServer 创建带echo工具的MCP服务端
+func newTestMCPServer() *mcp.Server {
+	server := mcp.NewServer("test-agent", "1.0.0")
+	server.RegisterTool(&mcp.ServerTool{
+		Name:        "echo",
+		Description: "回显输入",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			if args["fail"] == true {
+				return nil, fmt.Errorf("执行失败")
+			}
+			return args, nil
+		},
+	})
+	return server
+}
+
+// postRPC 发送JSON-RPC请求
+func postRPC(t *testing.T, handler http.Handler, sessionID string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if sessionID != "" {
+		req.Header.Set(mcp.SessionHeader, sessionID)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// initializeSession 完成initialize握手并返回会话ID
+func initializeSession(t *testing.T, handler http.Handler) string {
+	resp := postRPC(t, handler, "", `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	sessionID := resp.Header().Get(mcp.SessionHeader)
+	require.NotEmpty(t, sessionID)
+	return sessionID
+}
+
+// TestMCPServerLifecycle 测试MCP服务端的握手、工具列表和工具调用
+func TestMCPServerLifecycle(t *testing.T) {
+	server := newTestMCPServer()
+
+	// initialize
+	resp := postRPC(t, server, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test","version":"1"}}}`)
+	require.Equal(t, http.StatusOK, resp.Code)
+	sessionID := resp.Header().Get(mcp.SessionHeader)
+	require.NotEmpty(t, sessionID)
+
+	var initResp struct {
+		Result mcp.InitializeResult `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &initResp))
+	assert.Equal(t, "2025-03-26", initResp.Result.ProtocolVersion)
+	assert.Equal(t, "test-agent", initResp.Result.ServerInfo.Name)
+
+	// notifications/initialized
+	resp = postRPC(t, server, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+
+	// tools/list
+	resp = postRPC(t, server, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var listResp struct {
+		Result struct {
+			Tools []map[string]interface{} `json:"tools"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &listResp))
+	require.Len(t, listResp.Result.Tools, 1)
+	assert.Equal(t, "echo", listResp.Result.Tools[0]["name"])
+	assert.NotNil(t, listResp.Result.Tools[0]["inputSchema"])
+
+	// tools/call 成功
+	resp = postRPC(t, server, sessionID, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"msg":"hi"}}}`)
+	var callResp struct {
+		Result mcp.CallToolResult `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &callResp))
+	assert.False(t, callResp.Result.IsError)
+	assert.Contains(t, callResp.Result.Content[0].Text, "hi")
+
+	// tools/call 工具执行失败
+	resp = postRPC(t, server, sessionID, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{"fail":true}}}`)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &callResp))
+	assert.True(t, callResp.Result.IsError)
+
+	// 未知会话
+	resp = postRPC(t, server, "unknown-session", `{"jsonrpc":"2.0","id":5,"method":"ping"}`)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// 缺少会话头
+	resp = postRPC(t, server, "", `{"jsonrpc":"2.0","id":6,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+// TestMCPServerSessionLimits 测试会话空闲过期以及超过上限时淘汰最久未活动的会话
+func TestMCPServerSessionLimits(t *testing.T) {
+	server := newTestMCPServer()
+	server.SetSessionLimits(50*time.Millisecond, 2)
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+	first := initializeSession(t, server)
+	second := initializeSession(t, server)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, postRPC(t, server, first, ping).Code)
+
+	// second最久未活动，被新会话淘汰
+	third := initializeSession(t, server)
+	assert.Equal(t, http.StatusNotFound, postRPC(t, server, second, ping).Code)
+	assert.Equal(t, http.StatusOK, postRPC(t, server, first, ping).Code)
+	assert.Equal(t, http.StatusOK, postRPC(t, server, third, ping).Code)
+
+	// 空闲超时后过期
+	time.Sleep(80 * time.Millisecond)
+	assert.Equal(t, http.StatusNotFound, postRPC(t, server, first, ping).Code)
+}
+
+// TestMCPServerErrors 测试JSON-RPC错误处理
+func TestMCPServerErrors(t *testing.T) {
+	server := newTestMCPServer()
+	sessionID := initializeSession(t, server)
+
+	testCases := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"ParseError", `{invalid`, mcp.ErrCodeParseError},
+		{"MethodNotFound", `{"jsonrpc":"2.0","id":1,"method":"unknown"}`, mcp.ErrCodeMethodNotFound},
+		{"UnknownTool", `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"missing"}}`, mcp.ErrCodeInvalidParams},
+		{"InvalidVersion", `{"jsonrpc":"1.0","id":1,"method":"ping"}`, mcp.ErrCodeInvalidRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := postRPC(t, server, sessionID, tc.body)
+			var rpcResp mcp.JSONRPCResponse
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &rpcResp))
+			require.NotNil(t, rpcResp.Error)
+			assert.Equal(t, tc.code, rpcResp.Error.Code)
+		})
+	}
+}
+
+// TestMCPServerStdio 测试stdio传输
+func TestMCPServerStdio(t *testing.T) {
+	server := newTestMCPServer()
+
+	input := bytes.NewBufferString(
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}` + "\n" +
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"msg":"stdio"}}}` + "\n")
+	var output bytes.Buffer
+
+	// 输入EOF后应正常返回
+	err := server.ServeStdio(context.Background(), input, &output)
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	responses := make(map[string]mcp.JSONRPCResponse)
+	for _, line := range lines {
+		var resp mcp.JSONRPCResponse
+		require.NoError(t, json.Unmarshal(line, &resp))
+		responses[string(resp.ID)] = resp
+	}
+	assert.Nil(t, responses["1"].Error)
+	assert.Contains(t, fmt.Sprint(responses["2"].Result), "stdio")
+}
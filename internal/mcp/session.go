@@ -0,0 +1,451 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultClientName initialize时发送的默认客户端名称
+	DefaultClientName = "higress-community-agent"
+	// DefaultClientVersion initialize时发送的默认客户端版本
+	DefaultClientVersion = "1.0.0"
+	// ProtocolVersionHeader 初始化后的请求携带协商的协议版本
+	ProtocolVersionHeader = "MCP-Protocol-Version"
+	// maxErrorBodyBytes 错误响应中保留的最大字节数
+	maxErrorBodyBytes = 512
+)
+
+// ErrSessionExpired 服务端会话不存在或已过期（HTTP 404），需要重新初始化
+var ErrSessionExpired = errors.New("MCP会话已过期")
+
+// SessionInfo MCP会话信息
+type SessionInfo struct {
+	SessionID       string                 `json:"session_id,omitempty"`
+	ProtocolVersion string                 `json:"protocol_version"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      Implementation         `json:"server_info"`
+	Instructions    string                 `json:"instructions,omitempty"`
+	InitializedAt   time.Time              `json:"initialized_at"`
+}
+
+// HasCapability 判断服务器是否声明了指定能力，如tools、resources、prompts
+func (si *SessionInfo) HasCapability(name string) bool {
+	_, exists := si.Capabilities[name]
+	return exists
+}
+
+// clientSession 单个服务器的会话状态，mutex保证同一时间只有一个initialize握手
+// stopStream停止会话的GET SSE流，会话结束或过期时调用
+type clientSession struct {
+	mutex      sync.Mutex
+	info       *SessionInfo
+	stopStream context.CancelFunc
+}
+
+// rpcMessage 客户端发送的JSON-RPC消息，ID为0时为通知
+type rpcMessage struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// rpcResult 服务器返回的JSON-RPC响应
+type rpcResult struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Session 获取与服务器的会话信息，尚未初始化时返回false
+func (c *Client) Session(serverURL string) (*SessionInfo, bool) {
+	if c.stdio != nil {
+		return c.stdio.session()
+	}
+
+	session := c.session(serverURL)
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.info == nil {
+		return nil, false
+	}
+	info := *session.info
+	return &info, true
+}
+
+// Initialize 与服务器完成initialize握手，已有会话时直接返回
+func (c *Client) Initialize(ctx context.Context, serverURL string, headers map[string]string) (*SessionInfo, error) {
+	if c.stdio != nil {
+		if _, err := c.stdio.ensureProcess(ctx); err != nil {
+			return nil, err
+		}
+		info, _ := c.stdio.session()
+		return info, nil
+	}
+
+	info, err := c.ensureSession(ctx, serverURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	copied := *info
+	return &copied, nil
+}
+
+// Ping 发送ping请求检查服务器是否可用
+func (c *Client) Ping(ctx context.Context, serverURL string, headers map[string]string) error {
+	_, err := c.request(ctx, serverURL, headers, "ping", map[string]interface{}{})
+	return err
+}
+
+// Close 结束与服务器的会话，服务器分配了会话ID时发送DELETE通知服务器释放会话
+// 本地进程客户端关闭时结束进程
+func (c *Client) Close(ctx context.Context, serverURL string, headers map[string]string) error {
+	if c.stdio != nil {
+		return c.stdio.close()
+	}
+
+	session := c.session(serverURL)
+	session.mutex.Lock()
+	info := session.info
+	session.info = nil
+	session.closeStream()
+	session.mutex.Unlock()
+
+	if info == nil || info.SessionID == "" {
+		return nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodDelete, serverURL, nil)
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	c.setHeaders(httpReq, headers, info)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("结束MCP会话失败: %w", err)
+	}
+	resp.Body.Close()
+
+	// 服务器不支持客户端结束会话时返回405
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("结束MCP会话失败: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// request 在会话中发送请求并返回result，会话过期时重新初始化并重试一次
+func (c *Client) request(ctx context.Context, serverURL string, headers map[string]string, method string, params interface{}) (json.RawMessage, error) {
+	if c.stdio != nil {
+		return c.stdio.request(ctx, method, params)
+	}
+	if serverURL == "" {
+		return nil, fmt.Errorf("MCP服务器地址为空")
+	}
+
+	reinitialized, reauthorized := false, false
+	for {
+		var result json.RawMessage
+		info, err := c.ensureSession(ctx, serverURL, headers)
+		if err == nil {
+			id := c.nextID.Add(1)
+			if progressFromContext(ctx) != nil {
+				params = withProgressToken(params, id)
+			}
+			result, _, err = c.post(ctx, serverURL, headers, info, &rpcMessage{
+				JSONRPC: JSONRPCVersion,
+				ID:      id,
+				Method:  method,
+				Params:  params,
+			})
+			if errors.Is(err, ErrSessionExpired) && !reinitialized {
+				c.logger.WithField("server_url", serverURL).Info("MCP会话已过期，重新初始化")
+				c.resetSession(serverURL, info)
+				reinitialized = true
+				continue
+			}
+		}
+
+		// 服务器返回401时获取新令牌后重试一次
+		var unauthorized *unauthorizedError
+		if errors.As(err, &unauthorized) && c.oauth != nil && !reauthorized {
+			if authErr := c.oauth.handleUnauthorized(ctx, unauthorized.challenge, unauthorized.token); authErr != nil {
+				return nil, authErr
+			}
+			reauthorized = true
+			continue
+		}
+		return result, err
+	}
+}
+
+// ensureSession 获取会话，尚未初始化时执行initialize握手
+func (c *Client) ensureSession(ctx context.Context, serverURL string, headers map[string]string) (*SessionInfo, error) {
+	session := c.session(serverURL)
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.info != nil {
+		return session.info, nil
+	}
+
+	info, err := c.initialize(ctx, serverURL, headers)
+	if err != nil {
+		return nil, err
+	}
+	session.info = info
+
+	// 与请求无关的通知（如tools/list_changed、resources/updated）通过独立的GET SSE流发送
+	if c.notify != nil {
+		session.stopStream = c.listen(serverURL, headers, info)
+	}
+	return info, nil
+}
+
+// initialize 发送initialize请求协商协议版本，成功后发送notifications/initialized
+func (c *Client) initialize(ctx context.Context, serverURL string, headers map[string]string) (*SessionInfo, error) {
+	result, header, err := c.post(ctx, serverURL, headers, nil, &rpcMessage{
+		JSONRPC: JSONRPCVersion,
+		ID:      c.nextID.Add(1),
+		Method:  "initialize",
+		Params: InitializeParams{
+			ProtocolVersion: LatestProtocolVersion,
+			Capabilities:    map[string]interface{}{},
+			ClientInfo:      c.clientInfo,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MCP初始化失败: %w", err)
+	}
+
+	info, err := newSessionInfo(result, header.Get(SessionHeader))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := c.post(ctx, serverURL, headers, info, &rpcMessage{
+		JSONRPC: JSONRPCVersion,
+		Method:  "notifications/initialized",
+	}); err != nil {
+		return nil, fmt.Errorf("发送initialized通知失败: %w", err)
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"server_url":       serverURL,
+		"server":           info.ServerInfo.Name,
+		"protocol_version": info.ProtocolVersion,
+		"session":          info.SessionID != "",
+	}).Info("MCP会话已初始化")
+	return info, nil
+}
+
+// newSessionInfo 解析initialize结果并校验协议版本
+func newSessionInfo(result json.RawMessage, sessionID string) (*SessionInfo, error) {
+	var initResult InitializeResult
+	if err := json.Unmarshal(result, &initResult); err != nil {
+		return nil, fmt.Errorf("解析initialize结果失败: %w", err)
+	}
+	if !isSupportedProtocolVersion(initResult.ProtocolVersion) {
+		return nil, fmt.Errorf("MCP服务器协议版本不受支持: %s", initResult.ProtocolVersion)
+	}
+
+	info := &SessionInfo{
+		SessionID:       sessionID,
+		ProtocolVersion: initResult.ProtocolVersion,
+		Capabilities:    initResult.Capabilities,
+		ServerInfo:      initResult.ServerInfo,
+		Instructions:    initResult.Instructions,
+		InitializedAt:   time.Now(),
+	}
+	if info.Capabilities == nil {
+		info.Capabilities = map[string]interface{}{}
+	}
+	return info, nil
+}
+
+// post 发送JSON-RPC消息，通知消息不读取响应内容
+func (c *Client) post(ctx context.Context, serverURL string, headers map[string]string, info *SessionInfo, message *rpcMessage) (json.RawMessage, http.Header, error) {
+	reqBody, err := json.Marshal(message)
+	if err != nil {
+		return nil, nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	c.setHeaders(httpReq, headers, info)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 携带会话ID的请求返回404表示会话已过期
+	if resp.StatusCode == http.StatusNotFound && info != nil && info.SessionID != "" {
+		return nil, resp.Header, ErrSessionExpired
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, resp.Header, &unauthorizedError{
+			challenge: resp.Header.Get("WWW-Authenticate"),
+			token:     strings.TrimPrefix(httpReq.Header.Get("Authorization"), "Bearer "),
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, resp.Header, fmt.Errorf("MCP服务器返回错误状态: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// 通知没有响应
+	if message.ID == 0 {
+		return nil, resp.Header, nil
+	}
+
+	// 服务器可以用SSE流返回响应，并在响应前发送通知
+	if isEventStream(resp) {
+		result, err := c.readEventStream(ctx, serverURL, headers, info, resp.Body, message.ID)
+		return result, resp.Header, err
+	}
+
+	var result rpcResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, resp.Header, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if string(result.ID) != strconv.FormatInt(message.ID, 10) {
+		return nil, resp.Header, fmt.Errorf("响应ID不匹配: 期望%d，实际%s", message.ID, string(result.ID))
+	}
+	if result.Error != nil {
+		return nil, resp.Header, result.Error
+	}
+	if len(result.Result) == 0 {
+		return nil, resp.Header, fmt.Errorf("响应中缺少result字段")
+	}
+
+	return result.Result, resp.Header, nil
+}
+
+// send 发送客户端对服务器请求的响应
+func (c *Client) send(ctx context.Context, serverURL string, headers map[string]string, info *SessionInfo, response *JSONRPCResponse) error {
+	reqBody, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("序列化响应失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	c.setHeaders(httpReq, headers, info)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("发送响应失败: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("MCP服务器返回错误状态: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// withProgressToken 在请求参数的_meta中加入progressToken，服务器据此发送进度通知
+func withProgressToken(params interface{}, token int64) interface{} {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return params
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return params
+	}
+
+	meta, _ := values["_meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	meta["progressToken"] = token
+	values["_meta"] = meta
+	return values
+}
+
+// setHeaders 设置自定义请求头、OAuth访问令牌以及会话ID和协议版本
+func (c *Client) setHeaders(httpReq *http.Request, headers map[string]string, info *SessionInfo) {
+	for key, value := range headers {
+		httpReq.Header.Set(key, value)
+	}
+	if c.oauth != nil {
+		if token := c.oauth.accessToken(httpReq.Context()); token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	if info == nil {
+		return
+	}
+	if info.SessionID != "" {
+		httpReq.Header.Set(SessionHeader, info.SessionID)
+	}
+	httpReq.Header.Set(ProtocolVersionHeader, info.ProtocolVersion)
+}
+
+// session 获取服务器对应的会话状态
+func (c *Client) session(serverURL string) *clientSession {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	session, exists := c.sessions[serverURL]
+	if !exists {
+		session = &clientSession{}
+		c.sessions[serverURL] = session
+	}
+	return session
+}
+
+// resetSession 丢弃过期的会话，其他请求已重新初始化时保留新会话
+func (c *Client) resetSession(serverURL string, expired *SessionInfo) {
+	session := c.session(serverURL)
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.info == expired {
+		session.info = nil
+		session.closeStream()
+	}
+}
+
+// closeStream 停止会话的GET SSE流，调用方需持有mutex
+func (s *clientSession) closeStream() {
+	if s.stopStream != nil {
+		s.stopStream()
+		s.stopStream = nil
+	}
+}
+
+// isSupportedProtocolVersion 判断协议版本是否受支持
+func isSupportedProtocolVersion(version string) bool {
+	for _, supported := range SupportedProtocolVersions {
+		if supported == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,387 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxStreamResumes SSE流中断后使用Last-Event-ID恢复的最大次数
+	maxStreamResumes = 3
+	// defaultStreamRetry 服务器未通过retry字段指定时的恢复等待时间
+	defaultStreamRetry = 500 * time.Millisecond
+	// maxSSELineBytes 单行SSE数据的最大字节数
+	maxSSELineBytes = 4 << 20
+)
+
+// ProgressNotification 服务器在处理请求期间发送的进度通知（notifications/progress）
+type ProgressNotification struct {
+	ProgressToken interface{} `json:"progressToken"`
+	Progress      float64     `json:"progress"`
+	Total         float64     `json:"total,omitempty"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// ProgressFunc 进度通知回调
+type ProgressFunc func(progress ProgressNotification)
+
+type progressKey struct{}
+
+// WithProgress 将进度回调放入上下文，使用该上下文发出的请求会携带progressToken，
+// 服务器通过SSE流发送的进度通知会回调progress
+func WithProgress(ctx context.Context, progress ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// progressFromContext 获取上下文中的进度回调
+func progressFromContext(ctx context.Context) ProgressFunc {
+	progress, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return progress
+}
+
+// sseEvent SSE事件
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// sseReader 按SSE格式读取事件
+type sseReader struct {
+	scanner *bufio.Scanner
+}
+
+// newSSEReader 创建SSE读取器
+func newSSEReader(body io.Reader) *sseReader {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineBytes)
+	return &sseReader{scanner: scanner}
+}
+
+// Next 读取下一个事件，流结束时返回io.EOF
+func (r *sseReader) Next() (*sseEvent, error) {
+	event := &sseEvent{}
+	var data []string
+	hasField := false
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			// 空行分隔事件，只有注释的事件被忽略
+			if hasField {
+				event.Data = strings.Join(data, "\n")
+				return event, nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		hasField = true
+
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if millis, err := strconv.Atoi(value); err == nil {
+				event.Retry = time.Duration(millis) * time.Millisecond
+			}
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	// 流结束时未以空行结尾的事件仍然有效
+	if hasField {
+		event.Data = strings.Join(data, "\n")
+		return event, nil
+	}
+	return nil, io.EOF
+}
+
+// streamMessage SSE流中的JSON-RPC消息，可能是响应、通知或服务器请求
+type streamMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
+}
+
+// readEventStream 从SSE流中读取请求的响应，处理期间收到的通知和服务器请求
+// 流在收到响应前中断且服务器提供了事件ID时，使用Last-Event-ID重新连接恢复
+func (c *Client) readEventStream(ctx context.Context, serverURL string, headers map[string]string, info *SessionInfo, body io.ReadCloser, id int64) (json.RawMessage, error) {
+	expectedID := strconv.FormatInt(id, 10)
+	lastEventID := ""
+	retry := defaultStreamRetry
+
+	for resumes := 0; ; resumes++ {
+		reader := newSSEReader(body)
+		for {
+			event, err := reader.Next()
+			if err != nil {
+				break
+			}
+			if event.ID != "" {
+				lastEventID = event.ID
+			}
+			if event.Retry > 0 {
+				retry = event.Retry
+			}
+			if event.Data == "" || (event.Event != "" && event.Event != "message") {
+				continue
+			}
+
+			var message streamMessage
+			if err := json.Unmarshal([]byte(event.Data), &message); err != nil {
+				c.logger.WithError(err).Warn("解析SSE消息失败")
+				continue
+			}
+
+			switch {
+			case message.Method != "" && isNullID(message.ID):
+				c.handleNotification(ctx, message.Method, message.Params)
+			case message.Method != "":
+				c.handleServerRequest(ctx, serverURL, headers, info, &message)
+			case string(message.ID) == expectedID:
+				body.Close()
+				if message.Error != nil {
+					return nil, message.Error
+				}
+				if len(message.Result) == 0 {
+					return nil, fmt.Errorf("响应中缺少result字段")
+				}
+				return message.Result, nil
+			}
+		}
+		body.Close()
+
+		if lastEventID == "" {
+			return nil, fmt.Errorf("SSE流在收到响应前关闭")
+		}
+		if resumes >= maxStreamResumes {
+			return nil, fmt.Errorf("SSE流恢复次数超过上限: %d", maxStreamResumes)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retry):
+		}
+
+		resumed, err := c.resumeStream(ctx, serverURL, headers, info, lastEventID)
+		if err != nil {
+			return nil, err
+		}
+		body = resumed
+	}
+}
+
+// resumeStream 使用Last-Event-ID重新连接SSE流
+func (c *Client) resumeStream(ctx context.Context, serverURL string, headers map[string]string, info *SessionInfo, lastEventID string) (io.ReadCloser, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("Last-Event-ID", lastEventID)
+	c.setHeaders(httpReq, headers, info)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("恢复SSE流失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || !isEventStream(resp) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("恢复SSE流失败: %d", resp.StatusCode)
+	}
+
+	c.logger.WithField("last_event_id", lastEventID).Debug("已恢复SSE流")
+	return resp.Body, nil
+}
+
+// listen 在后台打开独立的GET SSE流，接收服务器主动发送的通知和请求，返回停止函数
+// 服务器不支持（405）或拒绝时停止；流中断后按retry间隔使用Last-Event-ID重新连接，连续失败maxStreamResumes次后放弃
+func (c *Client) listen(serverURL string, headers map[string]string, info *SessionInfo) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	// 流长期保持打开，不能使用带超时的客户端
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+
+	go func() {
+		lastEventID := ""
+		retry := defaultStreamRetry
+		for failures := 0; failures <= maxStreamResumes; {
+			httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, serverURL, nil)
+			if err != nil {
+				return
+			}
+			httpReq.Header.Set("Accept", "text/event-stream")
+			if lastEventID != "" {
+				httpReq.Header.Set("Last-Event-ID", lastEventID)
+			}
+			c.setHeaders(httpReq, headers, info)
+
+			resp, err := streamClient.Do(httpReq)
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				failures++
+			case resp.StatusCode == http.StatusOK && isEventStream(resp):
+				failures = 0
+				lastEventID, retry = c.readNotifications(ctx, serverURL, headers, info, resp.Body, lastEventID, retry)
+			default:
+				resp.Body.Close()
+				c.logger.WithFields(logrus.Fields{
+					"server_url": serverURL,
+					"status":     resp.StatusCode,
+				}).Debug("MCP服务器未提供GET SSE流，不接收服务器主动发送的通知")
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+		}
+		c.logger.WithField("server_url", serverURL).Warn("MCP服务器GET SSE流多次连接失败，不再接收服务器主动发送的通知")
+	}()
+	return cancel
+}
+
+// readNotifications 读取GET SSE流中的通知和服务器请求，直到流结束，返回最后的事件ID和重连间隔
+func (c *Client) readNotifications(ctx context.Context, serverURL string, headers map[string]string, info *SessionInfo, body io.ReadCloser, lastEventID string, retry time.Duration) (string, time.Duration) {
+	defer body.Close()
+
+	reader := newSSEReader(body)
+	for {
+		event, err := reader.Next()
+		if err != nil {
+			return lastEventID, retry
+		}
+		if event.ID != "" {
+			lastEventID = event.ID
+		}
+		if event.Retry > 0 {
+			retry = event.Retry
+		}
+		if event.Data == "" || (event.Event != "" && event.Event != "message") {
+			continue
+		}
+
+		var message streamMessage
+		if err := json.Unmarshal([]byte(event.Data), &message); err != nil {
+			c.logger.WithError(err).Warn("解析SSE消息失败")
+			continue
+		}
+		switch {
+		case message.Method != "" && isNullID(message.ID):
+			c.handleNotification(ctx, message.Method, message.Params)
+		case message.Method != "":
+			c.handleServerRequest(ctx, serverURL, headers, info, &message)
+		}
+	}
+}
+
+// handleNotification 处理服务器通知：进度通知回调调用方，日志通知写入日志，
+// 其他通知交给SetNotificationHandler设置的回调
+func (c *Client) handleNotification(ctx context.Context, method string, params json.RawMessage) {
+	switch method {
+	case "notifications/progress":
+		progress := progressFromContext(ctx)
+		if progress == nil {
+			return
+		}
+		var notification ProgressNotification
+		if err := json.Unmarshal(params, &notification); err != nil {
+			c.logger.WithError(err).Warn("解析进度通知失败")
+			return
+		}
+		progress(notification)
+
+	case "notifications/message":
+		var notification struct {
+			Level  string      `json:"level"`
+			Logger string      `json:"logger"`
+			Data   interface{} `json:"data"`
+		}
+		if err := json.Unmarshal(params, &notification); err != nil {
+			return
+		}
+		c.logger.WithFields(logrus.Fields{
+			"logger": notification.Logger,
+			"data":   notification.Data,
+		}).Log(mcpLogLevel(notification.Level), "MCP服务器日志")
+
+	default:
+		if c.notify == nil {
+			c.logger.WithField("method", method).Debug("忽略MCP服务器通知")
+			return
+		}
+		c.notify(method, params)
+	}
+}
+
+// handleServerRequest 响应服务器在流中发出的请求，目前只支持ping
+func (c *Client) handleServerRequest(ctx context.Context, serverURL string, headers map[string]string, info *SessionInfo, message *streamMessage) {
+	if err := c.send(ctx, serverURL, headers, info, serverRequestResponse(message)); err != nil {
+		c.logger.WithError(err).WithField("method", message.Method).Warn("响应MCP服务器请求失败")
+	}
+}
+
+// serverRequestResponse 生成服务器请求的响应：ping返回空结果，其他方法返回MethodNotFound
+func serverRequestResponse(message *streamMessage) *JSONRPCResponse {
+	response := &JSONRPCResponse{
+		JSONRPC: JSONRPCVersion,
+		ID:      message.ID,
+	}
+	if message.Method == "ping" {
+		response.Result = map[string]interface{}{}
+	} else {
+		response.Error = NewRPCError(ErrCodeMethodNotFound, "客户端不支持的方法: "+message.Method)
+	}
+	return response
+}
+
+// isEventStream 判断响应是否为SSE流
+func isEventStream(resp *http.Response) bool {
+	return strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// isNullID 判断JSON-RPC消息ID是否为空
+func isNullID(id json.RawMessage) bool {
+	return len(id) == 0 || string(id) == "null"
+}
+
+// mcpLogLevel 将MCP日志级别映射为logrus级别
+func mcpLogLevel(level string) logrus.Level {
+	switch level {
+	case "debug":
+		return logrus.DebugLevel
+	case "info", "notice":
+		return logrus.InfoLevel
+	case "warning":
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
@@ -0,0 +1,124 @@
+package mcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMCPClientSSE 测试SSE响应：流中的进度通知、服务器ping请求以及中断后通过Last-Event-ID恢复
+func TestMCPClientSSE(t *testing.T) {
+	var (
+		mutex         sync.Mutex
+		callID        json.RawMessage
+		progressToken interface{}
+		lastEventID   string
+		pingResponse  json.RawMessage
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		// 恢复流：发送剩余的进度通知和响应
+		if r.Method == http.MethodGet {
+			lastEventID = r.Header.Get("Last-Event-ID")
+			assert.Equal(t, "session-1", r.Header.Get(mcp.SessionHeader))
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "id: e4\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progressToken\":%v,\"progress\":2,\"total\":2}}\n\n", progressToken)
+			fmt.Fprintf(w, "id: e5\ndata: {\"jsonrpc\":\"2.0\",\"id\":%s,\"result\":{\"content\":[{\"type\":\"text\",\"text\":\"done\"}]}}\n\n", callID)
+			return
+		}
+
+		var message struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				Meta map[string]interface{} `json:"_meta"`
+			} `json:"params"`
+			Result json.RawMessage `json:"result"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+
+		switch {
+		case message.Method == "initialize":
+			w.Header().Set(mcp.SessionHeader, "session-1")
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},"serverInfo":{"name":"sse","version":"1"}}}`, message.ID)
+		case message.Method == "" && message.Result != nil:
+			pingResponse = message.ID
+			w.WriteHeader(http.StatusAccepted)
+		case message.Method == "tools/call":
+			callID = message.ID
+			progressToken = message.Params.Meta["progressToken"]
+
+			// 发送进度、日志和ping后在响应前断开
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": keep-alive\n\n")
+			fmt.Fprintf(w, "id: e1\nretry: 10\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progressToken\":%v,\"progress\":1,\"total\":2,\"message\":\"检索中\"}}\n\n", progressToken)
+			fmt.Fprint(w, "id: e2\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"info\",\"data\":\"hello\"}}\n\n")
+			fmt.Fprint(w, "id: e3\ndata: {\"jsonrpc\":\"2.0\",\"id\":99,\"method\":\"ping\"}\n\n")
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	var progress []mcp.ProgressNotification
+	ctx := mcp.WithProgress(context.Background(), func(notification mcp.ProgressNotification) {
+		progress = append(progress, notification)
+	})
+
+	client := mcp.NewClient(5 * time.Second)
+	response, err := client.CallTool(ctx, &mcp.CallToolRequest{ServerURL: server.URL, ToolName: "search"})
+	require.NoError(t, err)
+	assert.Equal(t, "done", mcp.ToolResultText(response.Output))
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.NotNil(t, progressToken)
+	assert.Equal(t, "e3", lastEventID)
+	assert.Equal(t, "99", string(pingResponse))
+
+	require.Len(t, progress, 2)
+	assert.Equal(t, 1.0, progress[0].Progress)
+	assert.Equal(t, 2.0, progress[0].Total)
+	assert.Equal(t, "检索中", progress[0].Message)
+	assert.Equal(t, 2.0, progress[1].Progress)
+}
+
+// TestMCPClientSSEWithoutResume 测试不可恢复的SSE流在响应前关闭时返回错误
+func TestMCPClientSSEWithoutResume(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&message)
+
+		switch message.Method {
+		case "initialize":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":{"protocolVersion":"2025-03-26","capabilities":{}}}`, message.ID)
+		case "tools/list":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"debug\",\"data\":\"x\"}}\n\n")
+		default:
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer server.Close()
+
+	client := mcp.NewClient(5 * time.Second)
+	_, err := client.ListTools(context.Background(), &mcp.ListToolsRequest{ServerURL: server.URL})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SSE")
+}
@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ServeStdio 通过标准输入输出提供MCP服务
+// 消息以换行分隔，输入EOF时等待处理中的请求完成后返回nil
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	readErr := make(chan error, 1)
+
+	// 读取协程：按行读取JSON-RPC消息
+	go func() {
+		reader := bufio.NewReader(in)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var writeMutex sync.Mutex
+	var inflight sync.WaitGroup
+	defer inflight.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				s.logger.Info("stdio输入已关闭，结束MCP会话")
+				return nil
+			}
+			return err
+		case line := <-lines:
+			// 并发处理请求，避免长时间的工具调用阻塞ping等请求
+			inflight.Add(1)
+			go func(line []byte) {
+				defer inflight.Done()
+
+				resp := s.HandleRaw(ctx, line)
+				if resp == nil {
+					return
+				}
+
+				writeMutex.Lock()
+				defer writeMutex.Unlock()
+				if _, err := out.Write(append(resp, '\n')); err != nil {
+					s.logger.WithError(err).Error("写入stdio响应失败")
+				}
+			}(line)
+		}
+	}
+}
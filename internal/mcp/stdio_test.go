@@ -0,0 +1,187 @@
+package mcp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/mcp"
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stdioHelperEnv 设置后测试二进制作为stdio MCP服务器运行
+const stdioHelperEnv = "MCP_STDIO_HELPER"
+
+// TestMCPStdioHelperProcess 不是真正的测试，由stdio客户端以子进程方式启动
+func TestMCPStdioHelperProcess(t *testing.T) {
+	switch os.Getenv(stdioHelperEnv) {
+	case "1":
+	case "duplicate":
+		serveDuplicateResponses()
+		os.Exit(0)
+	default:
+		t.Skip("仅作为子进程运行")
+	}
+
+	server := mcp.NewServer("stdio-server", "1.0.0")
+	server.RegisterTool(&mcp.ServerTool{
+		Name:        "echo",
+		Description: "回显输入和环境变量",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+			return arguments["text"].(string) + os.Getenv("STDIO_SUFFIX"), nil
+		},
+	})
+	server.RegisterTool(&mcp.ServerTool{
+		Name:        "crash",
+		Description: "模拟进程崩溃",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+			os.Exit(2)
+			return nil, nil
+		},
+	})
+	server.ServeStdio(context.Background(), os.Stdin, os.Stdout)
+	os.Exit(0)
+}
+
+// serveDuplicateResponses 每个请求的响应都发送三次，并在标准错误输出日志
+func serveDuplicateResponses() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &request) != nil || len(request.ID) == 0 {
+			continue
+		}
+
+		result := `{"tools":[]}`
+		if request.Method == "initialize" {
+			result = `{"protocolVersion":"` + mcp.LatestProtocolVersion + `","capabilities":{},"serverInfo":{"name":"duplicate","version":"1.0.0"}}`
+		}
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(os.Stdout, `{"jsonrpc":"2.0","id":%s,"result":%s}`+"\n", request.ID, result)
+		}
+		fmt.Fprintf(os.Stderr, "handled %s\n", request.Method)
+	}
+}
+
+// newStdioHelperConfig 创建启动测试二进制作为MCP服务器的配置
+func newStdioHelperConfig() model.MCPServer {
+	return model.MCPServer{
+		Enabled: true,
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestMCPStdioHelperProcess$"},
+		Env:     []string{stdioHelperEnv + "=1", "STDIO_SUFFIX=!"},
+	}
+}
+
+// TestMCPStdioTransport 测试通过Manager选择stdio传输，进程崩溃后自动重启
+func TestMCPStdioTransport(t *testing.T) {
+	manager := mcp.NewManager(&model.MCPConfig{
+		Servers: map[string]model.MCPServer{"local": newStdioHelperConfig()},
+	})
+	t.Cleanup(func() { manager.Close(context.Background()) })
+
+	client, err := manager.GetClient("local")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	tools, err := client.ListTools(ctx, &mcp.ListToolsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, tools.Tools, 2)
+
+	session, ok := client.Session("")
+	require.True(t, ok)
+	assert.Equal(t, "stdio-server", session.ServerInfo.Name)
+	assert.Empty(t, session.SessionID)
+
+	response, err := client.CallTool(ctx, &mcp.CallToolRequest{ToolName: "echo", Arguments: map[string]interface{}{"text": "hello"}})
+	require.NoError(t, err)
+	assert.Equal(t, "hello!", mcp.ToolResultText(response.Output))
+
+	// 进程崩溃时进行中的请求失败，之后的请求重启进程
+	_, err = client.CallTool(ctx, &mcp.CallToolRequest{ToolName: "crash"})
+	require.ErrorIs(t, err, mcp.ErrProcessExited)
+
+	started := time.Now()
+	response, err = client.CallTool(ctx, &mcp.CallToolRequest{ToolName: "echo", Arguments: map[string]interface{}{"text": "again"}})
+	require.NoError(t, err)
+	assert.Equal(t, "again!", mcp.ToolResultText(response.Output))
+	assert.GreaterOrEqual(t, time.Since(started), 400*time.Millisecond)
+
+	// 关闭后不再启动进程
+	require.NoError(t, client.Close(ctx, "", nil))
+	_, ok = client.Session("")
+	assert.False(t, ok)
+	_, err = client.ListTools(ctx, &mcp.ListToolsRequest{})
+	assert.Error(t, err)
+}
+
+// TestMCPStdioDuplicateResponses 测试服务器重复发送响应时不影响后续请求
+func TestMCPStdioDuplicateResponses(t *testing.T) {
+	config := newStdioHelperConfig()
+	client := mcp.NewStdioClient(mcp.StdioConfig{
+		Command: config.Command,
+		Args:    config.Args,
+		Env:     []string{stdioHelperEnv + "=duplicate"},
+	}, 5*time.Second)
+	t.Cleanup(func() { client.Close(context.Background(), "", nil) })
+
+	for i := 0; i < 5; i++ {
+		_, err := client.ListTools(context.Background(), &mcp.ListToolsRequest{})
+		require.NoError(t, err)
+	}
+}
+
+// TestMCPStdioCloseDuringRestart 测试等待重启期间关闭客户端不被阻塞，等待中的请求返回错误
+func TestMCPStdioCloseDuringRestart(t *testing.T) {
+	client := mcp.NewStdioClient(mcp.StdioConfig{
+		Command: os.Args[0],
+		Args:    newStdioHelperConfig().Args,
+		Env:     []string{stdioHelperEnv + "=1"},
+	}, 10*time.Second)
+
+	ctx := context.Background()
+	_, err := client.CallTool(ctx, &mcp.CallToolRequest{ToolName: "crash"})
+	require.ErrorIs(t, err, mcp.ErrProcessExited)
+
+	// 多个请求等待同一次重启
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := client.ListTools(ctx, &mcp.ListToolsRequest{})
+			errs <- err
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	started := time.Now()
+	require.NoError(t, client.Close(ctx, "", nil))
+	assert.Less(t, time.Since(started), 300*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-errs:
+			assert.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("关闭客户端后请求没有返回")
+		}
+	}
+}
+
+// TestMCPStdioCommandNotFound 测试命令不存在时返回错误
+func TestMCPStdioCommandNotFound(t *testing.T) {
+	client := mcp.NewStdioClient(mcp.StdioConfig{Command: "/nonexistent/mcp-server"}, 5*time.Second)
+	_, err := client.ListTools(context.Background(), &mcp.ListToolsRequest{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "启动MCP服务器进程失败")
+}
@@ -0,0 +1,473 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minRestartBackoff 进程崩溃后首次重启的等待时间
+	minRestartBackoff = 500 * time.Millisecond
+	// maxRestartBackoff 重启等待时间上限
+	maxRestartBackoff = 30 * time.Second
+	// stableRunDuration 进程运行超过该时间后退出视为偶发，重启等待时间重置
+	stableRunDuration = time.Minute
+	// stopGracePeriod 关闭标准输入后等待进程退出的时间
+	stopGracePeriod = 2 * time.Second
+)
+
+// ErrProcessExited 本地MCP服务器进程在请求完成前退出
+var ErrProcessExited = errors.New("MCP服务器进程已退出")
+
+// StdioConfig 本地MCP服务器进程配置
+type StdioConfig struct {
+	Command string   // 可执行文件
+	Args    []string // 命令行参数
+	Env     []string // 额外的环境变量，格式为KEY=VALUE，在当前进程环境变量基础上追加
+	Cwd     string   // 工作目录
+}
+
+// stdioTransport 通过子进程标准输入输出通信的MCP传输
+// 进程在首次请求时启动并完成initialize握手；进程退出后下一次请求按指数退避重启。
+// 同一时间只有一个请求负责启动进程，启动期间不持有锁，其他请求等待启动完成
+type stdioTransport struct {
+	config  StdioConfig
+	client  *Client
+	mutex   sync.Mutex
+	process *stdioProcess
+	// starting 正在启动时非空，启动结束后关闭；launching为启动中的进程，关闭客户端时一并停止
+	starting  chan struct{}
+	launching *stdioProcess
+	// restarts 连续异常退出次数，决定下次启动前的等待时间
+	restarts  int
+	nextStart time.Time
+	closed    bool
+}
+
+// stdioProcess 运行中的MCP服务器进程
+type stdioProcess struct {
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	writeMutex sync.Mutex
+	pending    map[string]chan *streamMessage
+	progress   map[string]ProgressFunc
+	mutex      sync.Mutex
+	info       *SessionInfo
+	startedAt  time.Time
+	stderrDone chan struct{}
+	done       chan struct{}
+	exitErr    error
+}
+
+// NewStdioClient 创建通过子进程标准输入输出通信的MCP客户端，timeout为单个请求的超时时间
+func NewStdioClient(config StdioConfig, timeout time.Duration) *Client {
+	client := NewClient(timeout)
+	client.stdio = &stdioTransport{
+		config: config,
+		client: client,
+	}
+	return client
+}
+
+// request 发送请求并等待响应，进程未运行时先启动
+func (t *stdioTransport) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	if timeout := t.client.httpClient.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	process, err := t.ensureProcess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.call(ctx, process, method, params)
+}
+
+// session 获取当前进程的会话信息
+func (t *stdioTransport) session() (*SessionInfo, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.process == nil || t.process.exited() {
+		return nil, false
+	}
+	info := *t.process.info
+	return &info, true
+}
+
+// close 停止进程（包括正在启动的进程），之后的请求返回错误
+func (t *stdioTransport) close() error {
+	t.mutex.Lock()
+	process, launching := t.process, t.launching
+	t.process = nil
+	t.closed = true
+	t.mutex.Unlock()
+
+	if launching != nil {
+		launching.stop()
+	}
+	if process == nil {
+		return nil
+	}
+	return process.stop()
+}
+
+// ensureProcess 获取运行中的进程，没有时按退避时间启动并完成initialize握手
+// 已有请求正在启动进程时等待其完成，启动失败后由等待的请求重新尝试
+func (t *stdioTransport) ensureProcess(ctx context.Context) (*stdioProcess, error) {
+	for {
+		t.mutex.Lock()
+		if t.closed {
+			t.mutex.Unlock()
+			return nil, fmt.Errorf("MCP客户端已关闭")
+		}
+		if t.process != nil && !t.process.exited() {
+			process := t.process
+			t.mutex.Unlock()
+			return process, nil
+		}
+		if starting := t.starting; starting != nil {
+			t.mutex.Unlock()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-starting:
+			}
+			continue
+		}
+
+		// 进程异常退出后等待退避时间再重启
+		if t.process != nil {
+			if time.Since(t.process.startedAt) >= stableRunDuration {
+				t.restarts = 0
+			}
+			backoff := t.backoff()
+
+			t.client.logger.WithError(t.process.exitErr).WithFields(logrus.Fields{
+				"command": t.config.Command,
+				"backoff": backoff,
+			}).Warn("MCP服务器进程已退出，等待后重启")
+			t.process = nil
+		}
+
+		starting := make(chan struct{})
+		t.starting = starting
+		wait := time.Until(t.nextStart)
+		t.mutex.Unlock()
+
+		process, err := t.launch(ctx, wait)
+
+		t.mutex.Lock()
+		t.starting = nil
+		close(starting)
+		if err == nil {
+			t.process = process
+		}
+		t.mutex.Unlock()
+		return process, err
+	}
+}
+
+// launch 等待退避时间后启动进程并完成initialize握手，由ensureProcess在不持有锁时调用
+func (t *stdioTransport) launch(ctx context.Context, wait time.Duration) (*stdioProcess, error) {
+	if wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		return nil, fmt.Errorf("MCP客户端已关闭")
+	}
+	process, err := t.start()
+	if err != nil {
+		t.backoff()
+		t.mutex.Unlock()
+		return nil, err
+	}
+	t.launching = process
+	t.mutex.Unlock()
+
+	info, err := t.initialize(ctx, process)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.launching = nil
+	if err == nil && t.closed {
+		err = fmt.Errorf("MCP客户端已关闭")
+	}
+	if err != nil {
+		process.stop()
+		t.backoff()
+		return nil, err
+	}
+	process.info = info
+	return process, nil
+}
+
+// backoff 计算下次启动前的等待时间（指数增长，不超过上限）并记录一次失败
+func (t *stdioTransport) backoff() time.Duration {
+	backoff := maxRestartBackoff
+	if t.restarts < 16 {
+		backoff = minRestartBackoff << t.restarts
+	}
+	if backoff > maxRestartBackoff {
+		backoff = maxRestartBackoff
+	}
+	t.nextStart = time.Now().Add(backoff)
+	t.restarts++
+	return backoff
+}
+
+// start 启动进程并开始读取标准输出和标准错误
+func (t *stdioTransport) start() (*stdioProcess, error) {
+	if t.config.Command == "" {
+		return nil, fmt.Errorf("未配置MCP服务器命令")
+	}
+
+	cmd := exec.Command(t.config.Command, t.config.Args...)
+	cmd.Dir = t.config.Cwd
+	cmd.Env = append(os.Environ(), t.config.Env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建标准输入失败: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建标准输出失败: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建标准错误失败: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动MCP服务器进程失败: %w", err)
+	}
+
+	process := &stdioProcess{
+		cmd:        cmd,
+		stdin:      stdin,
+		pending:    make(map[string]chan *streamMessage),
+		progress:   make(map[string]ProgressFunc),
+		startedAt:  time.Now(),
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go t.logStderr(process, stderr)
+	go t.readLoop(process, stdout)
+
+	t.client.logger.WithFields(logrus.Fields{
+		"command": t.config.Command,
+		"pid":     cmd.Process.Pid,
+	}).Info("MCP服务器进程已启动")
+	return process, nil
+}
+
+// initialize 与新启动的进程完成initialize握手
+func (t *stdioTransport) initialize(ctx context.Context, process *stdioProcess) (*SessionInfo, error) {
+	result, err := t.call(ctx, process, "initialize", InitializeParams{
+		ProtocolVersion: LatestProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      t.client.clientInfo,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("MCP初始化失败: %w", err)
+	}
+
+	info, err := newSessionInfo(result, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := process.write(&rpcMessage{JSONRPC: JSONRPCVersion, Method: "notifications/initialized"}); err != nil {
+		return nil, fmt.Errorf("发送initialized通知失败: %w", err)
+	}
+	return info, nil
+}
+
+// call 发送请求并等待对应ID的响应
+func (t *stdioTransport) call(ctx context.Context, process *stdioProcess, method string, params interface{}) (json.RawMessage, error) {
+	id := t.client.nextID.Add(1)
+	key := strconv.FormatInt(id, 10)
+
+	progress := progressFromContext(ctx)
+	if progress != nil {
+		params = withProgressToken(params, id)
+	}
+
+	responses := make(chan *streamMessage, 1)
+	process.mutex.Lock()
+	process.pending[key] = responses
+	if progress != nil {
+		process.progress[key] = progress
+	}
+	process.mutex.Unlock()
+
+	defer func() {
+		process.mutex.Lock()
+		delete(process.pending, key)
+		delete(process.progress, key)
+		process.mutex.Unlock()
+	}()
+
+	if err := process.write(&rpcMessage{JSONRPC: JSONRPCVersion, ID: id, Method: method, Params: params}); err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-process.done:
+		return nil, ErrProcessExited
+	case message := <-responses:
+		if message.Error != nil {
+			return nil, message.Error
+		}
+		if len(message.Result) == 0 {
+			return nil, fmt.Errorf("响应中缺少result字段")
+		}
+		return message.Result, nil
+	}
+}
+
+// readLoop 按行读取进程输出的JSON-RPC消息，进程退出后唤醒所有等待中的请求
+func (t *stdioTransport) readLoop(process *stdioProcess, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineBytes)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var message streamMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			t.client.logger.WithError(err).Warn("解析MCP服务器输出失败")
+			continue
+		}
+
+		switch {
+		case message.Method != "" && isNullID(message.ID):
+			ctx := context.Background()
+			if message.Method == "notifications/progress" {
+				ctx = process.progressContext(message.Params)
+			}
+			t.client.handleNotification(ctx, message.Method, message.Params)
+		case message.Method != "":
+			if err := process.write(serverRequestResponse(&message)); err != nil {
+				t.client.logger.WithError(err).WithField("method", message.Method).Warn("响应MCP服务器请求失败")
+			}
+		default:
+			process.mutex.Lock()
+			responses, ok := process.pending[string(message.ID)]
+			process.mutex.Unlock()
+			if !ok {
+				continue
+			}
+			// 重复ID的响应丢弃，不能阻塞读取
+			select {
+			case responses <- &message:
+			default:
+				t.client.logger.WithField("id", string(message.ID)).Warn("忽略MCP服务器重复的响应")
+			}
+		}
+	}
+
+	// 输出无法继续解析时结束进程，避免进程写满管道后挂起
+	if err := scanner.Err(); err != nil {
+		t.client.logger.WithError(err).Warn("读取MCP服务器输出失败，结束进程")
+		process.cmd.Process.Kill()
+	}
+
+	// 标准错误读取完毕后才能调用Wait，否则Wait关闭管道会丢失最后的输出
+	<-process.stderrDone
+	process.exitErr = process.cmd.Wait()
+	close(process.done)
+}
+
+// logStderr 将进程的标准错误写入日志，读取完毕后通知readLoop
+func (t *stdioTransport) logStderr(process *stdioProcess, stderr io.Reader) {
+	defer close(process.stderrDone)
+
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		t.client.logger.WithField("command", t.config.Command).Debug(scanner.Text())
+	}
+	// 单行过长时丢弃剩余输出，保证管道不被写满
+	io.Copy(io.Discard, stderr)
+}
+
+// write 写入一行JSON-RPC消息
+func (p *stdioProcess) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// progressContext 根据进度通知中的progressToken找到对应请求的进度回调
+func (p *stdioProcess) progressContext(params json.RawMessage) context.Context {
+	var notification struct {
+		ProgressToken json.RawMessage `json:"progressToken"`
+	}
+	json.Unmarshal(params, &notification)
+
+	p.mutex.Lock()
+	progress := p.progress[string(notification.ProgressToken)]
+	p.mutex.Unlock()
+
+	if progress == nil {
+		return context.Background()
+	}
+	return WithProgress(context.Background(), progress)
+}
+
+// exited 判断进程是否已退出
+func (p *stdioProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop 关闭标准输入让进程自行退出，超时后强制结束
+func (p *stdioProcess) stop() error {
+	p.stdin.Close()
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(stopGracePeriod):
+	}
+
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("结束MCP服务器进程失败: %w", err)
+	}
+	<-p.done
+	return nil
+}
@@ -33,7 +33,8 @@ func NewManager(config MemoryConfig) *Manager {
 		stopCleanup:       make(chan bool),
 	}
 
-	// 启动清理协程
+	// 启动清理协程，定时器在启动前创建，Stop不会与协程并发访问
+	manager.cleanupTicker = time.NewTicker(config.CleanupInterval)
 	go manager.startCleanupRoutine()
 
 	return manager
@@ -444,7 +445,6 @@ func (m *Manager) generateContextSummary(items []MemoryItem) string {
 
 // startCleanupRoutine 启动清理协程
 func (m *Manager) startCleanupRoutine() {
-	m.cleanupTicker = time.NewTicker(m.config.CleanupInterval)
 	defer m.cleanupTicker.Stop()
 
 	for {
@@ -499,10 +499,7 @@ func (m *Manager) cleanupExpiredMemories() {
 	}
 }
 
-// Stop 停止记忆管理器
+// Stop 停止记忆管理器，清理协程退出时停止定时器
 func (m *Manager) Stop() {
-	if m.cleanupTicker != nil {
-		m.cleanupTicker.Stop()
-	}
 	close(m.stopCleanup)
 }
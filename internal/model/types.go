@@ -100,6 +100,46 @@ type ProcessResponse struct {
 	ProcessingTime  string          `json:"processing_time"` // 处理时间
 	FusionScore     float64         `json:"fusion_score"`    // 融合质量分数
 	Recommendations []string        `json:"recommendations"` // 建议列表
+	SourceStatuses  []SourceStatus  `json:"source_statuses"` // 各知识源检索状态
+}
+
+// RetrievalStatus 知识源检索状态
+type RetrievalStatus string
+
+const (
+	RetrievalStatusOK        RetrievalStatus = "ok"        // 检索成功
+	RetrievalStatusTimeout   RetrievalStatus = "timeout"   // 超出时间预算
+	RetrievalStatusFallback  RetrievalStatus = "fallback"  // 使用备用数据
+	RetrievalStatusError     RetrievalStatus = "error"     // 检索失败
+	RetrievalStatusCancelled RetrievalStatus = "cancelled" // 已获得足够结果，提前取消
+)
+
+// SourceStatus 单个知识源的检索状态
+type SourceStatus struct {
+	Source  KnowledgeSource `json:"source"`          // 知识源
+	Status  RetrievalStatus `json:"status"`          // 检索状态
+	Count   int             `json:"count"`           // 返回的知识项数量
+	Latency string          `json:"latency"`         // 检索耗时
+	Error   string          `json:"error,omitempty"` // 错误信息
+}
+
+// StreamEventType 流式事件类型
+type StreamEventType string
+
+const (
+	StreamEventMemory   StreamEventType = "memory"   // 记忆检索完成
+	StreamEventQuestion StreamEventType = "question" // 问题理解完成
+	StreamEventSource   StreamEventType = "source"   // 单个知识源检索完成
+	StreamEventFusion   StreamEventType = "fusion"   // 知识融合完成
+	StreamEventToken    StreamEventType = "token"    // 回答增量内容
+	StreamEventDone     StreamEventType = "done"     // 处理完成
+	StreamEventError    StreamEventType = "error"    // 处理失败
+)
+
+// StreamEvent 问题处理过程中的流式事件
+type StreamEvent struct {
+	Type StreamEventType `json:"type"` // 事件类型
+	Data interface{}     `json:"data"` // 事件数据
 }
 
 // BugAnalysisResult Bug分析结果
@@ -217,6 +257,8 @@ type AgentConfig struct {
 	Memory    MemoryConfig     `json:"memory"`    // 记忆组件配置
 	Network   NetworkConfig    `json:"network"`   // 网络配置
 	MCP       MCPConfig        `json:"mcp"`       // MCP集成配置
+	Retrieval RetrievalBudgetConfig `json:"retrieval"` // 多源检索配置
+	ToolAgent ToolAgentConfig  `json:"tool_agent"` // 工具调用Agent配置
 }
 
 // MCPConfig MCP集成配置
@@ -224,6 +266,18 @@ type MCPConfig struct {
 	Enabled string                 `json:"enabled"` // 是否启用MCP
 	Timeout string                 `json:"timeout"` // 超时时间
 	Servers map[string]MCPServer  `json:"servers"` // MCP服务器配置
+	ApprovalTimeout string         `json:"approval_timeout"` // 需要审批的工具调用等待审批的时间，默认10分钟
+	HealthCheck     MCPHealthConfig `json:"health_check"`    // 服务器健康检查和熔断配置
+	CacheMaxEntries int            `json:"cache_max_entries"` // 工具调用响应缓存的最大条目数，超出时淘汰最久未使用的条目，默认1000
+	OAuthTokenFile  string         `json:"oauth_token_file"`  // OAuth凭据保存文件，为空时只保存在内存中
+	Approvers       map[string]string `json:"approvers"`       // 审批人名称到访问令牌，支持${ENV}占位符；未配置时审批接口不可用
+}
+
+// MCPHealthConfig MCP服务器健康检查和熔断配置
+type MCPHealthConfig struct {
+	Interval         string `json:"interval"`          // 后台健康检查间隔，默认30s，0表示不启用
+	FailureThreshold int    `json:"failure_threshold"` // 连续失败多少次后熔断，默认5
+	OpenTimeout      string `json:"open_timeout"`      // 熔断后多久进入半开状态试探，默认30s
 }
 
 // MCPServer MCP服务器配置
@@ -234,14 +288,40 @@ type MCPServer struct {
 	RequireApproval string            `json:"require_approval"` // 审批要求
 	AllowedTools    []string          `json:"allowed_tools"`   // 允许的工具
 	Headers         map[string]string `json:"headers"`         // 请求头
+	SourceType      string            `json:"source_type"`     // 检索结果的知识源类型，为空时使用服务器标签
+	Command         string            `json:"command"`         // 本地服务器命令，设置后通过stdio通信并忽略ServerURL
+	Args            []string          `json:"args"`            // 本地服务器命令参数
+	Env             []string          `json:"env"`             // 本地服务器额外环境变量，格式为KEY=VALUE（配置键会被转为小写，因此不使用map）
+	Cwd             string            `json:"cwd"`             // 本地服务器工作目录
+	Cache           MCPCacheConfig    `json:"cache"`           // 幂等工具调用的响应缓存
+	OAuth           MCPOAuthConfig    `json:"oauth"`           // 远程服务器的OAuth 2.1授权
+}
+
+// MCPOAuthConfig MCP服务器OAuth授权配置
+type MCPOAuthConfig struct {
+	Enabled             bool     `json:"enabled"`              // 是否启用OAuth
+	GrantType           string   `json:"grant_type"`           // authorization_code（默认，需用户授权）或client_credentials
+	ClientID            string   `json:"client_id"`            // 客户端ID，为空时通过动态客户端注册获取
+	ClientSecret        string   `json:"client_secret"`        // 客户端密钥，支持${ENV}占位符
+	Scopes              []string `json:"scopes"`               // 申请的权限范围
+	AuthorizationServer string   `json:"authorization_server"` // 授权服务器地址，为空时通过受保护资源元数据发现
+	RedirectURL         string   `json:"redirect_url"`         // 授权码回调地址，默认http://localhost:8080/api/v1/mcp/oauth/callback
+}
+
+// MCPCacheConfig MCP服务器工具调用响应缓存配置
+type MCPCacheConfig struct {
+	TTL      string   `json:"ttl"`       // 响应缓存时间，为空或0表示不缓存
+	StaleTTL string   `json:"stale_ttl"` // 过期后继续返回旧响应并在后台刷新的时间，默认与ttl相同
+	Tools    []string `json:"tools"`     // 可缓存的幂等工具，未列出的工具不缓存
 }
 
 // AgentInfo Agent基础信息
 type AgentInfo struct {
-	Name    string `json:"name"`    // Agent名称
-	Version string `json:"version"`  // 版本号
-	Port    int    `json:"port"`    // 服务端口
-	Debug   bool   `json:"debug"`   // 调试模式
+	Name          string        `json:"name"`           // Agent名称
+	Version       string        `json:"version"`        // 版本号
+	Port          int           `json:"port"`           // 服务端口
+	Debug         bool          `json:"debug"`          // 调试模式
+	StreamTimeout time.Duration `json:"stream_timeout"` // 流式处理接口的超时时间，默认2分钟
 }
 
 // OpenAIConfig OpenAI配置
@@ -255,6 +335,7 @@ type OpenAIConfig struct {
 // DeepWikiConfig DeepWiki配置
 type DeepWikiConfig struct {
 	Enabled    bool   `json:"enabled"`
+	Server     string `json:"server"` // 使用的MCP服务器标签，默认deepwiki
 	Endpoint   string `json:"endpoint"`
 	APIKey     string `json:"api_key"`
 	Timeout    string `json:"timeout"`
@@ -268,6 +349,10 @@ type HigressConfig struct {
 	RepoName              string `json:"repo_name"`
 	CacheDuration         string `json:"cache_duration"`
 	MaxConcurrentRequests int    `json:"max_concurrent_requests"`
+	DocsPath              string `json:"docs_path"`          // 本地文档仓库中的Markdown目录，优先于归档
+	DocsArchiveURL        string `json:"docs_archive_url"`   // 文档仓库的tar.gz归档地址
+	DocsArchiveDir        string `json:"docs_archive_dir"`   // 归档中的Markdown目录
+	DocsSyncInterval      string `json:"docs_sync_interval"` // 文档镜像同步间隔，为空时不定时同步
 }
 
 // GitHubConfig GitHub配置
@@ -284,6 +369,10 @@ type KnowledgeConfig struct {
 	StoragePath    string `json:"storage_path"`
 	MaxSize        string `json:"max_size"`
 	UpdateInterval string `json:"update_interval"`
+	Embedder       string `json:"embedder"`        // 向量类型：openai、hash，为空时根据是否配置OpenAI密钥自动选择
+	EmbeddingModel string `json:"embedding_model"` // OpenAI向量模型
+	ChunkSize      int    `json:"chunk_size"`      // 分块大小（字符数）
+	ChunkOverlap   int    `json:"chunk_overlap"`   // 相邻分块重叠字符数
 }
 
 // MemoryConfig 记忆组件配置
@@ -302,6 +391,33 @@ type FusionConfig struct {
 	SimilarityThreshold float64 `json:"similarity_threshold"`
 	MaxSources          int     `json:"max_sources"`
 	ResponseFormat      string  `json:"response_format"`
+	Reranker            string  `json:"reranker"`        // 重排序器：heuristic、embedding、llm
+	DedupThreshold      float64 `json:"dedup_threshold"` // 近似重复判定的Jaccard相似度阈值
+}
+
+// RetrievalBudgetConfig 多源检索时间预算配置
+type RetrievalBudgetConfig struct {
+	Timeout        time.Duration            `json:"timeout"`         // 整体检索时间预算
+	SourceTimeouts map[string]time.Duration `json:"source_timeouts"` // 各知识源的时间预算，键为知识源名称
+	MinSources     int                      `json:"min_sources"`     // 获得足够数量的高相关知识后提前返回，0表示等待全部知识源
+	MinRelevance   float64                  `json:"min_relevance"`   // 计入提前返回数量的最低相关性（重排序后）
+	Sources        []RetrieverConfig        `json:"sources"`         // 知识源检索器，为空时使用内置的local、higress、deepwiki
+}
+
+// ToolAgentConfig 工具调用Agent配置
+type ToolAgentConfig struct {
+	MaxSteps    int           `json:"max_steps"`   // 默认的最大推理步数，每一步调用一次模型，默认6
+	MaxTokens   int           `json:"max_tokens"`  // 每次调用模型的最大输出Token数，默认1500
+	Temperature float64       `json:"temperature"` // 模型温度，默认0.2
+	Timeout     time.Duration `json:"timeout"`     // 单次运行的整体超时时间，0表示不限制
+}
+
+// RetrieverConfig 知识源检索器配置
+type RetrieverConfig struct {
+	Name    string                 `json:"name"`    // 知识源名称，作为知识项的来源标识
+	Type    string                 `json:"type"`    // 检索器类型：local、higress、deepwiki、mcp、mcp_resource或自定义注册的类型
+	Enabled bool                   `json:"enabled"` // 是否启用
+	Options map[string]interface{} `json:"options"` // 检索器选项
 }
 
 // LoggingConfig 日志配置
@@ -352,12 +468,28 @@ type GitHubIssue struct {
 
 // GitHubComment GitHub评论结构体
 type GitHubComment struct {
-	ID        int         `json:"id"`         // 评论ID
-	Body      string      `json:"body"`       // 评论内容
-	User      *GitHubUser `json:"user"`       // 评论者
-	CreatedAt string      `json:"created_at"` // 创建时间
-	UpdatedAt string      `json:"updated_at"` // 更新时间
-	HTMLURL   string      `json:"html_url"`   // HTML URL
+	ID                int         `json:"id"`                 // 评论ID
+	Body              string      `json:"body"`               // 评论内容
+	User              *GitHubUser `json:"user"`               // 评论者
+	CreatedAt         string      `json:"created_at"`         // 创建时间
+	UpdatedAt         string      `json:"updated_at"`         // 更新时间
+	HTMLURL           string      `json:"html_url"`           // HTML URL
+	AuthorAssociation string      `json:"author_association"` // 评论者与仓库的关系（OWNER、MEMBER、COLLABORATOR等）
+	Reactions         int         `json:"reactions"`          // 正面反馈数（👍、❤️、🎉或讨论中的赞同数）
+}
+
+// GitHubDiscussion GitHub讨论结构体
+type GitHubDiscussion struct {
+	Number    int              `json:"number"`     // 讨论编号
+	Title     string           `json:"title"`      // 标题
+	Body      string           `json:"body"`       // 内容
+	URL       string           `json:"url"`        // HTML URL
+	Category  string           `json:"category"`   // 分类
+	CreatedAt string           `json:"created_at"` // 创建时间
+	Closed    bool             `json:"closed"`     // 是否已关闭
+	User      *GitHubUser      `json:"user"`       // 创建者
+	Answer    *GitHubComment   `json:"answer"`     // 被采纳的回答
+	Comments  []*GitHubComment `json:"comments"`   // 评论
 }
 
 // GitHubUser GitHub用户结构体
@@ -403,6 +535,9 @@ type SearchResult struct {
 	Content        string  `json:"content"`         // 内容
 	RelevanceScore float64 `json:"relevance_score"` // 相关性分数
 	Snippet        string  `json:"snippet"`         // 片段
+	ChunkIndex     int     `json:"chunk_index"`     // 命中的分块序号
+	StartOffset    int     `json:"start_offset"`    // 分块在文档内容中的起始字节偏移
+	EndOffset      int     `json:"end_offset"`      // 分块在文档内容中的结束字节偏移
 }
 
 // KnowledgeSearchResult 知识库搜索结果结构体
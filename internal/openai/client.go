@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -19,18 +20,28 @@ type Client struct {
 }
 
 // Message 聊天消息
+// 工具调用时assistant消息携带ToolCalls，工具结果消息使用tool角色并通过ToolCallID关联调用
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
 }
 
 // ChatRequest 聊天请求
 type ChatRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
-	Stream      bool      `json:"stream,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Tools         []Tool         `json:"tools,omitempty"`
+}
+
+// StreamOptions 流式请求选项
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // ChatResponse 聊天响应
@@ -42,16 +53,20 @@ type ChatResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage Usage `json:"usage"`
+}
+
+// Usage Token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // ErrorResponse 错误响应
@@ -84,6 +99,11 @@ func (c *Client) Chat(ctx context.Context, messages []Message, maxTokens int, te
 		Temperature: temperature,
 	}
 
+	return c.createChatCompletion(ctx, request)
+}
+
+// createChatCompletion 调用chat/completions接口
+func (c *Client) createChatCompletion(ctx context.Context, request ChatRequest) (*ChatResponse, error) {
 	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求失败: %w", err)
@@ -193,6 +213,17 @@ func (c *Client) SetModel(model string) {
 	c.model = model
 }
 
+// SetBaseURL 设置API地址（用于兼容OpenAI协议的服务或测试）
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = baseURL
+}
+
+// IsConfigured 判断是否配置了可用的API密钥
+// 配置文件中未展开的环境变量占位符（如${OPENAI_API_KEY}）视为未配置
+func (c *Client) IsConfigured() bool {
+	return c.apiKey != "" && !strings.HasPrefix(c.apiKey, "${")
+}
+
 // SetTimeout 设置超时时间
 func (c *Client) SetTimeout(timeout time.Duration) {
 	c.httpClient.Timeout = timeout
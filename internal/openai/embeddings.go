@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// DefaultEmbeddingModel 默认向量模型
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// EmbeddingRequest 向量请求
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse 向量响应
+type EmbeddingResponse struct {
+	Object string `json:"object"`
+	Data   []struct {
+		Object    string    `json:"object"`
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage Usage  `json:"usage"`
+}
+
+// CreateEmbeddings 批量生成文本向量，返回顺序与输入一致
+func (c *Client) CreateEmbeddings(ctx context.Context, inputs []string, model string) ([][]float32, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+
+	jsonData, err := json.Marshal(EmbeddingRequest{Model: model, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err != nil || errorResp.Error.Message == "" {
+			return nil, fmt.Errorf("API错误: HTTP %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("API错误: %s", errorResp.Error.Message)
+	}
+
+	var response EmbeddingResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if len(response.Data) != len(inputs) {
+		return nil, fmt.Errorf("向量数量不匹配: 期望%d，实际%d", len(inputs), len(response.Data))
+	}
+
+	sort.Slice(response.Data, func(i, j int) bool {
+		return response.Data[i].Index < response.Data[j].Index
+	})
+
+	embeddings := make([][]float32, len(response.Data))
+	for i, item := range response.Data {
+		embeddings[i] = item.Embedding
+	}
+
+	return embeddings, nil
+}
@@ -0,0 +1,220 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ChatStreamChunk 流式响应中的单个分片
+type ChatStreamChunk struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// StreamDelta 流式输出的增量
+// 最后一个增量携带Usage；出错时Err非空且通道随即关闭
+type StreamDelta struct {
+	Content      string `json:"content"`                 // 增量内容
+	FinishReason string `json:"finish_reason,omitempty"` // 结束原因
+	Usage        *Usage `json:"usage,omitempty"`         // Token用量（仅最终分片）
+	Err          error  `json:"-"`                       // 流式过程中的错误
+}
+
+// ChatStream 发送流式聊天请求
+// 请求建立失败时直接返回错误；之后的增量通过通道返回，ctx取消时停止读取并关闭通道
+func (c *Client) ChatStream(ctx context.Context, messages []Message, maxTokens int, temperature float64) (<-chan StreamDelta, error) {
+	request := ChatRequest{
+		Model:         c.model,
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		Temperature:   temperature,
+		Stream:        true,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	// 流式响应持续时间不确定，由ctx控制超时而不是客户端整体超时
+	streamClient := *c.httpClient
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err != nil || errorResp.Error.Message == "" {
+			return nil, fmt.Errorf("API错误: HTTP %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("API错误: %s", errorResp.Error.Message)
+	}
+
+	deltas := make(chan StreamDelta)
+	go c.readStream(ctx, resp.Body, deltas)
+
+	return deltas, nil
+}
+
+// readStream 读取SSE响应并转换为增量
+func (c *Client) readStream(ctx context.Context, body io.ReadCloser, deltas chan<- StreamDelta) {
+	defer close(deltas)
+	defer body.Close()
+
+	send := func(delta StreamDelta) bool {
+		select {
+		case deltas <- delta:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	reader := bufio.NewReader(body)
+	var data strings.Builder
+	finished := false
+
+	// flush 处理已累积的data负载，返回false表示应停止读取
+	flush := func() bool {
+		if data.Len() == 0 {
+			return true
+		}
+		payload := data.String()
+		data.Reset()
+
+		if payload == "[DONE]" {
+			finished = true
+			return false
+		}
+
+		delta, ok := parseStreamChunk(payload)
+		if !ok {
+			return true
+		}
+		if delta.FinishReason != "" {
+			finished = true
+		}
+		return send(delta) && delta.Err == nil
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		if strings.HasPrefix(line, "data:") {
+			// 仅处理data字段，忽略注释和其他字段
+			if data.Len() > 0 {
+				data.WriteString("\n")
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		} else if line == "" && !flush() {
+			// 空行表示一个事件结束
+			return
+		}
+
+		if err != nil {
+			if !flush() || finished {
+				return
+			}
+			switch {
+			case ctx.Err() != nil:
+				send(StreamDelta{Err: ctx.Err()})
+			case errors.Is(err, io.EOF):
+				send(StreamDelta{Err: fmt.Errorf("流式响应意外结束")})
+			default:
+				send(StreamDelta{Err: fmt.Errorf("读取流式响应失败: %w", err)})
+			}
+			return
+		}
+	}
+}
+
+// parseStreamChunk 解析单个data负载
+func parseStreamChunk(payload string) (StreamDelta, bool) {
+	// 流式过程中服务端可能返回错误对象
+	var errorResp ErrorResponse
+	if err := json.Unmarshal([]byte(payload), &errorResp); err == nil && errorResp.Error.Message != "" {
+		return StreamDelta{Err: fmt.Errorf("API错误: %s", errorResp.Error.Message)}, true
+	}
+
+	var chunk ChatStreamChunk
+	if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+		return StreamDelta{Err: fmt.Errorf("解析流式分片失败: %w", err)}, true
+	}
+
+	delta := StreamDelta{Usage: chunk.Usage}
+	if len(chunk.Choices) > 0 {
+		delta.Content = chunk.Choices[0].Delta.Content
+		delta.FinishReason = chunk.Choices[0].FinishReason
+	}
+
+	// 跳过既无内容也无结束信息的分片（例如只包含role的首个分片）
+	if delta.Content == "" && delta.FinishReason == "" && delta.Usage == nil {
+		return delta, false
+	}
+
+	return delta, true
+}
+
+// GenerateTextStream 流式生成文本
+func (c *Client) GenerateTextStream(ctx context.Context, prompt string, maxTokens int, temperature float64) (<-chan StreamDelta, error) {
+	messages := []Message{
+		{
+			Role:    "user",
+			Content: prompt,
+		},
+	}
+
+	return c.ChatStream(ctx, messages, maxTokens, temperature)
+}
+
+// CollectStream 读取全部增量并拼接为完整文本
+func CollectStream(deltas <-chan StreamDelta) (string, *Usage, error) {
+	var content strings.Builder
+	var usage *Usage
+
+	for delta := range deltas {
+		if delta.Err != nil {
+			return content.String(), usage, delta.Err
+		}
+		content.WriteString(delta.Content)
+		if delta.Usage != nil {
+			usage = delta.Usage
+		}
+	}
+
+	return content.String(), usage, nil
+}
@@ -0,0 +1,143 @@
+package openai_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStreamServer 创建返回固定SSE事件的模拟OpenAI服务
+func newStreamServer(events []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+			flusher.Flush()
+		}
+	}))
+}
+
+// TestChatStream 测试流式响应解析
+func TestChatStream(t *testing.T) {
+	server := newStreamServer([]string{
+		`{"choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":", Higress"}}]}`,
+		`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`,
+		`[DONE]`,
+	})
+	defer server.Close()
+
+	client := openai.NewClient("test-key", "gpt-4o")
+	client.SetBaseURL(server.URL)
+
+	deltas, err := client.ChatStream(context.Background(), []openai.Message{{Role: "user", Content: "hi"}}, 100, 0.5)
+	require.NoError(t, err)
+
+	content, usage, err := openai.CollectStream(deltas)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, Higress", content)
+	require.NotNil(t, usage)
+	assert.Equal(t, 8, usage.TotalTokens)
+}
+
+// TestChatStreamErrors 测试流式错误传播
+func TestChatStreamErrors(t *testing.T) {
+	t.Run("HTTPError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"invalid api key","type":"invalid_request_error"}}`)
+		}))
+		defer server.Close()
+
+		client := openai.NewClient("bad-key", "gpt-4o")
+		client.SetBaseURL(server.URL)
+
+		_, err := client.GenerateTextStream(context.Background(), "hi", 100, 0.5)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid api key")
+	})
+
+	t.Run("MidStreamError", func(t *testing.T) {
+		server := newStreamServer([]string{
+			`{"choices":[{"index":0,"delta":{"content":"partial"}}]}`,
+			`{"error":{"message":"server overloaded"}}`,
+		})
+		defer server.Close()
+
+		client := openai.NewClient("test-key", "gpt-4o")
+		client.SetBaseURL(server.URL)
+
+		deltas, err := client.GenerateTextStream(context.Background(), "hi", 100, 0.5)
+		require.NoError(t, err)
+
+		content, _, err := openai.CollectStream(deltas)
+		require.Error(t, err)
+		assert.Equal(t, "partial", content)
+		assert.Contains(t, err.Error(), "server overloaded")
+	})
+
+	t.Run("UnexpectedEOF", func(t *testing.T) {
+		server := newStreamServer([]string{
+			`{"choices":[{"index":0,"delta":{"content":"cut"}}]}`,
+		})
+		defer server.Close()
+
+		client := openai.NewClient("test-key", "gpt-4o")
+		client.SetBaseURL(server.URL)
+
+		deltas, err := client.GenerateTextStream(context.Background(), "hi", 100, 0.5)
+		require.NoError(t, err)
+
+		_, _, err = openai.CollectStream(deltas)
+		assert.Error(t, err)
+	})
+
+	t.Run("ContextCancel", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"slow\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+
+		client := openai.NewClient("test-key", "gpt-4o")
+		client.SetBaseURL(server.URL)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		deltas, err := client.GenerateTextStream(ctx, "hi", 100, 0.5)
+		require.NoError(t, err)
+
+		first := <-deltas
+		assert.Equal(t, "slow", first.Content)
+		cancel()
+
+		// 取消后通道应在合理时间内关闭
+		select {
+		case <-drain(deltas):
+		case <-time.After(2 * time.Second):
+			t.Fatal("取消后流式通道未关闭")
+		}
+	})
+}
+
+// drain 读取通道直到关闭
+func drain(deltas <-chan openai.StreamDelta) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range deltas {
+		}
+		close(done)
+	}()
+	return done
+}
@@ -0,0 +1,78 @@
+package openai
+
+import (
+	"context"
+	"regexp"
+)
+
+// ToolTypeFunction 函数调用工具类型
+const ToolTypeFunction = "function"
+
+// MaxFunctionNameLength 函数名最大长度
+const MaxFunctionNameLength = 64
+
+// invalidFunctionNameChars 函数名中不允许的字符，函数名只能包含字母、数字、下划线和连字符
+var invalidFunctionNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// Tool 函数调用工具定义
+type Tool struct {
+	Type     string             `json:"type"`
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition 函数定义，Parameters为JSON Schema
+type FunctionDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall 模型发起的工具调用
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 函数调用，Arguments为JSON编码的参数
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// NewFunctionTool 创建函数调用工具
+func NewFunctionTool(name, description string, parameters map[string]interface{}) Tool {
+	if parameters == nil {
+		parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return Tool{
+		Type: ToolTypeFunction,
+		Function: FunctionDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	}
+}
+
+// FunctionName 将任意工具名转换为合法的函数名，不允许的字符替换为下划线，超长时截断
+func FunctionName(name string) string {
+	name = invalidFunctionNameChars.ReplaceAllString(name, "_")
+	if len(name) > MaxFunctionNameLength {
+		name = name[:MaxFunctionNameLength]
+	}
+	return name
+}
+
+// ChatWithTools 发送带函数调用工具的聊天请求，模型可在响应中返回ToolCalls
+func (c *Client) ChatWithTools(ctx context.Context, messages []Message, tools []Tool, maxTokens int, temperature float64) (*ChatResponse, error) {
+	request := ChatRequest{
+		Model:       c.model,
+		Messages:    messages,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		Tools:       tools,
+	}
+
+	return c.createChatCompletion(ctx, request)
+}
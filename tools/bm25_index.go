@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+)
+
+const (
+	// bm25K1 词频饱和参数
+	bm25K1 = 1.2
+	// bm25B 文档长度归一化参数
+	bm25B = 0.75
+)
+
+// BM25Index 分块倒排索引
+// 用于精确匹配插件名、配置项和错误码等词项，与向量检索互补；索引只保存在内存中，启动时从文档存储重建
+type BM25Index struct {
+	chunkSize    int
+	chunkOverlap int
+	documents    map[string]bm25Document
+	postings     map[string]map[string]int // 词元 -> 分块标识 -> 词频
+	chunks       map[string]bm25Chunk      // 分块标识 -> 分块
+	totalLength  int
+	mutex        sync.RWMutex
+}
+
+// bm25Document 已索引的文档
+type bm25Document struct {
+	fingerprint string
+	chunkKeys   []string
+}
+
+// bm25Chunk 已索引的分块
+type bm25Chunk struct {
+	chunk  Chunk
+	length int
+	terms  []string // 去重后的词元，用于删除倒排项
+}
+
+// NewBM25Index 创建BM25索引，分块参数需与向量索引一致以便融合排序
+func NewBM25Index(chunkSize, chunkOverlap int) *BM25Index {
+	return &BM25Index{
+		chunkSize:    chunkSize,
+		chunkOverlap: chunkOverlap,
+		documents:    make(map[string]bm25Document),
+		postings:     make(map[string]map[string]int),
+		chunks:       make(map[string]bm25Chunk),
+	}
+}
+
+// Sync 使索引与文档集合保持一致，只重新索引新增或变化的文档
+func (bi *BM25Index) Sync(documents []model.Document) {
+	bi.mutex.Lock()
+	defer bi.mutex.Unlock()
+
+	current := make(map[string]bool, len(documents))
+	for _, doc := range documents {
+		current[doc.ID] = true
+		fingerprint := documentFingerprint(doc, bi.chunkSize, bi.chunkOverlap)
+		if indexed, ok := bi.documents[doc.ID]; ok && indexed.fingerprint == fingerprint {
+			continue
+		}
+		bi.removeDocument(doc.ID)
+		bi.addDocument(doc, fingerprint)
+	}
+
+	for id := range bi.documents {
+		if !current[id] {
+			bi.removeDocument(id)
+		}
+	}
+}
+
+// Search 按BM25得分返回匹配的分块，Relevance为分块包含的查询词元的IDF之和占全部查询词元IDF之和的比例
+func (bi *BM25Index) Search(query string, limit int) []ChunkHit {
+	return bi.SearchFiltered(query, limit, nil)
+}
+
+// SearchFiltered 只返回filter接受的文档中的分块，过滤在截断到limit之前进行；filter为nil时不过滤
+// IDF等统计量仍基于全部分块计算，过滤不改变得分
+func (bi *BM25Index) SearchFiltered(query string, limit int, filter func(documentID string) bool) []ChunkHit {
+	bi.mutex.RLock()
+	defer bi.mutex.RUnlock()
+
+	chunkCount := len(bi.chunks)
+	if chunkCount == 0 {
+		return nil
+	}
+	avgLength := float64(bi.totalLength) / float64(chunkCount)
+
+	scores := make(map[string]float64)
+	matched := make(map[string]float64)
+	totalIDF := 0.0
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		// 查询中重复的词元只计算一次
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		// 知识库中没有的词元IDF最大，计入覆盖率的分母
+		postings := bi.postings[term]
+		idf := math.Log(1 + (float64(chunkCount)-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		totalIDF += idf
+		for key, frequency := range postings {
+			matched[key] += idf
+			tf := float64(frequency)
+			length := float64(bi.chunks[key].length)
+			scores[key] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+		}
+	}
+
+	hits := make([]ChunkHit, 0, len(scores))
+	for key, score := range scores {
+		if filter != nil && !filter(bi.chunks[key].chunk.DocumentID) {
+			continue
+		}
+		hits = append(hits, ChunkHit{Chunk: bi.chunks[key].chunk, Score: score, Relevance: matched[key] / totalIDF})
+	}
+	sortChunkHits(hits)
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// ChunkCount 获取已索引的分块数量
+func (bi *BM25Index) ChunkCount() int {
+	bi.mutex.RLock()
+	defer bi.mutex.RUnlock()
+
+	return len(bi.chunks)
+}
+
+// addDocument 索引文档的所有分块，标题词元计入每个分块
+func (bi *BM25Index) addDocument(doc model.Document, fingerprint string) {
+	titleTokens := Tokenize(doc.Title)
+	indexed := bm25Document{fingerprint: fingerprint}
+
+	for _, chunk := range ChunkDocument(doc, bi.chunkSize, bi.chunkOverlap) {
+		key := chunk.key()
+		tokens := append(Tokenize(chunk.Text), titleTokens...)
+
+		var terms []string
+		for _, token := range tokens {
+			postings, ok := bi.postings[token]
+			if !ok {
+				postings = make(map[string]int)
+				bi.postings[token] = postings
+			}
+			if postings[key] == 0 {
+				terms = append(terms, token)
+			}
+			postings[key]++
+		}
+
+		bi.chunks[key] = bm25Chunk{chunk: chunk, length: len(tokens), terms: terms}
+		bi.totalLength += len(tokens)
+		indexed.chunkKeys = append(indexed.chunkKeys, key)
+	}
+
+	bi.documents[doc.ID] = indexed
+}
+
+// removeDocument 移除文档的所有分块
+func (bi *BM25Index) removeDocument(id string) {
+	indexed, ok := bi.documents[id]
+	if !ok {
+		return
+	}
+
+	for _, key := range indexed.chunkKeys {
+		chunk := bi.chunks[key]
+		for _, term := range chunk.terms {
+			postings := bi.postings[term]
+			delete(postings, key)
+			if len(postings) == 0 {
+				delete(bi.postings, term)
+			}
+		}
+		bi.totalLength -= chunk.length
+		delete(bi.chunks, key)
+	}
+
+	delete(bi.documents, id)
+}
+
+// sortChunkHits 按得分降序排序，得分相同时按文档和分块序号保证结果稳定
+func sortChunkHits(hits []ChunkHit) {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score == hits[j].Score {
+			if hits[i].Chunk.DocumentID == hits[j].Chunk.DocumentID {
+				return hits[i].Chunk.Index < hits[j].Chunk.Index
+			}
+			return hits[i].Chunk.DocumentID < hits[j].Chunk.DocumentID
+		}
+		return hits[i].Score > hits[j].Score
+	})
+}
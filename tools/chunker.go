@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+)
+
+const (
+	// DefaultChunkSize 默认分块大小（字符数）
+	DefaultChunkSize = 800
+	// DefaultChunkOverlap 默认相邻分块重叠字符数
+	DefaultChunkOverlap = 100
+)
+
+// Chunk 文档分块
+type Chunk struct {
+	DocumentID string `json:"document_id"` // 所属文档ID
+	Index      int    `json:"index"`       // 分块序号
+	Start      int    `json:"start"`       // 在文档内容中的起始字节偏移
+	End        int    `json:"end"`         // 在文档内容中的结束字节偏移（不含）
+	Text       string `json:"text"`        // 分块文本
+}
+
+// ChunkHit 检索命中的分块
+// Score只用于同一路检索内排序；Relevance是与排名无关的0-1相关度，
+// BM25为按IDF加权的查询词元覆盖率，向量检索为余弦相似度
+type ChunkHit struct {
+	Chunk     Chunk   `json:"chunk"`     // 命中的分块
+	Score     float64 `json:"score"`     // 检索得分
+	Relevance float64 `json:"relevance"` // 相关度
+}
+
+// key 分块在知识库中的唯一标识
+func (c Chunk) key() string {
+	return fmt.Sprintf("%s#%d", c.DocumentID, c.Index)
+}
+
+// ChunkDocument 将文档内容切分为带偏移的分块
+// size和overlap以字符计；切分点优先选择段落、换行和句末标点，避免截断句子
+func ChunkDocument(doc model.Document, size, overlap int) []Chunk {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	content := doc.Content
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	// 记录每个字符的字节偏移，便于按字符切分后换算
+	offsets := make([]int, 0, utf8.RuneCountInString(content)+1)
+	for offset := range content {
+		offsets = append(offsets, offset)
+	}
+	offsets = append(offsets, len(content))
+	runeCount := len(offsets) - 1
+	runes := []rune(content)
+
+	var chunks []Chunk
+	for start := 0; start < runeCount; {
+		end := start + size
+		if end >= runeCount {
+			end = runeCount
+		} else if boundary := findBoundary(runes, start+size*7/10, end); boundary > start {
+			end = boundary
+		}
+
+		// 去掉首尾空白，偏移指向实际文本
+		textStart, textEnd := start, end
+		for textStart < textEnd && unicode.IsSpace(runes[textStart]) {
+			textStart++
+		}
+		for textEnd > textStart && unicode.IsSpace(runes[textEnd-1]) {
+			textEnd--
+		}
+		if textStart < textEnd {
+			chunks = append(chunks, Chunk{
+				DocumentID: doc.ID,
+				Index:      len(chunks),
+				Start:      offsets[textStart],
+				End:        offsets[textEnd],
+				Text:       content[offsets[textStart]:offsets[textEnd]],
+			})
+		}
+
+		if end >= runeCount {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+
+	return chunks
+}
+
+// findBoundary 在[from, to)内从后向前寻找切分点，返回切分点之后的位置，找不到时返回-1
+func findBoundary(runes []rune, from, to int) int {
+	// 按优先级依次尝试段落、换行、句末标点
+	priorities := []func(i int) bool{
+		func(i int) bool { return runes[i] == '\n' && i > 0 && runes[i-1] == '\n' },
+		func(i int) bool { return runes[i] == '\n' },
+		func(i int) bool { return strings.ContainsRune("。！？；.!?;", runes[i]) },
+	}
+
+	for _, isBoundary := range priorities {
+		for i := to - 1; i >= from; i-- {
+			if isBoundary(i) {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
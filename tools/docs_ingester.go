@@ -0,0 +1,433 @@
+package tools
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+)
+
+const (
+	// DocsSource 文档镜像在知识库中的来源标识
+	DocsSource = "higress-docs"
+	// docsIDPrefix 文档镜像的文档ID前缀
+	docsIDPrefix = DocsSource + ":"
+	// maxDocsFileSize 单个Markdown文件的最大字节数，超过时跳过
+	maxDocsFileSize = 2 << 20
+	// maxSectionHeadingLevel 按该级别及以上的标题切分章节
+	maxSectionHeadingLevel = 3
+)
+
+var (
+	// headingPattern 匹配Markdown标题行
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	// customAnchorPattern 匹配标题中的自定义锚点 {#anchor}
+	customAnchorPattern = regexp.MustCompile(`\s*\{#([^}]+)\}\s*$`)
+)
+
+// IngestReport 文档同步结果
+type IngestReport struct {
+	Files     int           `json:"files"`     // 处理的Markdown文件数
+	Sections  int           `json:"sections"`  // 切分出的章节数
+	Added     int           `json:"added"`     // 新增章节数
+	Updated   int           `json:"updated"`   // 内容变化的章节数
+	Unchanged int           `json:"unchanged"` // 未变化的章节数
+	Removed   int           `json:"removed"`   // 已从文档源删除的章节数
+	Duration  time.Duration `json:"duration"`  // 耗时
+}
+
+// DocsIngester 将Higress文档Markdown同步到本地知识库
+// 每个章节存为一个文档，ID由文件路径和标题锚点组成，重复同步时只写入变化的章节并删除已不存在的章节
+type DocsIngester struct {
+	knowledgeBase *KnowledgeBase
+	baseURL       string
+	httpClient    *http.Client
+}
+
+// NewDocsIngester 创建文档同步器，baseURL为文档站点地址，章节URL为baseURL+文件相对路径+锚点
+func NewDocsIngester(knowledgeBase *KnowledgeBase, baseURL string) *DocsIngester {
+	return &DocsIngester{
+		knowledgeBase: knowledgeBase,
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+	}
+}
+
+// IngestDirectory 同步本地文档目录（如Higress文档仓库的检出）中的所有Markdown文件
+func (di *DocsIngester) IngestDirectory(ctx context.Context, root string) (*IngestReport, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), ".") && filePath != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdownFile(entry.Name()) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.Size() > maxDocsFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("读取文档失败: %w", err)
+		}
+
+		relative, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relative)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历文档目录失败: %w", err)
+	}
+
+	return di.sync(files)
+}
+
+// IngestArchive 下载文档仓库的tar.gz归档并同步subdir目录下的Markdown文件
+// 归档的第一级目录（如GitHub归档中的repo-main/）会被忽略
+func (di *DocsIngester) IngestArchive(ctx context.Context, archiveURL, subdir string) (*IngestReport, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	resp, err := di.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载文档归档失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载文档归档失败: %d", resp.StatusCode)
+	}
+
+	gzipReader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("解压文档归档失败: %w", err)
+	}
+	defer gzipReader.Close()
+
+	prefix := strings.Trim(subdir, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	files := make(map[string][]byte)
+	reader := tar.NewReader(gzipReader)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取文档归档失败: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxDocsFileSize || !isMarkdownFile(header.Name) {
+			continue
+		}
+
+		// 去掉归档的第一级目录
+		name := header.Name
+		if index := strings.Index(name, "/"); index >= 0 {
+			name = name[index+1:]
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("读取文档归档失败: %w", err)
+		}
+		files[strings.TrimPrefix(name, prefix)] = data
+	}
+
+	return di.sync(files)
+}
+
+// sync 将文件切分为章节并与知识库中已有的文档镜像比对更新
+func (di *DocsIngester) sync(files map[string][]byte) (*IngestReport, error) {
+	startTime := time.Now()
+	report := &IngestReport{Files: len(files)}
+
+	sections := make(map[string]model.Document)
+	for relative, data := range files {
+		for _, section := range SplitMarkdownSections(relative, data, di.baseURL) {
+			sections[section.ID] = section
+		}
+	}
+	report.Sections = len(sections)
+
+	// 文档源为空时通常是路径或归档配置错误，保留已有镜像
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("未找到可同步的Markdown文档")
+	}
+
+	store := di.knowledgeBase.GetStore()
+	for id, section := range sections {
+		existing, ok := store.Get(id)
+		if ok && existing.Title == section.Title && existing.Content == section.Content && existing.URL == section.URL {
+			report.Unchanged++
+			continue
+		}
+		if ok {
+			section.CreatedAt = existing.CreatedAt
+			report.Updated++
+		} else {
+			report.Added++
+		}
+		if _, err := di.knowledgeBase.AddDocument(section); err != nil {
+			return nil, fmt.Errorf("写入文档章节失败: %w", err)
+		}
+	}
+
+	for _, doc := range store.List() {
+		if doc.Source != DocsSource || !strings.HasPrefix(doc.ID, docsIDPrefix) {
+			continue
+		}
+		if _, ok := sections[doc.ID]; ok {
+			continue
+		}
+		if err := di.knowledgeBase.DeleteDocument(doc.ID); err != nil {
+			return nil, fmt.Errorf("删除过期文档章节失败: %w", err)
+		}
+		report.Removed++
+	}
+
+	report.Duration = time.Since(startTime)
+	return report, nil
+}
+
+// SplitMarkdownSections 将Markdown文件按一到三级标题切分为章节文档
+// 标题前的内容作为文件的引言章节；代码块中的#不视为标题；章节URL带有与文档站点一致的标题锚点
+func SplitMarkdownSections(relativePath string, data []byte, baseURL string) []model.Document {
+	relativePath = path.Clean(strings.TrimPrefix(filepath.ToSlash(relativePath), "/"))
+	frontMatter, body := splitFrontMatter(string(data))
+
+	pageTitle := frontMatter["title"]
+	pagePath := strings.TrimSuffix(relativePath, path.Ext(relativePath))
+	pagePath = strings.TrimSuffix(pagePath, "/index")
+	pageURL := strings.TrimRight(baseURL, "/") + "/" + pagePath
+	tags := docsTags(relativePath)
+
+	type section struct {
+		heading string
+		anchor  string
+		lines   []string
+	}
+
+	var sections []*section
+	current := &section{}
+	slugs := make(map[string]int)
+	fence := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), maxDocsFileSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		// 跟踪围栏代码块
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			marker := trimmed[:3]
+			if fence == "" {
+				fence = marker
+			} else if fence == marker {
+				fence = ""
+			}
+			current.lines = append(current.lines, line)
+			continue
+		}
+
+		if fence == "" {
+			if match := headingPattern.FindStringSubmatch(line); match != nil {
+				level := len(match[1])
+				heading := match[2]
+				anchor := ""
+				if custom := customAnchorPattern.FindStringSubmatch(heading); custom != nil {
+					anchor = custom[1]
+					heading = customAnchorPattern.ReplaceAllString(heading, "")
+				}
+				if anchor == "" {
+					anchor = uniqueSlug(slugs, heading)
+				}
+
+				if level == 1 && pageTitle == "" {
+					pageTitle = heading
+				}
+				if level <= maxSectionHeadingLevel {
+					sections = append(sections, current)
+					current = &section{heading: heading, anchor: anchor}
+					// 一级标题即页面标题，不重复出现在章节内容中
+					if level == 1 {
+						current.heading = ""
+						current.anchor = ""
+					}
+					continue
+				}
+			}
+		}
+		current.lines = append(current.lines, line)
+	}
+	sections = append(sections, current)
+
+	if pageTitle == "" {
+		pageTitle = path.Base(pagePath)
+	}
+
+	var documents []model.Document
+	for _, section := range sections {
+		content := strings.TrimSpace(strings.Join(section.lines, "\n"))
+		if content == "" {
+			continue
+		}
+
+		title := pageTitle
+		url := pageURL
+		id := docsIDPrefix + relativePath
+		if section.heading != "" {
+			title = pageTitle + " - " + section.heading
+			url += "#" + section.anchor
+			id += "#" + section.anchor
+		}
+
+		documents = append(documents, model.Document{
+			ID:      id,
+			Title:   title,
+			Content: content,
+			URL:     url,
+			Source:  DocsSource,
+			Tags:    tags,
+			Metadata: map[string]interface{}{
+				"path":    relativePath,
+				"anchor":  section.anchor,
+				"heading": section.heading,
+			},
+		})
+	}
+
+	// 同一文件内锚点相同（如引言与一级标题）时合并为一个文档
+	merged := make([]model.Document, 0, len(documents))
+	positions := make(map[string]int)
+	for _, doc := range documents {
+		if position, ok := positions[doc.ID]; ok {
+			merged[position].Content += "\n\n" + doc.Content
+			continue
+		}
+		positions[doc.ID] = len(merged)
+		merged = append(merged, doc)
+	}
+	return merged
+}
+
+// splitFrontMatter 拆分YAML front matter，只解析单行的key: value
+func splitFrontMatter(content string) (map[string]string, string) {
+	values := make(map[string]string)
+	content = strings.TrimPrefix(content, "\ufeff")
+	if !strings.HasPrefix(content, "---") {
+		return values, content
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "---" {
+			return values, strings.Join(lines[i+1:], "")
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	// 没有结束分隔符时不是front matter
+	return map[string]string{}, content
+}
+
+// uniqueSlug 生成与文档站点一致的标题锚点，重复的锚点追加序号
+func uniqueSlug(slugs map[string]int, heading string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(stripInlineMarkdown(heading)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			builder.WriteRune(r)
+		case unicode.IsSpace(r):
+			builder.WriteRune('-')
+		}
+	}
+
+	slug := builder.String()
+	count := slugs[slug]
+	slugs[slug] = count + 1
+	if count > 0 {
+		return fmt.Sprintf("%s-%d", slug, count)
+	}
+	return slug
+}
+
+// stripInlineMarkdown 去掉标题中的行内代码、链接等标记
+func stripInlineMarkdown(text string) string {
+	var buffer bytes.Buffer
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '`', '*':
+			continue
+		case '[':
+			// [text](url) 只保留text
+			if end := strings.Index(text[i:], "]("); end > 0 {
+				if close := strings.Index(text[i+end:], ")"); close > 0 {
+					buffer.WriteString(text[i+1 : i+end])
+					i += end + close
+					continue
+				}
+			}
+		}
+		buffer.WriteByte(text[i])
+	}
+	return buffer.String()
+}
+
+// docsTags 根据文件路径生成标签，如 latest/zh-cn/plugins/xxx.md -> higress, docs, zh-cn, plugins
+func docsTags(relativePath string) []string {
+	tags := []string{"higress", "docs"}
+	for _, segment := range strings.Split(path.Dir(relativePath), "/") {
+		if segment == "." || segment == "" || segment == "latest" || segment == "docs" {
+			continue
+		}
+		tags = append(tags, segment)
+	}
+	return tags
+}
+
+// isMarkdownFile 判断是否为Markdown文件
+func isMarkdownFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".mdx"
+}
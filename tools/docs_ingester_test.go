@@ -0,0 +1,125 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rateLimitDoc = `---
+title: 基于Key限流
+description: key-rate-limit 插件
+---
+
+本插件实现了基于特定键值实现限流。
+
+## 配置字段
+
+| 名称 | 数据类型 |
+| limit_by_header | string |
+
+` + "```yaml" + `
+# 这不是标题
+limit_by_header: x-api-key
+` + "```" + `
+
+## 配置示例 {#example}
+
+按请求头 x-api-key 限流：limit_keys 中配置每个key的 query_per_second。
+
+### 识别请求参数 ` + "`apikey`" + `
+
+limit_by_param: apikey
+
+## 配置字段
+
+重复的标题使用带序号的锚点。
+`
+
+// TestSplitMarkdownSections 测试按标题切分文档章节并生成锚点链接
+func TestSplitMarkdownSections(t *testing.T) {
+	sections := tools.SplitMarkdownSections("latest/zh-cn/plugins/key-rate-limit.md", []byte(rateLimitDoc), "https://higress.io/docs/")
+	require.Len(t, sections, 5)
+
+	intro := sections[0]
+	assert.Equal(t, "higress-docs:latest/zh-cn/plugins/key-rate-limit.md", intro.ID)
+	assert.Equal(t, "基于Key限流", intro.Title)
+	assert.Equal(t, "https://higress.io/docs/latest/zh-cn/plugins/key-rate-limit", intro.URL)
+	assert.Equal(t, tools.DocsSource, intro.Source)
+	assert.Equal(t, []string{"higress", "docs", "zh-cn", "plugins"}, intro.Tags)
+
+	// 代码块中的#不切分章节
+	fields := sections[1]
+	assert.Equal(t, "基于Key限流 - 配置字段", fields.Title)
+	assert.Equal(t, "https://higress.io/docs/latest/zh-cn/plugins/key-rate-limit#配置字段", fields.URL)
+	assert.Contains(t, fields.Content, "# 这不是标题")
+
+	assert.Equal(t, "https://higress.io/docs/latest/zh-cn/plugins/key-rate-limit#example", sections[2].URL)
+	assert.Equal(t, "基于Key限流 - 配置示例", sections[2].Title)
+	assert.Equal(t, "https://higress.io/docs/latest/zh-cn/plugins/key-rate-limit#识别请求参数-apikey", sections[3].URL)
+	assert.Equal(t, "https://higress.io/docs/latest/zh-cn/plugins/key-rate-limit#配置字段-1", sections[4].URL)
+}
+
+// TestDocsIngesterSync 测试文档目录同步：增量更新并删除已不存在的章节
+func TestDocsIngesterSync(t *testing.T) {
+	root := t.TempDir()
+	writeDoc := func(name, content string) {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	writeDoc("plugins/key-rate-limit.md", rateLimitDoc)
+	writeDoc("ops/deploy.md", "# 部署\n\n使用 helm 安装 Higress。\n\n## 升级\n\nhelm upgrade higress\n")
+	writeDoc("README.txt", "不是Markdown")
+
+	knowledgeBase := tools.NewKnowledgeBase("")
+	t.Cleanup(knowledgeBase.Close)
+	_, err := knowledgeBase.AddDocument(model.Document{ID: "user-doc", Title: "团队约定", Content: "限流统一使用 key-rate-limit 插件"})
+	require.NoError(t, err)
+
+	ingester := tools.NewDocsIngester(knowledgeBase, "https://higress.io/docs")
+	report, err := ingester.IngestDirectory(context.Background(), root)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Files)
+	assert.Equal(t, 7, report.Sections)
+	assert.Equal(t, 7, report.Added)
+	assert.Equal(t, 8, knowledgeBase.GetDocumentCount())
+
+	// 重复同步时未变化的章节不重写
+	report, err = ingester.IngestDirectory(context.Background(), root)
+	require.NoError(t, err)
+	assert.Equal(t, 7, report.Unchanged)
+	assert.Zero(t, report.Added+report.Updated+report.Removed)
+
+	// 删除章节和文件后镜像随之更新，用户文档保留
+	writeDoc("ops/deploy.md", "# 部署\n\n使用 helm 安装 Higress，需要 Kubernetes 1.22 以上。\n")
+	require.NoError(t, os.Remove(filepath.Join(root, "plugins/key-rate-limit.md")))
+	report, err = ingester.IngestDirectory(context.Background(), root)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 6, report.Removed)
+	assert.Equal(t, 2, knowledgeBase.GetDocumentCount())
+
+	deploy, err := knowledgeBase.GetDocument("higress-docs:ops/deploy.md")
+	require.NoError(t, err)
+	assert.Contains(t, deploy.Content, "Kubernetes 1.22")
+
+	// 文档源为空时保留已有镜像
+	_, err = ingester.IngestDirectory(context.Background(), t.TempDir())
+	assert.Error(t, err)
+	assert.Equal(t, 2, knowledgeBase.GetDocumentCount())
+
+	// 按来源过滤检索
+	result, err := knowledgeBase.SearchKnowledgeFiltered(context.Background(), "helm 安装", 5, func(doc model.Document) bool {
+		return doc.Source == tools.DocsSource
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "higress-docs:ops/deploy.md", result.Results[0].DocumentID)
+}
@@ -0,0 +1,398 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/google/uuid"
+)
+
+// ErrStorageFull 写入文档后会超出知识库容量上限
+var ErrStorageFull = errors.New("知识库存储空间不足")
+
+// documentsDir 存储目录下保存文档的子目录
+const documentsDir = "documents"
+
+// DocumentStore 文档存储
+// 每个文档以单独的JSON文件保存在StoragePath/documents下，写入时先写临时文件再重命名，
+// 进程重启或其他进程（如文档同步命令）写入文件后可通过Reload重新加载。
+// path为空时仅在内存中保存。
+type DocumentStore struct {
+	path      string
+	maxSize   int64
+	documents map[string]model.Document
+	sizes     map[string]int64
+	size      int64
+	listeners []func()
+	mutex     sync.RWMutex
+}
+
+// NewDocumentStore 创建文档存储并加载已有文档
+// maxSize小于等于0表示不限制容量
+func NewDocumentStore(path string, maxSize int64) (*DocumentStore, error) {
+	store := &DocumentStore{
+		path:      path,
+		maxSize:   maxSize,
+		documents: make(map[string]model.Document),
+		sizes:     make(map[string]int64),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(path, documentsDir), 0755); err != nil {
+		return nil, fmt.Errorf("创建知识库目录失败: %w", err)
+	}
+
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// NewMemoryDocumentStore 创建仅保存在内存中的文档存储
+func NewMemoryDocumentStore() *DocumentStore {
+	store, _ := NewDocumentStore("", 0)
+	return store
+}
+
+// Put 保存文档，ID为空时自动生成
+func (s *DocumentStore) Put(doc model.Document) (model.Document, error) {
+	now := time.Now()
+	if doc.ID == "" {
+		doc.ID = uuid.New().String()
+	}
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = now
+	}
+	doc.UpdatedAt = now
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return doc, fmt.Errorf("序列化文档失败: %w", err)
+	}
+	docSize := int64(len(data))
+
+	s.mutex.Lock()
+	newSize := s.size - s.sizes[doc.ID] + docSize
+	if s.maxSize > 0 && newSize > s.maxSize {
+		s.mutex.Unlock()
+		return doc, fmt.Errorf("%w: 需要%d字节，上限%d字节", ErrStorageFull, newSize, s.maxSize)
+	}
+
+	if err := s.writeFile(doc.ID, data); err != nil {
+		s.mutex.Unlock()
+		return doc, err
+	}
+
+	s.documents[doc.ID] = doc
+	s.sizes[doc.ID] = docSize
+	s.size = newSize
+	s.mutex.Unlock()
+
+	s.notify()
+	return doc, nil
+}
+
+// Get 获取文档
+func (s *DocumentStore) Get(id string) (model.Document, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	doc, exists := s.documents[id]
+	return doc, exists
+}
+
+// Delete 删除文档
+func (s *DocumentStore) Delete(id string) error {
+	s.mutex.Lock()
+	if _, exists := s.documents[id]; !exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("文档未找到: %s", id)
+	}
+
+	if s.path != "" {
+		if err := os.Remove(s.filePath(id)); err != nil && !os.IsNotExist(err) {
+			s.mutex.Unlock()
+			return fmt.Errorf("删除文档文件失败: %w", err)
+		}
+	}
+
+	s.size -= s.sizes[id]
+	delete(s.documents, id)
+	delete(s.sizes, id)
+	s.mutex.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// List 按创建时间返回所有文档
+func (s *DocumentStore) List() []model.Document {
+	s.mutex.RLock()
+	documents := make([]model.Document, 0, len(s.documents))
+	for _, doc := range s.documents {
+		documents = append(documents, doc)
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(documents, func(i, j int) bool {
+		if documents[i].CreatedAt.Equal(documents[j].CreatedAt) {
+			return documents[i].ID < documents[j].ID
+		}
+		return documents[i].CreatedAt.Before(documents[j].CreatedAt)
+	})
+
+	return documents
+}
+
+// IndexPath 向量索引文件路径，内存存储返回空
+func (s *DocumentStore) IndexPath() string {
+	if s.path == "" {
+		return ""
+	}
+	return filepath.Join(s.path, "index", "vectors.gob")
+}
+
+// Count 获取文档数量
+func (s *DocumentStore) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.documents)
+}
+
+// Size 获取已用容量（字节）
+func (s *DocumentStore) Size() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.size
+}
+
+// Clear 删除所有文档
+func (s *DocumentStore) Clear() error {
+	s.mutex.Lock()
+	if s.path != "" {
+		dir := filepath.Join(s.path, documentsDir)
+		if err := os.RemoveAll(dir); err != nil {
+			s.mutex.Unlock()
+			return fmt.Errorf("清空知识库目录失败: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			s.mutex.Unlock()
+			return fmt.Errorf("创建知识库目录失败: %w", err)
+		}
+	}
+
+	s.documents = make(map[string]model.Document)
+	s.sizes = make(map[string]int64)
+	s.size = 0
+	s.mutex.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Reload 从磁盘重新加载所有文档，文档有变化时通知变更回调
+// 无法解析的文件会被跳过，超出容量上限的文档按创建时间从新到旧被忽略。
+// 读取目录期间持有锁，Put和Delete在锁内写文件，重新加载不会丢失并发写入的文档
+func (s *DocumentStore) Reload() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.mutex.Lock()
+	entries, err := os.ReadDir(filepath.Join(s.path, documentsDir))
+	if err != nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("读取知识库目录失败: %w", err)
+	}
+
+	type loadedDocument struct {
+		doc  model.Document
+		size int64
+	}
+	var loaded []loadedDocument
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.path, documentsDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var doc model.Document
+		if err := json.Unmarshal(data, &doc); err != nil || doc.ID == "" {
+			continue
+		}
+		loaded = append(loaded, loadedDocument{doc: doc, size: int64(len(data))})
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].doc.CreatedAt.Before(loaded[j].doc.CreatedAt)
+	})
+
+	documents := make(map[string]model.Document, len(loaded))
+	sizes := make(map[string]int64, len(loaded))
+	var size int64
+	for _, item := range loaded {
+		if s.maxSize > 0 && size+item.size > s.maxSize {
+			break
+		}
+		documents[item.doc.ID] = item.doc
+		sizes[item.doc.ID] = item.size
+		size += item.size
+	}
+
+	changed := !s.sameDocuments(documents, sizes)
+	s.documents = documents
+	s.sizes = sizes
+	s.size = size
+	s.mutex.Unlock()
+
+	// 磁盘上的文档没有变化时不通知，避免定时重新加载触发重建索引
+	if changed {
+		s.notify()
+	}
+	return nil
+}
+
+// sameDocuments 判断重新加载的文档与当前文档是否一致，调用方需持有锁
+// 文档每次保存都会更新UpdatedAt，按ID、大小和更新时间比较即可
+func (s *DocumentStore) sameDocuments(documents map[string]model.Document, sizes map[string]int64) bool {
+	if len(documents) != len(s.documents) {
+		return false
+	}
+	for id, doc := range documents {
+		current, exists := s.documents[id]
+		if !exists || sizes[id] != s.sizes[id] || !doc.UpdatedAt.Equal(current.UpdatedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// StartAutoReload 按固定间隔重新加载文档，ctx取消时停止
+func (s *DocumentStore) StartAutoReload(ctx context.Context, interval time.Duration, onError func(error)) {
+	if s.path == "" || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Reload(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// OnChange 注册文档变更回调，用于重建索引
+func (s *DocumentStore) OnChange(listener func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// notify 通知文档变更
+func (s *DocumentStore) notify() {
+	s.mutex.RLock()
+	listeners := append([]func(){}, s.listeners...)
+	s.mutex.RUnlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// writeFile 原子写入文档文件
+func (s *DocumentStore) writeFile(id string, data []byte) error {
+	if s.path == "" {
+		return nil
+	}
+
+	target := s.filePath(id)
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入文档失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入文档失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("保存文档失败: %w", err)
+	}
+
+	return nil
+}
+
+// filePath 文档ID对应的文件路径，ID经过哈希以避免非法文件名
+func (s *DocumentStore) filePath(id string) string {
+	sum := sha1.Sum([]byte(id))
+	return filepath.Join(s.path, documentsDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// ParseByteSize 解析容量配置，如"1GB"、"512MB"、"1024"
+func ParseByteSize(raw string) (int64, error) {
+	value := strings.TrimSpace(strings.ToUpper(raw))
+	if value == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"TB", 1 << 40},
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("无效的容量配置: %s", raw)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"github.com/community-governance-mcp-higress/internal/openai"
+)
+
+const (
+	// EmbedderOpenAI 使用OpenAI向量接口
+	EmbedderOpenAI = "openai"
+	// EmbedderHash 使用本地哈希向量，无需网络
+	EmbedderHash = "hash"
+
+	// defaultHashDimension 哈希向量默认维度
+	defaultHashDimension = 512
+	// embeddingBatchSize 单次向量请求的最大文本数
+	embeddingBatchSize = 64
+)
+
+// Embedder 文本向量生成器
+type Embedder interface {
+	// Embed 批量生成向量，返回顺序与输入一致
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Name 向量模型标识，模型变化时需要重建索引
+	Name() string
+}
+
+// NewEmbedder 根据类型创建向量生成器
+// kind为空时，配置了OpenAI密钥则使用OpenAI向量，否则使用本地哈希向量
+func NewEmbedder(kind, apiKey, model string) (Embedder, error) {
+	client := openai.NewClient(apiKey, "")
+
+	switch kind {
+	case "":
+		if client.IsConfigured() {
+			return NewOpenAIEmbedder(client, model), nil
+		}
+		return NewHashEmbedder(defaultHashDimension), nil
+	case EmbedderOpenAI:
+		if !client.IsConfigured() {
+			return nil, fmt.Errorf("OpenAI向量需要配置API密钥")
+		}
+		return NewOpenAIEmbedder(client, model), nil
+	case EmbedderHash:
+		return NewHashEmbedder(defaultHashDimension), nil
+	default:
+		return nil, fmt.Errorf("不支持的向量类型: %s", kind)
+	}
+}
+
+// OpenAIEmbedder 基于OpenAI向量接口的向量生成器
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAIEmbedder 创建OpenAI向量生成器
+func NewOpenAIEmbedder(client *openai.Client, model string) *OpenAIEmbedder {
+	if model == "" {
+		model = openai.DefaultEmbeddingModel
+	}
+	return &OpenAIEmbedder{
+		client: client,
+		model:  model,
+	}
+}
+
+// Embed 分批请求向量接口
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, err := e.client.CreateEmbeddings(ctx, texts[start:end], e.model)
+		if err != nil {
+			return nil, fmt.Errorf("生成向量失败: %w", err)
+		}
+		for _, vector := range batch {
+			embeddings = append(embeddings, normalizeVector(vector))
+		}
+	}
+	return embeddings, nil
+}
+
+// Name 向量模型标识
+func (e *OpenAIEmbedder) Name() string {
+	return "openai:" + e.model
+}
+
+// HashEmbedder 本地哈希向量生成器
+// 将词元哈希到固定维度并做L2归一化，结果确定且无需网络，适用于离线环境和测试
+type HashEmbedder struct {
+	dimension int
+}
+
+// NewHashEmbedder 创建哈希向量生成器
+func NewHashEmbedder(dimension int) *HashEmbedder {
+	if dimension <= 0 {
+		dimension = defaultHashDimension
+	}
+	return &HashEmbedder{dimension: dimension}
+}
+
+// Embed 生成哈希向量
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, e.dimension)
+		for _, token := range Tokenize(text) {
+			hasher := fnv.New64a()
+			hasher.Write([]byte(token))
+			sum := hasher.Sum64()
+
+			// 使用哈希的最高位决定符号，减少哈希冲突带来的偏差
+			weight := float32(1)
+			if sum>>63 == 1 {
+				weight = -1
+			}
+			vector[sum%uint64(e.dimension)] += weight
+		}
+		embeddings[i] = normalizeVector(vector)
+	}
+	return embeddings, nil
+}
+
+// Name 向量模型标识
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash:%d:v%d", e.dimension, tokenizerVersion)
+}
+
+// normalizeVector L2归一化，归一化后余弦相似度等于点积
+func normalizeVector(vector []float32) []float32 {
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value) * float64(value)
+	}
+	if norm == 0 {
+		return vector
+	}
+
+	scale := float32(1 / math.Sqrt(norm))
+	normalized := make([]float32, len(vector))
+	for i, value := range vector {
+		normalized[i] = value * scale
+	}
+	return normalized
+}
@@ -1,9 +1,13 @@
 package tools
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -28,6 +32,11 @@ func NewGitHubManager(token string) *GitHubManager {
 	}
 }
 
+// SetBaseURL 设置API地址，用于GitHub Enterprise或测试
+func (gm *GitHubManager) SetBaseURL(baseURL string) {
+	gm.baseURL = strings.TrimRight(baseURL, "/")
+}
+
 // GetIssue 获取Issue详情
 func (gm *GitHubManager) GetIssue(owner string, repo string, issueNumber int) (*model.GitHubIssue, error) {
 	url := fmt.Sprintf("%s/repos/%s/%s/issues/%d", gm.baseURL, owner, repo, issueNumber)
@@ -271,44 +280,221 @@ func (gm *GitHubManager) GetComments(owner string, repo string, issueNumber int)
 
 // SearchIssues 搜索Issue
 func (gm *GitHubManager) SearchIssues(query string, owner string, repo string) ([]*model.GitHubIssue, error) {
-	searchQuery := fmt.Sprintf("%s repo:%s/%s", query, owner, repo)
-	url := fmt.Sprintf("%s/search/issues?q=%s", gm.baseURL, searchQuery)
+	return gm.SearchIssuesContext(context.Background(), query, owner, repo)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
+// SearchIssuesContext 搜索仓库中的Issue，query支持GitHub搜索限定词（如is:closed）
+func (gm *GitHubManager) SearchIssuesContext(ctx context.Context, query string, owner string, repo string) ([]*model.GitHubIssue, error) {
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("%s repo:%s/%s", query, owner, repo))
+	endpoint := fmt.Sprintf("%s/search/issues?%s", gm.baseURL, params.Encode())
+
+	var searchResult map[string]interface{}
+	if err := gm.doJSON(ctx, http.MethodGet, endpoint, nil, &searchResult); err != nil {
 		return nil, err
 	}
 
-	if gm.token != "" {
-		req.Header.Set("Authorization", "Bearer "+gm.token)
+	var issues []*model.GitHubIssue
+	if items, ok := searchResult["items"].([]interface{}); ok {
+		for _, item := range items {
+			if issueMap, ok := item.(map[string]interface{}); ok {
+				issues = append(issues, gm.parseIssue(issueMap))
+			}
+		}
 	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
 
-	resp, err := gm.httpClient.Do(req)
-	if err != nil {
+	return issues, nil
+}
+
+// GetCommentsContext 获取Issue评论，支持取消
+func (gm *GitHubManager) GetCommentsContext(ctx context.Context, owner string, repo string, issueNumber int) ([]*model.GitHubComment, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/issues/%d/comments?per_page=100", gm.baseURL, owner, repo, issueNumber)
+
+	var comments []map[string]interface{}
+	if err := gm.doJSON(ctx, http.MethodGet, endpoint, nil, &comments); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API请求失败: %d", resp.StatusCode)
+	var result []*model.GitHubComment
+	for _, comment := range comments {
+		result = append(result, gm.parseComment(comment))
 	}
 
-	var searchResult map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&searchResult); err != nil {
+	return result, nil
+}
+
+// discussionSearchQuery 搜索讨论的GraphQL查询
+const discussionSearchQuery = `query($query: String!, $first: Int!) {
+  search(query: $query, type: DISCUSSION, first: $first) {
+    nodes {
+      ... on Discussion {
+        number
+        title
+        body
+        url
+        closed
+        createdAt
+        category { name }
+        author { login url }
+        answer { body url createdAt authorAssociation upvoteCount author { login url } }
+        comments(first: 20) {
+          nodes { body url createdAt authorAssociation upvoteCount author { login url } }
+        }
+      }
+    }
+  }
+}`
+
+// graphQLComment GraphQL返回的讨论评论
+type graphQLComment struct {
+	Body              string `json:"body"`
+	URL               string `json:"url"`
+	CreatedAt         string `json:"createdAt"`
+	AuthorAssociation string `json:"authorAssociation"`
+	UpvoteCount       int    `json:"upvoteCount"`
+	Author            *struct {
+		Login string `json:"login"`
+		URL   string `json:"url"`
+	} `json:"author"`
+}
+
+// SearchDiscussionsContext 通过GraphQL搜索仓库讨论，包含被采纳的回答和前20条评论
+// GitHub的GraphQL接口要求认证，未配置token时返回错误
+func (gm *GitHubManager) SearchDiscussionsContext(ctx context.Context, query string, owner string, repo string, limit int) ([]*model.GitHubDiscussion, error) {
+	if gm.token == "" {
+		return nil, fmt.Errorf("搜索GitHub讨论需要配置token")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	request := map[string]interface{}{
+		"query": discussionSearchQuery,
+		"variables": map[string]interface{}{
+			"query": fmt.Sprintf("%s repo:%s/%s", query, owner, repo),
+			"first": limit,
+		},
+	}
+
+	var response struct {
+		Data struct {
+			Search struct {
+				Nodes []struct {
+					Number    int    `json:"number"`
+					Title     string `json:"title"`
+					Body      string `json:"body"`
+					URL       string `json:"url"`
+					Closed    bool   `json:"closed"`
+					CreatedAt string `json:"createdAt"`
+					Category  struct {
+						Name string `json:"name"`
+					} `json:"category"`
+					Author *struct {
+						Login string `json:"login"`
+						URL   string `json:"url"`
+					} `json:"author"`
+					Answer   *graphQLComment `json:"answer"`
+					Comments struct {
+						Nodes []*graphQLComment `json:"nodes"`
+					} `json:"comments"`
+				} `json:"nodes"`
+			} `json:"search"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	if err := gm.doJSON(ctx, http.MethodPost, gm.baseURL+"/graphql", request, &response); err != nil {
 		return nil, err
 	}
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("GitHub GraphQL请求失败: %s", response.Errors[0].Message)
+	}
 
-	var issues []*model.GitHubIssue
-	if items, ok := searchResult["items"].([]interface{}); ok {
-		for _, item := range items {
-			if issueMap, ok := item.(map[string]interface{}); ok {
-				issues = append(issues, gm.parseIssue(issueMap))
-			}
+	var discussions []*model.GitHubDiscussion
+	for _, node := range response.Data.Search.Nodes {
+		// 搜索结果中非讨论类型的节点字段为空
+		if node.Number == 0 {
+			continue
 		}
+
+		discussion := &model.GitHubDiscussion{
+			Number:    node.Number,
+			Title:     node.Title,
+			Body:      node.Body,
+			URL:       node.URL,
+			Category:  node.Category.Name,
+			CreatedAt: node.CreatedAt,
+			Closed:    node.Closed,
+			Answer:    convertGraphQLComment(node.Answer),
+		}
+		if node.Author != nil {
+			discussion.User = &model.GitHubUser{Login: node.Author.Login, HTMLURL: node.Author.URL}
+		}
+		for _, comment := range node.Comments.Nodes {
+			discussion.Comments = append(discussion.Comments, convertGraphQLComment(comment))
+		}
+		discussions = append(discussions, discussion)
 	}
 
-	return issues, nil
+	return discussions, nil
+}
+
+// convertGraphQLComment 转换GraphQL评论
+func convertGraphQLComment(comment *graphQLComment) *model.GitHubComment {
+	if comment == nil {
+		return nil
+	}
+
+	result := &model.GitHubComment{
+		Body:              comment.Body,
+		CreatedAt:         comment.CreatedAt,
+		HTMLURL:           comment.URL,
+		AuthorAssociation: comment.AuthorAssociation,
+		Reactions:         comment.UpvoteCount,
+	}
+	if comment.Author != nil {
+		result.User = &model.GitHubUser{Login: comment.Author.Login, HTMLURL: comment.Author.URL}
+	}
+	return result
+}
+
+// doJSON 发送带认证的JSON请求并解析响应
+func (gm *GitHubManager) doJSON(ctx context.Context, method string, endpoint string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+
+	if gm.token != "" {
+		req.Header.Set("Authorization", "Bearer "+gm.token)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := gm.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API请求失败: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
 }
 
 // GetRepositoryStats 获取仓库统计
@@ -398,12 +584,19 @@ func (gm *GitHubManager) parseIssue(data map[string]interface{}) *model.GitHubIs
 // parseComment 解析评论数据
 func (gm *GitHubManager) parseComment(data map[string]interface{}) *model.GitHubComment {
 	comment := &model.GitHubComment{
-		ID:        getInt(data, "id"),
-		Body:      getString(data, "body"),
-		User:      gm.parseUser(getMap(data, "user")),
-		CreatedAt: getString(data, "created_at"),
-		UpdatedAt: getString(data, "updated_at"),
-		HTMLURL:   getString(data, "html_url"),
+		ID:                getInt(data, "id"),
+		Body:              getString(data, "body"),
+		User:              gm.parseUser(getMap(data, "user")),
+		CreatedAt:         getString(data, "created_at"),
+		UpdatedAt:         getString(data, "updated_at"),
+		HTMLURL:           getString(data, "html_url"),
+		AuthorAssociation: getString(data, "author_association"),
+	}
+
+	// 只统计正面反馈
+	reactions := getMap(data, "reactions")
+	for _, key := range []string{"+1", "heart", "hooray"} {
+		comment.Reactions += getInt(reactions, key)
 	}
 
 	return comment
@@ -0,0 +1,33 @@
+package tools
+
+// DefaultRRFK 倒数排名融合的平滑常数
+const DefaultRRFK = 60
+
+// FuseRankings 使用倒数排名融合（RRF）合并多路检索结果
+// 每个分块的得分为各路排名r对应的1/(k+r)之和，k通常取60；返回结果按融合得分降序排列
+// 融合得分只反映排名，相关度取各路中最高的Relevance
+func FuseRankings(k int, rankings ...[]ChunkHit) []ChunkHit {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	scores := make(map[string]float64)
+	relevance := make(map[string]float64)
+	chunks := make(map[string]Chunk)
+	for _, ranking := range rankings {
+		for rank, hit := range ranking {
+			key := hit.Chunk.key()
+			scores[key] += 1 / float64(k+rank+1)
+			relevance[key] = max(relevance[key], hit.Relevance)
+			chunks[key] = hit.Chunk
+		}
+	}
+
+	fused := make([]ChunkHit, 0, len(scores))
+	for key, score := range scores {
+		fused = append(fused, ChunkHit{Chunk: chunks[key], Score: score, Relevance: relevance[key]})
+	}
+	sortChunkHits(fused)
+
+	return fused
+}
@@ -5,33 +5,111 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
 	"github.com/community-governance-mcp-higress/internal/model"
-	"github.com/community-governance-mcp-higress/internal/openai"
 )
 
+// minVectorSimilarity 向量检索结果的最低余弦相似度，低于该值的分块视为语义无关
+const minVectorSimilarity = 0.3
+
 // KnowledgeBase 知识库工具
+// 检索时同时使用BM25词项匹配和向量语义检索，并以倒数排名融合合并结果；
+// 向量索引在后台更新，检索不等待向量生成，索引完成前只使用已索引的分块
 type KnowledgeBase struct {
-	openaiClient *openai.Client
-	documents    []model.Document
+	store        *DocumentStore
+	index        *VectorIndex
+	lexical      *BM25Index
+	lexicalDirty atomic.Bool
+	vectorDirty  atomic.Bool
+	indexing     atomic.Bool
+
+	mutex        sync.Mutex
+	errorHandler func(error)
+	closed       bool
+	background   sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
 }
 
-// NewKnowledgeBase 创建新的知识库
+// NewKnowledgeBase 创建新的知识库，文档仅保存在内存中
 func NewKnowledgeBase(apiKey string) *KnowledgeBase {
-	return &KnowledgeBase{
-		openaiClient: openai.NewClient(apiKey, "gpt-4o"),
-		documents:    []model.Document{},
+	return NewKnowledgeBaseWithStore(apiKey, NewMemoryDocumentStore())
+}
+
+// NewKnowledgeBaseWithStore 基于指定文档存储创建知识库
+// 配置了OpenAI密钥时使用OpenAI向量，否则使用本地哈希向量；持久化存储的索引保存在存储目录下
+func NewKnowledgeBaseWithStore(apiKey string, store *DocumentStore) *KnowledgeBase {
+	embedder, err := NewEmbedder("", apiKey, "")
+	if err != nil {
+		embedder = NewHashEmbedder(defaultHashDimension)
+	}
+
+	index, err := NewVectorIndex(store.IndexPath(), embedder, DefaultChunkSize, DefaultChunkOverlap)
+	if err != nil {
+		index, _ = NewVectorIndex("", embedder, DefaultChunkSize, DefaultChunkOverlap)
+	}
+
+	return NewKnowledgeBaseWithIndex(store, index)
+}
+
+// NewKnowledgeBaseWithIndex 基于指定文档存储和向量索引创建知识库，BM25索引使用与向量索引相同的分块参数
+func NewKnowledgeBaseWithIndex(store *DocumentStore, index *VectorIndex) *KnowledgeBase {
+	kb := &KnowledgeBase{
+		store:   store,
+		index:   index,
+		lexical: NewBM25Index(index.ChunkSize(), index.ChunkOverlap()),
 	}
+	kb.ctx, kb.cancel = context.WithCancel(context.Background())
+	kb.lexicalDirty.Store(true)
+	kb.vectorDirty.Store(true)
+
+	// 文档变化后BM25索引在下次检索前更新，向量索引立即在后台更新
+	store.OnChange(func() {
+		kb.lexicalDirty.Store(true)
+		kb.vectorDirty.Store(true)
+		kb.ReindexAsync()
+	})
+
+	return kb
 }
 
-// AddDocument 添加文档到知识库
-func (kb *KnowledgeBase) AddDocument(doc model.Document) {
-	kb.documents = append(kb.documents, doc)
+// OnIndexError 设置后台更新向量索引失败时的回调，失败的更新在文档变化或下次检索时重试
+func (kb *KnowledgeBase) OnIndexError(handler func(error)) {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+	kb.errorHandler = handler
+}
+
+// AddDocument 添加文档到知识库，ID为空时自动生成
+func (kb *KnowledgeBase) AddDocument(doc model.Document) (*model.Document, error) {
+	saved, err := kb.store.Put(doc)
+	if err != nil {
+		return nil, fmt.Errorf("添加文档失败: %w", err)
+	}
+	return &saved, nil
 }
 
 // SearchKnowledge 搜索知识库
 func (kb *KnowledgeBase) SearchKnowledge(query string, maxResults int) (*model.KnowledgeSearchResult, error) {
-	if len(kb.documents) == 0 {
+	return kb.SearchKnowledgeContext(context.Background(), query, maxResults)
+}
+
+// SearchKnowledgeContext 混合检索知识库
+// BM25和向量检索各自召回候选分块后按倒数排名融合，每个文档返回得分最高的分块；
+// 向量服务不可用时只使用BM25结果
+func (kb *KnowledgeBase) SearchKnowledgeContext(ctx context.Context, query string, maxResults int) (*model.KnowledgeSearchResult, error) {
+	return kb.SearchKnowledgeFiltered(ctx, query, maxResults, nil)
+}
+
+// SearchKnowledgeFiltered 只在filter返回true的文档中检索，filter为nil时检索全部文档
+func (kb *KnowledgeBase) SearchKnowledgeFiltered(ctx context.Context, query string, maxResults int, filter func(doc model.Document) bool) (*model.KnowledgeSearchResult, error) {
+	if maxResults <= 0 {
+		maxResults = 5
+	}
+	if kb.store.Count() == 0 {
 		return &model.KnowledgeSearchResult{
 			Query:     query,
 			Results:   []model.SearchResult{},
@@ -39,12 +117,34 @@ func (kb *KnowledgeBase) SearchKnowledge(query string, maxResults int) (*model.K
 		}, nil
 	}
 
-	// 使用AI进行语义搜索
-	results, err := kb.semanticSearch(query, maxResults)
-	if err != nil {
-		return nil, fmt.Errorf("语义搜索失败: %w", err)
+	// 为了按文档去重后仍有足够结果，每路召回更多候选
+	candidates := maxResults * 10
+	if candidates < 50 {
+		candidates = 50
+	}
+
+	// 上次后台更新失败时重新触发，检索本身不等待向量生成
+	kb.syncLexical()
+	if kb.vectorDirty.Load() {
+		kb.ReindexAsync()
 	}
 
+	// 过滤在两路截断候选之前进行，避免被过滤掉的文档占满候选
+	accept := kb.documentFilter(filter)
+
+	var rankings [][]ChunkHit
+	if lexicalHits := kb.lexical.SearchFiltered(query, candidates, accept); len(lexicalHits) > 0 {
+		rankings = append(rankings, lexicalHits)
+	}
+	if kb.index.ChunkCount() > 0 {
+		vectorHits, err := kb.vectorSearch(ctx, query, candidates, accept)
+		if err == nil && len(vectorHits) > 0 {
+			rankings = append(rankings, vectorHits)
+		}
+	}
+
+	results := kb.buildResults(query, FuseRankings(DefaultRRFK, rankings...), maxResults)
+
 	return &model.KnowledgeSearchResult{
 		Query:     query,
 		Results:   results,
@@ -52,131 +152,128 @@ func (kb *KnowledgeBase) SearchKnowledge(query string, maxResults int) (*model.K
 	}, nil
 }
 
-// semanticSearch 语义搜索
-func (kb *KnowledgeBase) semanticSearch(query string, maxResults int) ([]model.SearchResult, error) {
-	// 构建搜索提示
-	prompt := kb.buildSearchPrompt(query, maxResults)
-
-	// 使用AI进行搜索
-	response, err := kb.openaiClient.GenerateText(context.Background(), prompt, 800, 0.3)
-	if err != nil {
-		return nil, fmt.Errorf("AI搜索失败: %w", err)
+// Reindex 增量更新BM25和向量索引并等待完成
+// BM25索引总是会更新；向量生成失败时返回错误，已完成的批次会保留，下次更新时继续
+func (kb *KnowledgeBase) Reindex(ctx context.Context) error {
+	kb.syncLexical()
+	if !kb.vectorDirty.Swap(false) {
+		return nil
 	}
 
-	// 解析搜索结果
-	results := kb.parseSearchResults(response, query)
+	if err := kb.index.Sync(ctx, kb.store.List()); err != nil {
+		kb.vectorDirty.Store(true)
+		return fmt.Errorf("更新向量索引失败: %w", err)
+	}
+	return nil
+}
 
-	// 限制结果数量
-	if len(results) > maxResults {
-		results = results[:maxResults]
+// ReindexAsync 在后台增量更新向量索引，已有后台更新时由其处理新的变化；Close之后不再更新
+func (kb *KnowledgeBase) ReindexAsync() {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+	if kb.closed || !kb.indexing.CompareAndSwap(false, true) {
+		return
 	}
 
-	return results, nil
+	kb.background.Add(1)
+	go func() {
+		defer kb.background.Done()
+		for {
+			err := kb.Reindex(kb.ctx)
+			kb.indexing.Store(false)
+			if err != nil {
+				kb.mutex.Lock()
+				handler := kb.errorHandler
+				kb.mutex.Unlock()
+				if handler != nil {
+					handler(err)
+				}
+				return
+			}
+			// 更新期间文档又发生变化时继续更新
+			if !kb.vectorDirty.Load() || !kb.indexing.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止后台索引更新并等待其退出，已完成的批次会保留
+func (kb *KnowledgeBase) Close() {
+	kb.mutex.Lock()
+	kb.closed = true
+	kb.mutex.Unlock()
+
+	kb.cancel()
+	kb.background.Wait()
 }
 
-// buildSearchPrompt 构建搜索提示
-func (kb *KnowledgeBase) buildSearchPrompt(query string, maxResults int) string {
-	// 构建文档内容
-	var docContent strings.Builder
-	for i, doc := range kb.documents {
-		docContent.WriteString(fmt.Sprintf("文档%d:\n标题: %s\n内容: %s\n\n", i+1, doc.Title, doc.Content))
+// syncLexical 文档变化后更新BM25索引
+func (kb *KnowledgeBase) syncLexical() {
+	if kb.lexicalDirty.Swap(false) {
+		kb.lexical.Sync(kb.store.List())
 	}
-
-	return fmt.Sprintf(`请从以下知识库文档中搜索与查询最相关的内容：
-
-查询: %s
-
-知识库文档:
-%s
-
-请返回最相关的%d个结果，格式如下：
-{
-  "results": [
-    {
-      "document_id": "文档ID",
-      "title": "文档标题",
-      "content": "相关内容片段",
-      "relevance_score": 0.95,
-      "snippet": "匹配的文本片段"
-    }
-  ]
-}`, query, docContent.String(), maxResults)
 }
 
-// parseSearchResults 解析搜索结果
-func (kb *KnowledgeBase) parseSearchResults(response string, query string) []model.SearchResult {
-	var results []model.SearchResult
-
-	// 尝试解析JSON响应
-	if strings.Contains(response, "{") && strings.Contains(response, "}") {
-		start := strings.Index(response, "{")
-		end := strings.LastIndex(response, "}") + 1
-		if start >= 0 && end > start {
-			jsonStr := response[start:end]
-
-			var result map[string]interface{}
-			if err := json.Unmarshal([]byte(jsonStr), &result); err == nil {
-				if resultsArray, ok := result["results"].([]interface{}); ok {
-					for _, item := range resultsArray {
-						if resultMap, ok := item.(map[string]interface{}); ok {
-							searchResult := model.SearchResult{
-								DocumentID:     getStringFromMap(resultMap, "document_id"),
-								Title:          getStringFromMap(resultMap, "title"),
-								Content:        getStringFromMap(resultMap, "content"),
-								RelevanceScore: getFloatFromMap(resultMap, "relevance_score"),
-								Snippet:        getStringFromMap(resultMap, "snippet"),
-							}
-							results = append(results, searchResult)
-						}
-					}
-				}
-			}
+// documentFilter 将文档过滤条件转换为按文档ID的过滤，同一文档只判断一次；索引中已删除的文档也会被过滤掉
+func (kb *KnowledgeBase) documentFilter(filter func(doc model.Document) bool) func(documentID string) bool {
+	accepted := make(map[string]bool)
+	return func(documentID string) bool {
+		if ok, checked := accepted[documentID]; checked {
+			return ok
 		}
+		doc, exists := kb.store.Get(documentID)
+		ok := exists && (filter == nil || filter(doc))
+		accepted[documentID] = ok
+		return ok
 	}
+}
 
-	// 如果没有解析到JSON，使用简单的文本匹配
-	if len(results) == 0 {
-		results = kb.fallbackTextSearch(query)
+// vectorSearch 向量检索，只返回accept接受的文档中相似度不低于minVectorSimilarity的分块，没有词元匹配的分块只有语义相近时才作为结果
+func (kb *KnowledgeBase) vectorSearch(ctx context.Context, query string, limit int, accept func(documentID string) bool) ([]ChunkHit, error) {
+	vectors, err := kb.index.Embedder().Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("生成查询向量失败: %w", err)
 	}
 
-	return results
+	hits := kb.index.SearchFiltered(vectors[0], limit, accept)
+	for i, hit := range hits {
+		if hit.Score < minVectorSimilarity {
+			return hits[:i], nil
+		}
+	}
+	return hits, nil
 }
 
-// fallbackTextSearch 备用文本搜索
-func (kb *KnowledgeBase) fallbackTextSearch(query string) []model.SearchResult {
-	var results []model.SearchResult
-	query = strings.ToLower(query)
+// buildResults 将融合后的分块转换为搜索结果，按融合排名排序，每个文档只保留排名最高的分块
+// 相关度使用分块的Relevance（查询词元覆盖率或余弦相似度），不随排名变化，便于与其他知识源的相关性比较
+func (kb *KnowledgeBase) buildResults(query string, hits []ChunkHit, maxResults int) []model.SearchResult {
+	results := []model.SearchResult{}
 
-	for i, doc := range kb.documents {
-		content := strings.ToLower(doc.Content)
-		title := strings.ToLower(doc.Title)
-
-		// 简单的关键词匹配
-		relevance := 0.0
-		if strings.Contains(content, query) || strings.Contains(title, query) {
-			relevance = 0.8
+	seen := make(map[string]bool)
+	for _, hit := range hits {
+		if len(results) >= maxResults {
+			break
 		}
-
-		// 检查部分匹配
-		words := strings.Fields(query)
-		for _, word := range words {
-			if strings.Contains(content, word) || strings.Contains(title, word) {
-				relevance += 0.2
-			}
+		if seen[hit.Chunk.DocumentID] {
+			continue
 		}
-
-		if relevance > 0.0 {
-			// 生成片段
-			snippet := kb.generateSnippet(doc.Content, query)
-
-			results = append(results, model.SearchResult{
-				DocumentID:     fmt.Sprintf("doc_%d", i),
-				Title:          doc.Title,
-				Content:        doc.Content,
-				RelevanceScore: relevance,
-				Snippet:        snippet,
-			})
+		doc, exists := kb.store.Get(hit.Chunk.DocumentID)
+		if !exists {
+			continue
 		}
+		seen[hit.Chunk.DocumentID] = true
+
+		results = append(results, model.SearchResult{
+			DocumentID:     doc.ID,
+			Title:          doc.Title,
+			Content:        hit.Chunk.Text,
+			RelevanceScore: min(hit.Relevance, 1),
+			Snippet:        kb.generateSnippet(hit.Chunk.Text, query),
+			ChunkIndex:     hit.Chunk.Index,
+			StartOffset:    hit.Chunk.Start,
+			EndOffset:      hit.Chunk.End,
+		})
 	}
 
 	return results
@@ -196,91 +293,89 @@ func (kb *KnowledgeBase) generateSnippet(content string, query string) string {
 			if end > len(content) {
 				end = len(content)
 			}
+			// 对齐到字符边界，避免截断多字节字符
+			for start > 0 && !utf8.RuneStart(content[start]) {
+				start--
+			}
+			for end < len(content) && !utf8.RuneStart(content[end]) {
+				end++
+			}
 			return content[start:end] + "..."
 		}
 	}
 
 	// 如果没有找到匹配，返回前100个字符
 	if len(content) > 100 {
-		return content[:100] + "..."
+		end := 100
+		for end < len(content) && !utf8.RuneStart(content[end]) {
+			end++
+		}
+		return content[:end] + "..."
 	}
 	return content
 }
 
 // GetDocument 获取文档
 func (kb *KnowledgeBase) GetDocument(documentID string) (*model.Document, error) {
-	for _, doc := range kb.documents {
-		if doc.ID == documentID {
-			return &doc, nil
-		}
+	doc, exists := kb.store.Get(documentID)
+	if !exists {
+		return nil, fmt.Errorf("文档未找到: %s", documentID)
 	}
-	return nil, fmt.Errorf("文档未找到: %s", documentID)
+	return &doc, nil
 }
 
 // UpdateDocument 更新文档
 func (kb *KnowledgeBase) UpdateDocument(documentID string, updates model.Document) error {
-	for i, doc := range kb.documents {
-		if doc.ID == documentID {
-			kb.documents[i] = updates
-			return nil
-		}
+	doc, exists := kb.store.Get(documentID)
+	if !exists {
+		return fmt.Errorf("文档未找到: %s", documentID)
 	}
-	return fmt.Errorf("文档未找到: %s", documentID)
+	updates.ID = documentID
+	updates.CreatedAt = doc.CreatedAt
+	_, err := kb.store.Put(updates)
+	return err
 }
 
 // DeleteDocument 删除文档
 func (kb *KnowledgeBase) DeleteDocument(documentID string) error {
-	for i, doc := range kb.documents {
-		if doc.ID == documentID {
-			kb.documents = append(kb.documents[:i], kb.documents[i+1:]...)
-			return nil
-		}
-	}
-	return fmt.Errorf("文档未找到: %s", documentID)
+	return kb.store.Delete(documentID)
 }
 
 // GetDocumentCount 获取文档数量
 func (kb *KnowledgeBase) GetDocumentCount() int {
-	return len(kb.documents)
+	return kb.store.Count()
 }
 
 // GetDocuments 获取所有文档
 func (kb *KnowledgeBase) GetDocuments() []model.Document {
-	return kb.documents
+	return kb.store.List()
+}
+
+// GetStore 获取底层文档存储
+func (kb *KnowledgeBase) GetStore() *DocumentStore {
+	return kb.store
 }
 
 // ClearDocuments 清空所有文档
-func (kb *KnowledgeBase) ClearDocuments() {
-	kb.documents = []model.Document{}
+func (kb *KnowledgeBase) ClearDocuments() error {
+	return kb.store.Clear()
 }
 
 // ExportDocuments 导出文档
 func (kb *KnowledgeBase) ExportDocuments() ([]byte, error) {
-	return json.Marshal(kb.documents)
+	return json.Marshal(kb.store.List())
 }
 
-// ImportDocuments 导入文档
+// ImportDocuments 导入文档，已存在的同ID文档会被覆盖
 func (kb *KnowledgeBase) ImportDocuments(data []byte) error {
 	var documents []model.Document
 	if err := json.Unmarshal(data, &documents); err != nil {
 		return fmt.Errorf("解析文档数据失败: %w", err)
 	}
-	kb.documents = documents
-	return nil
-}
-
-// getStringFromMap 安全获取字符串值
-func getStringFromMap(m map[string]interface{}, key string) string {
-	if val, ok := m[key].(string); ok {
-		return val
-	}
-	return ""
-}
-
-// getFloatFromMap 安全获取浮点数值
-func getFloatFromMap(m map[string]interface{}, key string) float64 {
-	if val, ok := m[key].(float64); ok {
-		return val
+	for _, doc := range documents {
+		if _, err := kb.store.Put(doc); err != nil {
+			return fmt.Errorf("导入文档失败: %w", err)
+		}
 	}
-	return 0.0
+	return nil
 }
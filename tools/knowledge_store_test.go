@@ -0,0 +1,135 @@
+package tools_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDocumentStorePersistence 测试文档存储在重新打开后保留数据
+func TestDocumentStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := tools.NewDocumentStore(dir, 0)
+	require.NoError(t, err)
+
+	saved, err := store.Put(model.Document{Title: "限流插件", Content: "key-rate-limit 插件按请求键限流", URL: "https://higress.io/docs/plugins/key-rate-limit"})
+	require.NoError(t, err)
+	require.NotEmpty(t, saved.ID)
+	_, err = store.Put(model.Document{ID: "plugins/ai-proxy", Title: "AI代理", Content: "ai-proxy 插件统一多家模型服务"})
+	require.NoError(t, err)
+
+	// 重新打开存储模拟进程重启
+	reopened, err := tools.NewDocumentStore(dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reopened.Count())
+	assert.Equal(t, store.Size(), reopened.Size())
+
+	doc, ok := reopened.Get(saved.ID)
+	require.True(t, ok)
+	assert.Equal(t, "限流插件", doc.Title)
+
+	// 删除后重启不再出现
+	require.NoError(t, reopened.Delete("plugins/ai-proxy"))
+	reopened, err = tools.NewDocumentStore(dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, reopened.Count())
+
+	// 其他实例写入的文档通过Reload可见，只有文档变化时才通知
+	changes := 0
+	store.OnChange(func() { changes++ })
+	_, err = reopened.Put(model.Document{ID: "gateway", Title: "网关", Content: "Higress网关配置"})
+	require.NoError(t, err)
+	require.NoError(t, store.Reload())
+	_, ok = store.Get("gateway")
+	assert.True(t, ok)
+	assert.Equal(t, 1, changes)
+
+	require.NoError(t, store.Reload())
+	assert.Equal(t, 1, changes)
+
+	_, err = reopened.Put(model.Document{ID: "gateway", Title: "网关", Content: "Higress网关配置已更新"})
+	require.NoError(t, err)
+	require.NoError(t, store.Reload())
+	assert.Equal(t, 2, changes)
+}
+
+// TestDocumentStoreMaxSize 测试容量上限
+func TestDocumentStoreMaxSize(t *testing.T) {
+	store, err := tools.NewDocumentStore(t.TempDir(), 400)
+	require.NoError(t, err)
+
+	_, err = store.Put(model.Document{ID: "small", Title: "small", Content: "ok"})
+	require.NoError(t, err)
+
+	large := make([]byte, 500)
+	for i := range large {
+		large[i] = 'a'
+	}
+	_, err = store.Put(model.Document{ID: "large", Title: "large", Content: string(large)})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, tools.ErrStorageFull))
+	assert.Equal(t, 1, store.Count())
+
+	size, err := tools.ParseByteSize("1GB")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), size)
+	_, err = tools.ParseByteSize("lots")
+	assert.Error(t, err)
+}
+
+// TestKnowledgeBaseSharedStore 测试知识库基于共享存储检索
+func TestKnowledgeBaseSharedStore(t *testing.T) {
+	store, err := tools.NewDocumentStore(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	// 未配置OpenAI时使用文本匹配检索
+	writer := tools.NewKnowledgeBaseWithStore("", store)
+	t.Cleanup(writer.Close)
+	reader := tools.NewKnowledgeBaseWithStore("", store)
+	t.Cleanup(reader.Close)
+
+	_, err = writer.AddDocument(model.Document{ID: "rate-limit", Title: "限流", Content: "使用 key-rate-limit 插件配置限流"})
+	require.NoError(t, err)
+
+	result, err := reader.SearchKnowledge("key-rate-limit", 5)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "rate-limit", result.Results[0].DocumentID)
+}
+
+// TestDocumentStoreReloadDuringPut 测试重新加载期间写入的文档不会丢失
+func TestDocumentStoreReloadDuringPut(t *testing.T) {
+	store, err := tools.NewDocumentStore(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				assert.NoError(t, store.Reload())
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		_, err := store.Put(model.Document{ID: fmt.Sprintf("doc-%d", i), Title: "文档", Content: "内容"})
+		require.NoError(t, err)
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Equal(t, 100, store.Count())
+}
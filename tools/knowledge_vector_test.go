@@ -0,0 +1,412 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+	"github.com/community-governance-mcp-higress/internal/openai"
+	"github.com/community-governance-mcp-higress/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingEmbedder 统计向量生成次数的哈希向量生成器
+type countingEmbedder struct {
+	*tools.HashEmbedder
+	calls atomic.Int32
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls.Add(int32(len(texts)))
+	return e.HashEmbedder.Embed(ctx, texts)
+}
+
+// TestChunkDocument 测试文档分块及偏移
+func TestChunkDocument(t *testing.T) {
+	content := strings.Repeat("Higress网关支持Wasm插件扩展。", 40) + "\n\n" + strings.Repeat("限流插件按路由配置。", 30)
+	doc := model.Document{ID: "doc", Content: content}
+
+	chunks := tools.ChunkDocument(doc, 200, 20)
+	require.Greater(t, len(chunks), 1)
+
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.Equal(t, content[chunk.Start:chunk.End], chunk.Text)
+		assert.LessOrEqual(t, len([]rune(chunk.Text)), 200)
+	}
+	assert.Equal(t, 0, chunks[0].Start)
+	assert.Equal(t, len(content), chunks[len(chunks)-1].End)
+
+	// 切分点优先落在句末
+	assert.True(t, strings.HasSuffix(chunks[0].Text, "。"))
+
+	assert.Empty(t, tools.ChunkDocument(model.Document{ID: "empty", Content: "  \n"}, 200, 20))
+}
+
+// TestVectorSearch 测试哈希向量检索及索引持久化
+func TestVectorSearch(t *testing.T) {
+	dir := t.TempDir()
+	store, err := tools.NewDocumentStore(dir, 0)
+	require.NoError(t, err)
+
+	docs := []model.Document{
+		{ID: "rate-limit", Title: "限流插件", Content: "key-rate-limit 插件可以按照请求头或参数对请求进行限流。"},
+		{ID: "ai-proxy", Title: "AI代理插件", Content: "ai-proxy 插件将请求转发到 OpenAI、通义千问等大模型服务。"},
+		{ID: "wasm", Title: "Wasm插件开发", Content: "使用 Go 编写 Wasm 插件并通过 WasmPlugin 资源下发到网关。"},
+	}
+	for _, doc := range docs {
+		_, err := store.Put(doc)
+		require.NoError(t, err)
+	}
+
+	embedder := &countingEmbedder{HashEmbedder: tools.NewHashEmbedder(256)}
+	index, err := tools.NewVectorIndex(store.IndexPath(), embedder, 200, 20)
+	require.NoError(t, err)
+	kb := tools.NewKnowledgeBaseWithIndex(store, index)
+	t.Cleanup(kb.Close)
+	require.NoError(t, kb.Reindex(context.Background()))
+	assert.Equal(t, int32(3), embedder.calls.Load())
+
+	embedder.calls.Store(0)
+	result, err := kb.SearchKnowledgeContext(context.Background(), "如何配置限流", 2)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Results)
+	top := result.Results[0]
+	assert.Equal(t, "rate-limit", top.DocumentID)
+	assert.Greater(t, top.RelevanceScore, 0.0)
+	assert.Equal(t, docs[0].Content[top.StartOffset:top.EndOffset], top.Content)
+	assert.Equal(t, int32(1), embedder.calls.Load()) // 只生成查询向量
+
+	// 未变化的文档不会重新生成向量
+	embedder.calls.Store(0)
+	_, err = kb.SearchKnowledgeContext(context.Background(), "AI代理", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), embedder.calls.Load())
+
+	// 重新打开索引后无需重新生成向量
+	reopenedEmbedder := &countingEmbedder{HashEmbedder: tools.NewHashEmbedder(256)}
+	reopened, err := tools.NewVectorIndex(store.IndexPath(), reopenedEmbedder, 200, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reopened.ChunkCount())
+	require.NoError(t, reopened.Sync(context.Background(), store.List()))
+	assert.Equal(t, int32(0), reopenedEmbedder.calls.Load())
+
+	// 向量模型变化时丢弃旧索引
+	changed, err := tools.NewVectorIndex(store.IndexPath(), tools.NewHashEmbedder(128), 200, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 0, changed.ChunkCount())
+}
+
+// gatedEmbedder 放行前阻塞的哈希向量生成器，failAfter大于0时第failAfter次之后的调用返回错误
+type gatedEmbedder struct {
+	*tools.HashEmbedder
+	gate      chan struct{}
+	failAfter atomic.Int32
+	calls     atomic.Int32
+}
+
+func (e *gatedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.gate != nil {
+		select {
+		case <-e.gate:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if failAfter, calls := e.failAfter.Load(), e.calls.Add(1); failAfter > 0 && calls > failAfter {
+		return nil, errors.New("embedding service unavailable")
+	}
+	return e.HashEmbedder.Embed(ctx, texts)
+}
+
+// TestSearchDoesNotWaitForIndex 测试检索不等待向量生成，文档变化后索引在后台更新
+func TestSearchDoesNotWaitForIndex(t *testing.T) {
+	store := tools.NewMemoryDocumentStore()
+	embedder := &gatedEmbedder{HashEmbedder: tools.NewHashEmbedder(256), gate: make(chan struct{})}
+	index, err := tools.NewVectorIndex("", embedder, 200, 20)
+	require.NoError(t, err)
+	kb := tools.NewKnowledgeBaseWithIndex(store, index)
+	t.Cleanup(kb.Close)
+
+	_, err = kb.AddDocument(model.Document{ID: "rate-limit", Title: "限流插件", Content: "key-rate-limit 插件可以按照请求头或参数对请求进行限流。"})
+	require.NoError(t, err)
+
+	// 向量生成阻塞时检索只使用BM25结果
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := kb.SearchKnowledgeContext(ctx, "key-rate-limit", 5)
+	require.NoError(t, err)
+	require.NoError(t, ctx.Err())
+	require.NotEmpty(t, result.Results)
+	assert.Equal(t, "rate-limit", result.Results[0].DocumentID)
+	assert.Equal(t, 0, index.ChunkCount())
+
+	close(embedder.gate)
+	require.Eventually(t, func() bool { return index.ChunkCount() == 1 }, 2*time.Second, 10*time.Millisecond)
+
+	// 后台更新失败时通知回调，下次检索时重试
+	failures := make(chan error, 1)
+	kb.OnIndexError(func(err error) { failures <- err })
+	embedder.failAfter.Store(embedder.calls.Load())
+	_, err = kb.AddDocument(model.Document{ID: "ai-proxy", Title: "AI代理插件", Content: "ai-proxy 插件将请求转发到大模型服务。"})
+	require.NoError(t, err)
+	select {
+	case err := <-failures:
+		assert.Contains(t, err.Error(), "embedding service unavailable")
+	case <-time.After(2 * time.Second):
+		t.Fatal("后台更新失败时没有通知回调")
+	}
+
+	embedder.failAfter.Store(0)
+	_, err = kb.SearchKnowledge("ai-proxy", 5)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return index.ChunkCount() == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+// TestKnowledgeBaseClose 测试Close取消并等待后台索引更新，之后文档变化不再触发更新
+func TestKnowledgeBaseClose(t *testing.T) {
+	store := tools.NewMemoryDocumentStore()
+	embedder := &gatedEmbedder{HashEmbedder: tools.NewHashEmbedder(64), gate: make(chan struct{})}
+	index, err := tools.NewVectorIndex("", embedder, 200, 20)
+	require.NoError(t, err)
+	kb := tools.NewKnowledgeBaseWithIndex(store, index)
+	t.Cleanup(kb.Close)
+
+	_, err = kb.AddDocument(model.Document{ID: "rate-limit", Title: "限流插件", Content: "key-rate-limit 插件可以按照请求头或参数对请求进行限流。"})
+	require.NoError(t, err)
+
+	// 向量生成阻塞时Close取消更新并返回
+	done := make(chan struct{})
+	go func() {
+		kb.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close没有等到后台更新退出")
+	}
+
+	close(embedder.gate)
+	_, err = kb.AddDocument(model.Document{ID: "ai-proxy", Title: "AI代理插件", Content: "ai-proxy 插件将请求转发到大模型服务。"})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, index.ChunkCount())
+}
+
+// TestVectorIndexSyncBatches 测试向量按批生成，失败前完成的批次已生效并写回磁盘
+func TestVectorIndexSyncBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.gob")
+	var documents []model.Document
+	for i := 0; i < 70; i++ {
+		documents = append(documents, model.Document{ID: fmt.Sprintf("doc-%d", i), Title: "文档", Content: fmt.Sprintf("第%d篇文档的内容", i)})
+	}
+
+	embedder := &gatedEmbedder{HashEmbedder: tools.NewHashEmbedder(64)}
+	embedder.failAfter.Store(1)
+	index, err := tools.NewVectorIndex(path, embedder, 200, 20)
+	require.NoError(t, err)
+	require.Error(t, index.Sync(context.Background(), documents))
+	assert.Equal(t, 64, index.ChunkCount())
+
+	// 重新打开后只为剩余文档生成向量
+	counting := &countingEmbedder{HashEmbedder: tools.NewHashEmbedder(64)}
+	reopened, err := tools.NewVectorIndex(path, counting, 200, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 64, reopened.ChunkCount())
+	require.NoError(t, reopened.Sync(context.Background(), documents))
+	assert.Equal(t, int32(6), counting.calls.Load())
+	assert.Equal(t, 70, reopened.ChunkCount())
+
+	// 删除的文档立即移除
+	require.NoError(t, reopened.Sync(context.Background(), documents[:10]))
+	assert.Equal(t, 10, reopened.ChunkCount())
+}
+
+// TestOpenAIEmbedder 测试OpenAI向量接口
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		var req openai.EmbeddingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		// 倒序返回，验证按index还原顺序
+		data := make([]map[string]interface{}, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{
+				"index":     i,
+				"embedding": []float32{float32(i + 1), 1},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	client := openai.NewClient("test-key", "gpt-4o")
+	client.SetBaseURL(server.URL)
+	embedder := tools.NewOpenAIEmbedder(client, "")
+	assert.Equal(t, "openai:"+openai.DefaultEmbeddingModel, embedder.Name())
+
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	require.Len(t, vectors, 2)
+	// 按输入顺序返回且已归一化
+	assert.InDelta(t, 0.7071, vectors[0][0], 1e-4)
+	assert.InDelta(t, 0.8944, vectors[1][0], 1e-4)
+}
+
+// TestTokenize 测试中英文混合分词
+func TestTokenize(t *testing.T) {
+	tokens := tools.Tokenize("启用wasm-go插件后返回503 UH")
+	assert.Contains(t, tokens, "wasm-go")
+	assert.Contains(t, tokens, "wasm")
+	assert.Contains(t, tokens, "go")
+	assert.Contains(t, tokens, "503")
+	assert.Contains(t, tokens, "uh")
+	assert.Contains(t, tokens, "插件")
+	assert.Contains(t, tokens, "启用")
+	assert.NotContains(t, tokens, "wasm-go插件")
+}
+
+// TestHybridSearch 测试BM25精确匹配与混合排序
+func TestHybridSearch(t *testing.T) {
+	store := tools.NewMemoryDocumentStore()
+	docs := []model.Document{
+		{ID: "ai-proxy", Title: "AI代理", Content: "ai-proxy 插件支持多家大模型服务，配置 provider.type 选择服务商。"},
+		{ID: "ai-cache", Title: "AI缓存", Content: "ai-cache 插件缓存大模型响应，降低调用成本。"},
+		{ID: "troubleshooting", Title: "常见问题", Content: "请求返回 503 UH 表示上游没有健康的节点，请检查服务发现配置。"},
+	}
+	for _, doc := range docs {
+		_, err := store.Put(doc)
+		require.NoError(t, err)
+	}
+
+	lexical := tools.NewBM25Index(200, 20)
+	lexical.Sync(store.List())
+	assert.Equal(t, 3, lexical.ChunkCount())
+
+	// 复合词精确匹配
+	hits := lexical.Search("ai-proxy 怎么配置", 10)
+	require.NotEmpty(t, hits)
+	assert.Equal(t, "ai-proxy", hits[0].Chunk.DocumentID)
+
+	hits = lexical.Search("503 UH", 10)
+	require.NotEmpty(t, hits)
+	assert.Equal(t, "troubleshooting", hits[0].Chunk.DocumentID)
+
+	// 删除文档后不再命中
+	require.NoError(t, store.Delete("troubleshooting"))
+	lexical.Sync(store.List())
+	for _, hit := range lexical.Search("503 UH", 10) {
+		assert.NotEqual(t, "troubleshooting", hit.Chunk.DocumentID)
+	}
+
+	// 两路都排第一的分块融合后排第一
+	a := tools.ChunkHit{Chunk: tools.Chunk{DocumentID: "a"}}
+	b := tools.ChunkHit{Chunk: tools.Chunk{DocumentID: "b"}}
+	c := tools.ChunkHit{Chunk: tools.Chunk{DocumentID: "c"}}
+	fused := tools.FuseRankings(tools.DefaultRRFK, []tools.ChunkHit{a, b}, []tools.ChunkHit{a, c, b})
+	require.Len(t, fused, 3)
+	assert.Equal(t, "a", fused[0].Chunk.DocumentID)
+	assert.Equal(t, "b", fused[1].Chunk.DocumentID)
+
+	// 知识库检索结果相关度在0-1之间
+	kb := tools.NewKnowledgeBaseWithStore("", store)
+	t.Cleanup(kb.Close)
+	result, err := kb.SearchKnowledge("ai-cache", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Results)
+	assert.Equal(t, "ai-cache", result.Results[0].DocumentID)
+	assert.LessOrEqual(t, result.Results[0].RelevanceScore, 1.0)
+}
+
+// TestSearchRelevanceCalibrated 测试相关度与排名无关：无关文档即使排第一也不会被当作高相关结果
+func TestSearchRelevanceCalibrated(t *testing.T) {
+	store := tools.NewMemoryDocumentStore()
+	docs := []model.Document{
+		{ID: "pasta", Title: "意面做法", Content: "意大利面煮八分钟后捞出，拌入番茄酱和罗勒即可。"},
+		{ID: "garden", Title: "阳台种菜", Content: "番茄需要充足日照，每周浇水两到三次，注意防治蚜虫。"},
+		{ID: "bicycle", Title: "自行车保养", Content: "链条每骑行三百公里清洁一次并上油，刹车片磨损后及时更换。"},
+	}
+	for _, doc := range docs {
+		_, err := store.Put(doc)
+		require.NoError(t, err)
+	}
+
+	index, err := tools.NewVectorIndex("", tools.NewHashEmbedder(256), 200, 20)
+	require.NoError(t, err)
+	kb := tools.NewKnowledgeBaseWithIndex(store, index)
+	t.Cleanup(kb.Close)
+	require.NoError(t, kb.Reindex(context.Background()))
+
+	result, err := kb.SearchKnowledgeContext(context.Background(), "Higress 网关的 ai-proxy 插件怎么配置", 5)
+	require.NoError(t, err)
+	for _, item := range result.Results {
+		assert.Less(t, item.RelevanceScore, 0.3, item.DocumentID)
+	}
+
+	// 相关文档仍然得到高相关度
+	_, err = kb.AddDocument(model.Document{ID: "ai-proxy", Title: "AI代理插件", Content: "Higress 网关的 ai-proxy 插件将请求转发到大模型服务，配置 provider.type 选择服务商。"})
+	require.NoError(t, err)
+	require.NoError(t, kb.Reindex(context.Background()))
+	result, err = kb.SearchKnowledgeContext(context.Background(), "Higress 网关的 ai-proxy 插件怎么配置", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Results)
+	assert.Equal(t, "ai-proxy", result.Results[0].DocumentID)
+	assert.Greater(t, result.Results[0].RelevanceScore, 0.5)
+	for _, item := range result.Results[1:] {
+		assert.Less(t, item.RelevanceScore, 0.3, item.DocumentID)
+	}
+}
+
+// TestSearchFilteredBeforeTruncation 测试过滤在截断候选之前进行，文档镜像超过候选数量时不会挤掉本地文档，反之亦然
+func TestSearchFilteredBeforeTruncation(t *testing.T) {
+	store := tools.NewMemoryDocumentStore()
+	filler := strings.Repeat("网关按路由转发请求，支持多种部署方式。", 5)
+	for i := 0; i < 60; i++ {
+		_, err := store.Put(model.Document{ID: fmt.Sprintf("docs-%d", i), Source: tools.DocsSource, Content: fmt.Sprintf("限流 限流 限流 第%d节", i)})
+		require.NoError(t, err)
+		_, err = store.Put(model.Document{ID: fmt.Sprintf("local-%d", i), Content: fmt.Sprintf("缓存 缓存 缓存 第%d条", i)})
+		require.NoError(t, err)
+	}
+	_, err := store.Put(model.Document{ID: "local-rate-limit", Content: filler + "限流" + filler})
+	require.NoError(t, err)
+	_, err = store.Put(model.Document{ID: "docs-cache", Source: tools.DocsSource, Content: filler + "缓存" + filler})
+	require.NoError(t, err)
+
+	index, err := tools.NewVectorIndex("", tools.NewHashEmbedder(256), 200, 20)
+	require.NoError(t, err)
+	kb := tools.NewKnowledgeBaseWithIndex(store, index)
+	t.Cleanup(kb.Close)
+	require.NoError(t, kb.Reindex(context.Background()))
+
+	isMirror := func(doc model.Document) bool { return doc.Source == tools.DocsSource }
+	isLocal := func(doc model.Document) bool { return !isMirror(doc) }
+
+	result, err := kb.SearchKnowledgeFiltered(context.Background(), "限流", 5, isLocal)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "local-rate-limit", result.Results[0].DocumentID)
+
+	result, err = kb.SearchKnowledgeFiltered(context.Background(), "缓存", 5, isMirror)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "docs-cache", result.Results[0].DocumentID)
+
+	result, err = kb.SearchKnowledgeFiltered(context.Background(), "限流", 5, isMirror)
+	require.NoError(t, err)
+	require.Len(t, result.Results, 5)
+	for _, item := range result.Results {
+		assert.True(t, strings.HasPrefix(item.DocumentID, "docs-"), item.DocumentID)
+	}
+}
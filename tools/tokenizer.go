@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenizerVersion 分词规则版本，规则变化后依赖分词的哈希向量需要重建
+const tokenizerVersion = 2
+
+// Tokenize 将文本切分为检索用的词元
+// 拉丁字母和数字按连续字符组成单词并转为小写；由-、_、.连接的复合词（如wasm-go、ai-proxy）
+// 同时保留整体和各部分，便于精确匹配插件名和配置项；中日韩文字没有空格分词，按单字和相邻双字切分
+func Tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	var cjk []rune
+
+	flushWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		compound := strings.Trim(word.String(), "-_.")
+		word.Reset()
+		if compound == "" {
+			return
+		}
+
+		parts := strings.FieldsFunc(compound, isConnector)
+		if len(parts) > 1 {
+			tokens = append(tokens, compound)
+		}
+		tokens = append(tokens, parts...)
+	}
+	flushCJK := func() {
+		for i, r := range cjk {
+			tokens = append(tokens, string(r))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word.WriteRune(unicode.ToLower(r))
+		case isConnector(r) && word.Len() > 0:
+			word.WriteRune(r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return tokens
+}
+
+// isConnector 判断是否为复合词连接符
+func isConnector(r rune) bool {
+	return r == '-' || r == '_' || r == '.'
+}
+
+// isCJK 判断是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/community-governance-mcp-higress/internal/model"
+)
+
+const (
+	// vectorIndexVersion 索引文件格式版本，格式变化时旧索引会被重建
+	vectorIndexVersion = 1
+	// syncBatchSize 同步索引时每批生成向量的分块数，每批完成后写回磁盘
+	syncBatchSize = 64
+)
+
+// VectorIndex 分块向量索引
+// 使用暴力余弦检索（向量已归一化，即点积），文档规模在数万分块以内时足够快；
+// 索引按文档指纹增量更新，并以gob格式持久化，重启后无需重新生成向量
+type VectorIndex struct {
+	path         string
+	embedder     Embedder
+	chunkSize    int
+	chunkOverlap int
+	documents    map[string]indexedDocument
+	mutex        sync.RWMutex
+	syncMutex    sync.Mutex
+}
+
+// indexedDocument 已索引的文档
+type indexedDocument struct {
+	Fingerprint string
+	Entries     []vectorEntry
+}
+
+// vectorEntry 分块及其向量
+type vectorEntry struct {
+	Chunk  Chunk
+	Vector []float32
+}
+
+// vectorIndexFile 索引文件内容
+type vectorIndexFile struct {
+	Version   int
+	Embedder  string
+	Documents map[string]indexedDocument
+}
+
+// NewVectorIndex 创建向量索引，path非空时加载已有索引
+// 索引文件的向量模型与embedder不一致时丢弃旧索引
+func NewVectorIndex(path string, embedder Embedder, chunkSize, chunkOverlap int) (*VectorIndex, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkOverlap < 0 {
+		chunkOverlap = DefaultChunkOverlap
+	}
+
+	index := &VectorIndex{
+		path:         path,
+		embedder:     embedder,
+		chunkSize:    chunkSize,
+		chunkOverlap: chunkOverlap,
+		documents:    make(map[string]indexedDocument),
+	}
+
+	if path == "" {
+		return index, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开向量索引失败: %w", err)
+	}
+	defer file.Close()
+
+	var data vectorIndexFile
+	if err := gob.NewDecoder(file).Decode(&data); err != nil {
+		// 索引损坏时重建
+		return index, nil
+	}
+	if data.Version == vectorIndexVersion && data.Embedder == embedder.Name() && data.Documents != nil {
+		index.documents = data.Documents
+	}
+
+	return index, nil
+}
+
+// Sync 使索引与文档集合保持一致
+// 只为新增或内容变化的文档生成向量，已删除文档的分块会被移除；
+// 向量按批生成，每批完成后立即生效并写回磁盘，失败时已完成的批次不会丢失，
+// 内容变化的文档在重新生成向量之前继续使用旧的分块
+func (vi *VectorIndex) Sync(ctx context.Context, documents []model.Document) error {
+	vi.syncMutex.Lock()
+	defer vi.syncMutex.Unlock()
+
+	vi.mutex.RLock()
+	existing := vi.documents
+	vi.mutex.RUnlock()
+
+	updated := make(map[string]indexedDocument, len(documents))
+	var pending []model.Document
+	var fingerprints []string
+	retained := 0
+
+	for _, doc := range documents {
+		fingerprint := documentFingerprint(doc, vi.chunkSize, vi.chunkOverlap)
+		indexed, ok := existing[doc.ID]
+		if ok {
+			updated[doc.ID] = indexed
+			retained++
+			if indexed.Fingerprint == fingerprint {
+				continue
+			}
+		}
+		pending = append(pending, doc)
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	if len(pending) == 0 && retained == len(existing) {
+		return nil
+	}
+	if retained != len(existing) {
+		vi.publish(updated)
+		if len(pending) == 0 {
+			return vi.save(updated)
+		}
+	}
+
+	for start := 0; start < len(pending); {
+		// 每批包含完整的文档，单个文档的分块不会拆到两批
+		var batchChunks []Chunk
+		var batchTexts []string
+		end := start
+		for end < len(pending) && (end == start || len(batchTexts) < syncBatchSize) {
+			doc := pending[end]
+			for _, chunk := range ChunkDocument(doc, vi.chunkSize, vi.chunkOverlap) {
+				batchChunks = append(batchChunks, chunk)
+				// 标题参与向量计算，提升短分块的语义完整性
+				batchTexts = append(batchTexts, doc.Title+"\n"+chunk.Text)
+			}
+			end++
+		}
+
+		var vectors [][]float32
+		if len(batchTexts) > 0 {
+			var err error
+			vectors, err = vi.embedder.Embed(ctx, batchTexts)
+			if err != nil {
+				if saveErr := vi.save(updated); saveErr != nil {
+					return saveErr
+				}
+				return fmt.Errorf("生成分块向量失败: %w", err)
+			}
+		}
+
+		for i := start; i < end; i++ {
+			updated[pending[i].ID] = indexedDocument{Fingerprint: fingerprints[i]}
+		}
+		for i, chunk := range batchChunks {
+			indexed := updated[chunk.DocumentID]
+			indexed.Entries = append(indexed.Entries, vectorEntry{Chunk: chunk, Vector: vectors[i]})
+			updated[chunk.DocumentID] = indexed
+		}
+		vi.publish(updated)
+		if err := vi.save(updated); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	return nil
+}
+
+// publish 使索引进度对检索可见，Sync继续修改的是另一份副本
+func (vi *VectorIndex) publish(documents map[string]indexedDocument) {
+	vi.mutex.Lock()
+	vi.documents = maps.Clone(documents)
+	vi.mutex.Unlock()
+}
+
+// Search 返回与查询向量最相似的分块
+func (vi *VectorIndex) Search(query []float32, limit int) []ChunkHit {
+	return vi.SearchFiltered(query, limit, nil)
+}
+
+// SearchFiltered 只返回filter接受的文档中的分块，过滤在截断到limit之前进行；filter为nil时不过滤
+func (vi *VectorIndex) SearchFiltered(query []float32, limit int, filter func(documentID string) bool) []ChunkHit {
+	vi.mutex.RLock()
+	defer vi.mutex.RUnlock()
+
+	var hits []ChunkHit
+	for documentID, indexed := range vi.documents {
+		if filter != nil && !filter(documentID) {
+			continue
+		}
+		for _, entry := range indexed.Entries {
+			if len(entry.Vector) != len(query) {
+				continue
+			}
+			similarity := dotProduct(query, entry.Vector)
+			hits = append(hits, ChunkHit{Chunk: entry.Chunk, Score: similarity, Relevance: math.Max(similarity, 0)})
+		}
+	}
+
+	sortChunkHits(hits)
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// Embedder 获取索引使用的向量生成器
+func (vi *VectorIndex) Embedder() Embedder {
+	return vi.embedder
+}
+
+// ChunkSize 分块大小
+func (vi *VectorIndex) ChunkSize() int {
+	return vi.chunkSize
+}
+
+// ChunkOverlap 相邻分块重叠字符数
+func (vi *VectorIndex) ChunkOverlap() int {
+	return vi.chunkOverlap
+}
+
+// ChunkCount 获取已索引的分块数量
+func (vi *VectorIndex) ChunkCount() int {
+	vi.mutex.RLock()
+	defer vi.mutex.RUnlock()
+
+	count := 0
+	for _, indexed := range vi.documents {
+		count += len(indexed.Entries)
+	}
+	return count
+}
+
+// save 原子写入索引文件
+func (vi *VectorIndex) save(documents map[string]indexedDocument) error {
+	if vi.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(vi.path), 0755); err != nil {
+		return fmt.Errorf("创建索引目录失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(vi.path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	data := vectorIndexFile{
+		Version:   vectorIndexVersion,
+		Embedder:  vi.embedder.Name(),
+		Documents: documents,
+	}
+	if err := gob.NewEncoder(tmp).Encode(&data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入向量索引失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入向量索引失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), vi.path); err != nil {
+		return fmt.Errorf("保存向量索引失败: %w", err)
+	}
+
+	return nil
+}
+
+// documentFingerprint 文档指纹，标题、内容或分块参数变化时需要重新索引
+func documentFingerprint(doc model.Document, chunkSize, chunkOverlap int) string {
+	hasher := sha1.New()
+	fmt.Fprintf(hasher, "%d:%d\x00%s\x00%s", chunkSize, chunkOverlap, doc.Title, doc.Content)
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// dotProduct 向量点积
+func dotProduct(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}